@@ -28,7 +28,6 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/kube-state-metrics/v2/pkg/metric"
-	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
 )
 
 var (
@@ -39,9 +38,9 @@ var (
 	descBroadcastJobLabelsDefaultLabels = []string{"namespace", "broadcastjob"}
 )
 
-func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string) []generator.FamilyGenerator {
-	return []generator.FamilyGenerator{
-		*generator.NewFamilyGenerator(
+func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
 			"kruise_broadcastjob_created",
 			"Unix creation timestamp",
 			metric.Gauge,
@@ -60,7 +59,7 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_broadcastjob_status_active",
 			"The number of actively running pods.",
 			metric.Gauge,
@@ -75,7 +74,7 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_broadcastjob_status_succeeded",
 			"The number of pods which reached phase Succeeded.",
 			metric.Gauge,
@@ -90,7 +89,7 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_broadcastjob_status_failed",
 			"The number of pods which reached phase Failed.",
 			metric.Gauge,
@@ -105,7 +104,7 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_broadcastjob_status_desired",
 			"The desired number of pods, this is typically equal to the number of nodes satisfied to run pods.",
 			metric.Gauge,
@@ -120,32 +119,29 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_broadcastjob_status_condition",
 			"The current status conditions of a broadcastjob.",
 			metric.Gauge,
 			"",
 			wrapBroadcastJobFunc(func(bj *v1alpha1.BroadcastJob) *metric.Family {
-				ms := make([]*metric.Metric, len(bj.Status.Conditions)*len(conditionStatuses))
-
-				for i, bj := range bj.Status.Conditions {
-					conditionMetrics := addConditionMetrics(bj.Status)
-
-					for j, m := range conditionMetrics {
-						metric := m
-
-						metric.LabelKeys = []string{"condition", "status"}
-						metric.LabelValues = append([]string{string(bj.Type)}, metric.LabelValues...)
-						ms[i*len(conditionStatuses)+j] = metric
-					}
+				return &metric.Family{
+					Metrics: addStatusConditionMetrics(broadcastJobConditions(bj)),
 				}
-
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_broadcastjob_status_condition_last_transition_time",
+			"Unix timestamp of the last transition of the current status conditions of a broadcastjob.",
+			metric.Gauge,
+			"",
+			wrapBroadcastJobFunc(func(bj *v1alpha1.BroadcastJob) *metric.Family {
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: addConditionLastTransitionTimeMetrics(broadcastJobConditions(bj)),
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_broadcastjob_spec_parallelism",
 			"The maximum desired number of pods the job should.",
 			metric.Gauge,
@@ -160,7 +156,7 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_broadcastjob_metadata_generation",
 			"Sequence number representing a specific generation of the desired state.",
 			metric.Gauge,
@@ -175,7 +171,7 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descBroadcastJobAnnotationsName,
 			descBroadcastJobAnnotationsHelp,
 			metric.Gauge,
@@ -193,7 +189,7 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descBroadcastJobLabelsName,
 			descBroadcastJobLabelsHelp,
 			metric.Gauge,
@@ -211,10 +207,11 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGeneratorWithUnit(
 			"kruise_broadcastjob_spec_strategy_activedeadline_seconds",
 			"The duration in seconds relative to the startTime that the job may be active.",
 			metric.Gauge,
+			unitSeconds,
 			"",
 			wrapBroadcastJobFunc(func(bj *v1alpha1.BroadcastJob) *metric.Family {
 				value := 0.0
@@ -230,10 +227,11 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGeneratorWithUnit(
 			"kruise_broadcastjob_spec_strategy_ttl_seconds",
 			"The lifetime of a Job that has finished.",
 			metric.Gauge,
+			unitSeconds,
 			"",
 			wrapBroadcastJobFunc(func(bj *v1alpha1.BroadcastJob) *metric.Family {
 				value := 0.0
@@ -249,7 +247,7 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_broadcastjob_spec_strategy_type",
 			"The type of completionpolicy.",
 			metric.Gauge,
@@ -268,6 +266,26 @@ func broadcastJobMetricFamilies(allowAnnotationsList, allowLabelsList []string)
 	}
 }
 
+// broadcastJobConditions returns the status conditions of a broadcastjob.
+func broadcastJobConditions(bj *v1alpha1.BroadcastJob) []statusCondition {
+	conditions := make([]statusCondition, len(bj.Status.Conditions))
+	for i, c := range bj.Status.Conditions {
+		conditions[i] = statusCondition{
+			conditionType:      string(c.Type),
+			status:             c.Status,
+			reason:             c.Reason,
+			lastTransitionTime: c.LastTransitionTime,
+		}
+	}
+	return conditions
+}
+
+// broadcastJobConditionReasonMetricFamilies returns the family of the reasons
+// of the status conditions of broadcastjobs.
+func broadcastJobConditionReasonMetricFamilies(reasons []string) []FamilyGenerator {
+	return conditionReasonMetricFamilies("broadcastjob", reasons, broadcastJobConditions, wrapBroadcastJobFunc)
+}
+
 func wrapBroadcastJobFunc(f func(*v1alpha1.BroadcastJob) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		broadcastjob := obj.(*v1alpha1.BroadcastJob)
@@ -283,12 +301,16 @@ func wrapBroadcastJobFunc(f func(*v1alpha1.BroadcastJob) *metric.Family) func(in
 	}
 }
 
-func createBroadcastJobListWatch(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+func createBroadcastJobListWatch(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().BroadcastJobs(ns).List(context.TODO(), opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().BroadcastJobs(ns).Watch(context.TODO(), opts)
 		},
 	}
@@ -19,34 +19,47 @@ package store
 import (
 	"context"
 	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
+	policyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	vpaclientset "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/clientset/versioned"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/tools/cache"
 	klog "k8s.io/klog/v2"
 	ksmtypes "k8s.io/kube-state-metrics/v2/pkg/builder/types"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
 	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
 	metricsstore "k8s.io/kube-state-metrics/v2/pkg/metrics_store"
 	"k8s.io/kube-state-metrics/v2/pkg/options"
 	"k8s.io/kube-state-metrics/v2/pkg/sharding"
 	"k8s.io/kube-state-metrics/v2/pkg/watch"
+	"k8s.io/utils/clock"
 )
 
-// BuildKruiseStoresFunc function signature that is used to return a list of metricsstore.MetricsStore
-type BuildKruiseStoresFunc func(metricFamilies []generator.FamilyGenerator,
+// ResourceWildcard selects all resources without a selector of their own.
+const ResourceWildcard = "*"
+
+// BuildKruiseStoresFunc function signature that is used to return a list of MetricsStore
+type BuildKruiseStoresFunc func(metricFamilies []FamilyGenerator,
 	expectedType interface{},
 	listWatchFunc func(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher,
 	useAPIServerCache bool,
-) []*metricsstore.MetricsStore
+) []*MetricsStore
 
 // Make sure the internal Builder implements the public BuilderInterface.
 // New Builder methods should be added to the public BuilderInterface.
@@ -57,6 +70,7 @@ var _ ksmtypes.BuilderInterface = &Builder{}
 type Builder struct {
 	kubeClient            clientset.Interface
 	kruiseClient          kruiseclientset.Interface
+	metadataClient        metadata.Interface
 	namespaces            options.NamespaceList
 	ctx                   context.Context
 	enabledResources      []string
@@ -65,16 +79,45 @@ type Builder struct {
 	shardingMetrics       *sharding.Metrics
 	shard                 int32
 	totalShards           int
+	shardFunc             shardFunc
 	buildStoresFunc       ksmtypes.BuildStoresFunc
 	buildKruiseStoresFunc BuildKruiseStoresFunc
 	allowAnnotationsList  map[string][]string
 	allowLabelsList       map[string][]string
 	useAPIServerCache     bool
+	seriesDroppedTotal    *prometheus.CounterVec
+	seriesLimiter         *seriesLimiter
+	resourceSelectorInfo  *prometheus.GaugeVec
+	labelSelectors        map[string]string
+	fieldSelectors        map[string]string
+	namespaceSelector     labels.Selector
+	namespaceWatcher      *namespaceWatcher
+	keepState             bool
+	stateListener         StateListener
+	clock                 clock.PassiveClock
+	rollouts              *rolloutTracker
+	transitions           *transitionTracker
+	podMetrics            bool
+	eventReasons          []string
+	conditionReasons      []string
+	events                *eventCounter
+	injections            *sidecarSetInjections
+	spreads               *workloadSpreadCompliance
+	pubs                  *pubBlocking
+	summary               *workloadsSummary
+	slos                  *workloadSLOs
+
+	// activeStores holds the stores of the last Build by resource.
+	activeStoresMtx sync.RWMutex
+	activeStores    map[string][]*MetricsStore
 }
 
 // NewBuilder returns a new builder.
 func NewBuilder() *Builder {
-	b := &Builder{}
+	b := &Builder{
+		shardFunc: jumpShard,
+		clock:     clock.RealClock{},
+	}
 	return b
 }
 
@@ -82,6 +125,20 @@ func NewBuilder() *Builder {
 func (b *Builder) WithMetrics(r prometheus.Registerer) {
 	b.listWatchMetrics = watch.NewListWatchMetrics(r)
 	b.shardingMetrics = sharding.NewShardingMetrics(r)
+	b.seriesDroppedTotal = promauto.With(r).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kruise_state_metrics_series_dropped_total",
+			Help: "Number of series dropped because their metric family went over its series limit.",
+		},
+		[]string{"family"},
+	)
+	b.resourceSelectorInfo = promauto.With(r).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kruise_state_metrics_resource_selector_info",
+			Help: "Label and field selectors used to list and watch the objects of a resource.",
+		},
+		[]string{"resource", "label_selector", "field_selector"},
+	)
 }
 
 // WithEnabledResources sets the enabledResources property of a Builder.
@@ -91,6 +148,12 @@ func (b *Builder) WithEnabledResources(r []string) error {
 			return errors.Errorf("resource %s does not exist. Available resources: %s", col, strings.Join(availableResources(), ","))
 		}
 	}
+	for _, col := range r {
+		deps, ok := resourceDependencies[col]
+		if ok && !slices.ContainsFunc(deps, func(dep string) bool { return slices.Contains(r, dep) }) {
+			return errors.Errorf("resource %s requires one of the resources %s", col, strings.Join(deps, ","))
+		}
+	}
 
 	var copy []string
 	copy = append(copy, r...)
@@ -106,6 +169,24 @@ func (b *Builder) WithNamespaces(n options.NamespaceList) {
 	b.namespaces = n
 }
 
+// WithNamespaceSelector sets a label selector on Namespace objects. When set,
+// the objects of the namespaces matching the selector are exposed, following
+// namespaces as they are created, relabeled or deleted. If namespaces are set
+// as well, only the selected namespaces among them are exposed.
+func (b *Builder) WithNamespaceSelector(selector string) error {
+	if selector == "" {
+		b.namespaceSelector = nil
+		return nil
+	}
+
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return errors.Wrap(err, "invalid namespace selector")
+	}
+	b.namespaceSelector = s
+	return nil
+}
+
 // WithSharding sets the shard and totalShards property of a Builder.
 func (b *Builder) WithSharding(shard int32, totalShards int) {
 	b.shard = shard
@@ -116,6 +197,17 @@ func (b *Builder) WithSharding(shard int32, totalShards int) {
 	b.shardingMetrics.Total.Set(float64(totalShards))
 }
 
+// WithShardingAlgorithm sets the algorithm assigning objects to shards, see
+// ShardingAlgorithms for the available ones.
+func (b *Builder) WithShardingAlgorithm(name string) error {
+	f, ok := shardingAlgorithms[name]
+	if !ok {
+		return errors.Errorf("sharding algorithm %s does not exist. Available algorithms: %s", name, strings.Join(ShardingAlgorithms(), ","))
+	}
+	b.shardFunc = f
+	return nil
+}
+
 // WithContext sets the ctx property of a Builder.
 func (b *Builder) WithContext(ctx context.Context) {
 	b.ctx = ctx
@@ -131,6 +223,13 @@ func (b *Builder) WithKruiseClient(c kruiseclientset.Interface) {
 	b.kruiseClient = c
 }
 
+// WithMetadataClient sets the metadataClient property of a Builder, which
+// watches the metadata of the objects the metrics of the pods need, e.g. to
+// resolve the owners of the pods up to their workload.
+func (b *Builder) WithMetadataClient(c metadata.Interface) {
+	b.metadataClient = c
+}
+
 // WithVPAClient sets the vpaClient property of a Builder so that the verticalpodautoscaler collector can query VPA objects.
 func (b *Builder) WithVPAClient(c vpaclientset.Interface) {
 	// nothing to do
@@ -167,6 +266,106 @@ func (b *Builder) WithAllowLabels(labels map[string][]string) {
 	}
 }
 
+// WithSeriesLimits configures the maximum number of series per metric family
+// and across all families. Limits of 0 disable the corresponding check, the
+// per family limits take precedence over familyLimit.
+func (b *Builder) WithSeriesLimits(familyLimit int, familyLimits map[string]int, totalLimit int) {
+	b.seriesLimiter = newSeriesLimiter(familyLimit, familyLimits, totalLimit, b.seriesDroppedTotal)
+}
+
+// WithState configures whether the stores keep the structured metrics of
+// every object next to their text representation, e.g. to serve them as
+// JSON.
+func (b *Builder) WithState(enabled bool) {
+	b.keepState = enabled
+}
+
+// WithPodMetrics configures whether the pods are watched to compute the
+// metrics of the workloads derived from them, e.g. their in-place updates.
+func (b *Builder) WithPodMetrics(enabled bool) {
+	b.podMetrics = enabled
+}
+
+// WithEventReasons sets the reasons of the events counted by the events store,
+// the events of other reasons are counted together.
+func (b *Builder) WithEventReasons(reasons []string) {
+	b.eventReasons = reasons
+}
+
+// WithConditionReasons sets the reasons of the status conditions reported by
+// the condition reason families, the conditions of other reasons are reported
+// together. The families are not generated if no reason is set.
+func (b *Builder) WithConditionReasons(reasons []string) {
+	b.conditionReasons = reasons
+}
+
+// WithStateListener sets the listener notified of the state changes of the
+// objects of all stores. It requires the state to be kept.
+func (b *Builder) WithStateListener(l StateListener) {
+	b.stateListener = l
+}
+
+// WithResourceSelectors sets the label and field selectors used to list and
+// watch the objects of each resource, indexed by resource name. The selector
+// of ResourceWildcard applies to all resources without a selector of their own.
+func (b *Builder) WithResourceSelectors(labelSelectors, fieldSelectors map[string]string) error {
+	for r, selector := range labelSelectors {
+		if r != ResourceWildcard && !resourceExists(r) {
+			return errors.Errorf("label selector for resource %s which does not exist. Available resources: %s", r, strings.Join(availableResources(), ","))
+		}
+		if _, err := labels.Parse(selector); err != nil {
+			return errors.Wrapf(err, "invalid label selector for resource %s", r)
+		}
+	}
+	for r, selector := range fieldSelectors {
+		if r != ResourceWildcard && !resourceExists(r) {
+			return errors.Errorf("field selector for resource %s which does not exist. Available resources: %s", r, strings.Join(availableResources(), ","))
+		}
+		if _, err := fields.ParseSelector(selector); err != nil {
+			return errors.Wrapf(err, "invalid field selector for resource %s", r)
+		}
+	}
+
+	b.labelSelectors = labelSelectors
+	b.fieldSelectors = fieldSelectors
+
+	if b.resourceSelectorInfo != nil {
+		b.resourceSelectorInfo.Reset()
+		for _, r := range b.enabledResources {
+			labelSelector, fieldSelector := b.selectorsFor(r)
+			if labelSelector != "" || fieldSelector != "" {
+				b.resourceSelectorInfo.WithLabelValues(r, labelSelector, fieldSelector).Set(1)
+			}
+		}
+	}
+	return nil
+}
+
+// selectorsFor returns the label and field selectors of the given resource.
+func (b *Builder) selectorsFor(resource string) (string, string) {
+	labelSelector, ok := b.labelSelectors[resource]
+	if !ok {
+		labelSelector = b.labelSelectors[ResourceWildcard]
+	}
+	fieldSelector, ok := b.fieldSelectors[resource]
+	if !ok {
+		fieldSelector = b.fieldSelectors[ResourceWildcard]
+	}
+	return labelSelector, fieldSelector
+}
+
+// selectedListWatch binds the selectors of the given resource to its list
+// watch function.
+func (b *Builder) selectedListWatch(
+	resource string,
+	listWatchFunc func(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher,
+) func(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+	labelSelector, fieldSelector := b.selectorsFor(resource)
+	return func(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+		return listWatchFunc(kruiseClient, ns, labelSelector, fieldSelector)
+	}
+}
+
 // Build initializes and registers all enabled stores.
 // It returns metrics writers which can be used to write out
 // metrics from the stores.
@@ -177,33 +376,162 @@ func (b *Builder) Build() []metricsstore.MetricsWriter {
 
 	var metricsWriters []metricsstore.MetricsWriter
 	var activeStoreNames []string
+	watchPodUpdates := false
+	var podHandlers []podHandler
+	activeStores := map[string][]*MetricsStore{}
+
+	if b.seriesLimiter != nil {
+		b.seriesLimiter.reset()
+	}
+	b.rollouts = newRolloutTracker(b.clock)
+	b.transitions = newTransitionTracker(b.clock)
+	b.events = newEventCounter(b.eventReasons)
+	b.injections = nil
+	b.spreads = nil
+	b.pubs = nil
+	b.summary = nil
+	if summary := newWorkloadsSummary(); slices.Contains(b.enabledResources, workloadsSummaryResource) && summary.allow(b.allowDenyList.IsIncluded) {
+		b.summary = summary
+	}
+	b.slos = nil
+	if slos := newWorkloadSLOs(b.clock); slices.Contains(b.enabledResources, workloadSLOsResource) && slos.allow(b.allowDenyList.IsIncluded) {
+		b.slos = slos
+	}
+	if pubs := newPUBBlocking(b.clock); pubs.allow(b.allowDenyList.IsIncluded) {
+		b.pubs = pubs
+		b.events.handlers = append(b.events.handlers, pubs)
+	}
+	var nsLabels, nodeLabels *objectLabels
+	owners := &workloadOwners{}
+	targets := newTargetReplicas()
+	if b.podMetrics {
+		nsLabels = newNamespaceLabels(b.kubeClient)
+		if injections := newSidecarSetInjections(nsLabels.get, owners.resolve); injections.allow(b.allowDenyList.IsIncluded) {
+			b.injections = injections
+			nsLabels.notify(injections.updateNamespace)
+		}
+		nodeLabels = newNodeLabels(b.metadataClient)
+		if spreads := newWorkloadSpreadCompliance(nodeLabels.get, targets.get); spreads.allow(b.allowDenyList.IsIncluded) {
+			b.spreads = spreads
+		}
+	}
+
+	b.namespaceWatcher = nil
+	if b.namespaceSelector != nil {
+		b.namespaceWatcher = newNamespaceWatcher(b.kubeClient, b.namespaceSelector, b.namespaces)
+	}
 
 	for _, c := range b.enabledResources {
 		constructor, ok := availableStores[c]
 		if ok {
 			stores := constructor(b)
+			if b.stateListener != nil {
+				for _, s := range stores {
+					s.setStateListener(c, b.stateListener)
+				}
+			}
 			activeStoreNames = append(activeStoreNames, c)
-			if len(stores) == 1 {
-				metricsWriters = append(metricsWriters, stores[0])
-			} else {
-				metricsWriters = append(metricsWriters, metricsstore.NewMultiStoreMetricsWriter(stores))
+			activeStores[c] = stores
+			metricsWriters = append(metricsWriters, NewResourceMetricsWriter(c, stores))
+			if h, ok := b.transitions.durations[c]; ok && h.allow(b.allowDenyList.IsIncluded) {
+				metricsWriters = append(metricsWriters, h)
+			}
+			if c == b.events.resource && b.events.allow(b.allowDenyList.IsIncluded) {
+				metricsWriters = append(metricsWriters, b.events)
+			}
+			if h, ok := b.transitions.inPlaceDurations[c]; ok && b.podMetrics {
+				if h.allow(b.allowDenyList.IsIncluded) {
+					metricsWriters = append(metricsWriters, h)
+				}
+				watchPodUpdates = true
+			}
+			if c == "sidecarsets" && b.injections != nil {
+				metricsWriters = append(metricsWriters, b.injections)
+				podHandlers = append(podHandlers, b.injections)
+				go nsLabels.run(b.ctx)
+				b.buildWorkloadOwners(owners)
+			}
+			if c == "workloadspreads" && b.spreads != nil {
+				metricsWriters = append(metricsWriters, b.spreads)
+				podHandlers = append(podHandlers, b.spreads)
+				go nodeLabels.run(b.ctx)
+				b.buildTargetReplicas(targets)
+			}
+			if c == "podunavailablebudgets" && b.pubs != nil {
+				metricsWriters = append(metricsWriters, b.pubs)
+			}
+			if c == workloadsSummaryResource && b.summary != nil {
+				metricsWriters = append(metricsWriters, b.summary)
+			}
+			if c == workloadSLOsResource && b.slos != nil {
+				metricsWriters = append(metricsWriters, b.slos)
 			}
 		}
 	}
 
 	klog.Infof("Active resources: %s", strings.Join(activeStoreNames, ","))
 
+	// The series of the collectors are limited when they are written.
+	if b.seriesLimiter != nil {
+		for _, w := range metricsWriters {
+			if c, ok := w.(interface{ limitSeries(*seriesLimiter) }); ok {
+				c.limitSeries(b.seriesLimiter)
+			}
+		}
+	}
+
+	if watchPodUpdates {
+		podHandlers = append(podHandlers, b.transitions)
+	}
+	if len(podHandlers) > 0 {
+		b.buildPodWatch(podHandlers...)
+	}
+
+	b.activeStoresMtx.Lock()
+	b.activeStores = activeStores
+	b.activeStoresMtx.Unlock()
+
+	if b.namespaceWatcher != nil {
+		go b.namespaceWatcher.run(b.ctx)
+	}
+
 	return metricsWriters
 }
 
-var availableStores = map[string]func(f *Builder) []*metricsstore.MetricsStore{
-	"clonesets":                 func(b *Builder) []*metricsstore.MetricsStore { return b.buildCloneSetStores() },
-	"statefulsets":              func(b *Builder) []*metricsstore.MetricsStore { return b.buildStatefulSetStores() },
-	"sidecarsets":               func(b *Builder) []*metricsstore.MetricsStore { return b.buildSidecarSetStores() },
-	"workloadspreads":           func(b *Builder) []*metricsstore.MetricsStore { return b.buildWorkloadSpreadStores() },
-	"daemonsets":                func(b *Builder) []*metricsstore.MetricsStore { return b.buildDaemonSetStores() },
-	"broadcastjobs":             func(b *Builder) []*metricsstore.MetricsStore { return b.buildBroadcastJob() },
-	"containerrecreaterequests": func(b *Builder) []*metricsstore.MetricsStore { return b.buildContainerRecreateRequest() },
+// ObjectCounts returns the number of objects in the stores of every active
+// resource.
+func (b *Builder) ObjectCounts() map[string]int {
+	b.activeStoresMtx.RLock()
+	defer b.activeStoresMtx.RUnlock()
+
+	counts := make(map[string]int, len(b.activeStores))
+	for resource, stores := range b.activeStores {
+		for _, s := range stores {
+			counts[resource] += s.Len()
+		}
+	}
+	return counts
+}
+
+var availableStores = map[string]func(f *Builder) []*MetricsStore{
+	"clonesets":                 func(b *Builder) []*MetricsStore { return b.buildCloneSetStores() },
+	"statefulsets":              func(b *Builder) []*MetricsStore { return b.buildStatefulSetStores() },
+	"sidecarsets":               func(b *Builder) []*MetricsStore { return b.buildSidecarSetStores() },
+	"workloadspreads":           func(b *Builder) []*MetricsStore { return b.buildWorkloadSpreadStores() },
+	"daemonsets":                func(b *Builder) []*MetricsStore { return b.buildDaemonSetStores() },
+	"broadcastjobs":             func(b *Builder) []*MetricsStore { return b.buildBroadcastJob() },
+	"containerrecreaterequests": func(b *Builder) []*MetricsStore { return b.buildContainerRecreateRequest() },
+	"podunavailablebudgets":     func(b *Builder) []*MetricsStore { return b.buildPodUnavailableBudgetStores() },
+	"events":                    func(b *Builder) []*MetricsStore { return b.buildEventStores() },
+	workloadsSummaryResource:    func(b *Builder) []*MetricsStore { return b.buildWorkloadsSummaryStores() },
+	workloadSLOsResource:        func(b *Builder) []*MetricsStore { return b.buildWorkloadSLOStores() },
+}
+
+// resourceDependencies are the resources derived from the objects of other
+// resources, one of which at least must be enabled.
+var resourceDependencies = map[string][]string{
+	workloadsSummaryResource: {"clonesets", "statefulsets", "daemonsets"},
+	workloadSLOsResource:     {"clonesets", "statefulsets", "daemonsets"},
 }
 
 func resourceExists(name string) bool {
@@ -230,66 +558,165 @@ func (b *Builder) DefaultKruiseStoresFunc() BuildKruiseStoresFunc {
 	return b.buildKruiseStores
 }
 
-func (b *Builder) buildCloneSetStores() []*metricsstore.MetricsStore {
-	return b.buildKruiseStoresFunc(cloneSetMetricFamilies(b.allowAnnotationsList["clonesets"], b.allowLabelsList["clonesets"]), &appsv1alpha1.CloneSet{}, createCloneSetListWatch, b.useAPIServerCache)
+// StaticKruiseStoresFunc returns a Kruise store function which fills every
+// store with the given objects of its type instead of running a reflector,
+// e.g. to compute the metrics of manifests offline. The objects must have a
+// UID and are filtered by the configured namespaces. The objects whose
+// metrics cannot be generated, e.g. because they lack fields defaulted by the
+// apiserver, are left out and passed to onError. The objects are passed to
+// the observers too, e.g. to render the workloads summary. The events
+// resource, whose store always watches the cluster, is not supported.
+func (b *Builder) StaticKruiseStoresFunc(objects []interface{}, onError func(obj interface{}, err error)) BuildKruiseStoresFunc {
+	return func(
+		metricFamilies []FamilyGenerator,
+		expectedType interface{},
+		_ func(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher,
+		_ bool,
+	) []*MetricsStore {
+		metricFamilies = filterMetricFamilies(b.allowDenyList, metricFamilies)
+		store := NewMetricsStore(metricFamilies, composeMetricGenFuncs(metricFamilies))
+		if b.keepState {
+			store.WithState(isNamespaced(expectedType))
+		}
+		observed := b.observe(expectedType, store)
+
+		namespaces := map[string]struct{}{}
+		for _, ns := range b.namespaces {
+			namespaces[ns] = struct{}{}
+		}
+		for _, o := range objects {
+			if reflect.TypeOf(o) != reflect.TypeOf(expectedType) {
+				continue
+			}
+			if len(b.namespaces) > 0 && !isAllNamespaces(b.namespaces) && isNamespaced(expectedType) {
+				if _, ok := namespaces[o.(metav1.Object).GetNamespace()]; !ok {
+					continue
+				}
+			}
+			if err := addStatic(observed, o); err != nil {
+				onError(o, err)
+			}
+		}
+		return []*MetricsStore{store}
+	}
 }
 
-func (b *Builder) buildStatefulSetStores() []*metricsstore.MetricsStore {
-	return b.buildKruiseStoresFunc(statefulSetMetricFamilies(b.allowAnnotationsList["statefulsets"], b.allowLabelsList["statefulsets"]), &appsv1beta1.StatefulSet{}, createStatefulSetListWatch, b.useAPIServerCache)
+// addStatic adds the object to the store, turning the panics of the metric
+// generators on unexpected objects into errors.
+func addStatic(store cache.Store, obj interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("generate metrics: %v", r)
+		}
+	}()
+	return store.Add(obj)
 }
 
-func (b *Builder) buildSidecarSetStores() []*metricsstore.MetricsStore {
-	return b.buildKruiseStoresFunc(sidecarSetMetricFamilies(b.allowAnnotationsList["sidecarsets"], b.allowLabelsList["sidecarsets"]), &appsv1alpha1.SidecarSet{}, createSidecarSetListWatch, b.useAPIServerCache)
+func (b *Builder) buildCloneSetStores() []*MetricsStore {
+	families := cloneSetMetricFamilies(b.allowAnnotationsList["clonesets"], b.allowLabelsList["clonesets"])
+	families = append(families, cloneSetRolloutMetricFamilies(b.rollouts)...)
+	families = append(families, cloneSetTransitionMetricFamilies(b.transitions)...)
+	if b.podMetrics {
+		families = append(families, cloneSetPodUpdateMetricFamilies(b.transitions)...)
+	}
+	if len(b.conditionReasons) > 0 {
+		families = append(families, cloneSetConditionReasonMetricFamilies(b.conditionReasons)...)
+	}
+	return b.buildKruiseStoresFunc(families, &appsv1alpha1.CloneSet{}, b.selectedListWatch("clonesets", createCloneSetListWatch), b.useAPIServerCache)
 }
 
-func (b *Builder) buildWorkloadSpreadStores() []*metricsstore.MetricsStore {
-	return b.buildKruiseStoresFunc(workloadSpreadMetricFamilies(b.allowAnnotationsList["workloadspreads"], b.allowLabelsList["workloadspreads"]), &appsv1alpha1.WorkloadSpread{}, createWorkloadSpreadListWatch, b.useAPIServerCache)
+func (b *Builder) buildStatefulSetStores() []*MetricsStore {
+	families := statefulSetMetricFamilies(b.allowAnnotationsList["statefulsets"], b.allowLabelsList["statefulsets"])
+	families = append(families, statefulSetRolloutMetricFamilies(b.rollouts)...)
+	families = append(families, statefulSetTransitionMetricFamilies(b.transitions)...)
+	if b.podMetrics {
+		families = append(families, statefulSetPodUpdateMetricFamilies(b.transitions)...)
+	}
+	if len(b.conditionReasons) > 0 {
+		families = append(families, statefulSetConditionReasonMetricFamilies(b.conditionReasons)...)
+	}
+	return b.buildKruiseStoresFunc(families, &appsv1beta1.StatefulSet{}, b.selectedListWatch("statefulsets", createStatefulSetListWatch), b.useAPIServerCache)
 }
 
-func (b *Builder) buildDaemonSetStores() []*metricsstore.MetricsStore {
-	return b.buildKruiseStoresFunc(daemonSetMetricFamilies(b.allowAnnotationsList["daemonsets"], b.allowLabelsList["daemonsets"]), &appsv1alpha1.DaemonSet{}, createDaemonSetListWatch, b.useAPIServerCache)
+func (b *Builder) buildSidecarSetStores() []*MetricsStore {
+	return b.buildKruiseStoresFunc(append(sidecarSetMetricFamilies(b.allowAnnotationsList["sidecarsets"], b.allowLabelsList["sidecarsets"]), sidecarSetRolloutMetricFamilies(b.rollouts)...), &appsv1alpha1.SidecarSet{}, b.selectedListWatch("sidecarsets", createSidecarSetListWatch), b.useAPIServerCache)
 }
 
-func (b *Builder) buildBroadcastJob() []*metricsstore.MetricsStore {
-	return b.buildKruiseStoresFunc(broadcastJobMetricFamilies(b.allowAnnotationsList["broadcastjobs"], b.allowLabelsList["broadcastjobs"]), &appsv1alpha1.BroadcastJob{}, createBroadcastJobListWatch, b.useAPIServerCache)
+func (b *Builder) buildWorkloadSpreadStores() []*MetricsStore {
+	return b.buildKruiseStoresFunc(workloadSpreadMetricFamilies(b.allowAnnotationsList["workloadspreads"], b.allowLabelsList["workloadspreads"]), &appsv1alpha1.WorkloadSpread{}, b.selectedListWatch("workloadspreads", createWorkloadSpreadListWatch), b.useAPIServerCache)
 }
 
-func (b *Builder) buildContainerRecreateRequest() []*metricsstore.MetricsStore {
-	return b.buildKruiseStoresFunc(containerRecreateRequestMetricFamilies(b.allowAnnotationsList["containerrecreaterequests"], b.allowLabelsList["containerrecreaterequests"]), &appsv1alpha1.ContainerRecreateRequest{}, createContainerRecreateRequestListWatch, b.useAPIServerCache)
+func (b *Builder) buildDaemonSetStores() []*MetricsStore {
+	families := append(daemonSetMetricFamilies(b.allowAnnotationsList["daemonsets"], b.allowLabelsList["daemonsets"]), daemonSetRolloutMetricFamilies(b.rollouts)...)
+	if len(b.conditionReasons) > 0 {
+		families = append(families, daemonSetConditionReasonMetricFamilies(b.conditionReasons)...)
+	}
+	return b.buildKruiseStoresFunc(families, &appsv1alpha1.DaemonSet{}, b.selectedListWatch("daemonsets", createDaemonSetListWatch), b.useAPIServerCache)
+}
+
+func (b *Builder) buildBroadcastJob() []*MetricsStore {
+	families := broadcastJobMetricFamilies(b.allowAnnotationsList["broadcastjobs"], b.allowLabelsList["broadcastjobs"])
+	if len(b.conditionReasons) > 0 {
+		families = append(families, broadcastJobConditionReasonMetricFamilies(b.conditionReasons)...)
+	}
+	return b.buildKruiseStoresFunc(families, &appsv1alpha1.BroadcastJob{}, b.selectedListWatch("broadcastjobs", createBroadcastJobListWatch), b.useAPIServerCache)
+}
+
+func (b *Builder) buildContainerRecreateRequest() []*MetricsStore {
+	return b.buildKruiseStoresFunc(containerRecreateRequestMetricFamilies(b.allowAnnotationsList["containerrecreaterequests"], b.allowLabelsList["containerrecreaterequests"]), &appsv1alpha1.ContainerRecreateRequest{}, b.selectedListWatch("containerrecreaterequests", createContainerRecreateRequestListWatch), b.useAPIServerCache)
+}
+
+func (b *Builder) buildPodUnavailableBudgetStores() []*MetricsStore {
+	return b.buildKruiseStoresFunc(podUnavailableBudgetMetricFamilies(b.allowAnnotationsList["podunavailablebudgets"], b.allowLabelsList["podunavailablebudgets"]), &policyv1alpha1.PodUnavailableBudget{}, b.selectedListWatch("podunavailablebudgets", createPodUnavailableBudgetListWatch), b.useAPIServerCache)
 }
 
 func (b *Builder) buildKruiseStores(
-	metricFamilies []generator.FamilyGenerator,
+	metricFamilies []FamilyGenerator,
 	expectedType interface{},
 	listWatchFunc func(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher,
 	useAPIServerCache bool,
-) []*metricsstore.MetricsStore {
-	metricFamilies = generator.FilterMetricFamilies(b.allowDenyList, metricFamilies)
-	composedMetricGenFuncs := generator.ComposeMetricGenFuncs(metricFamilies)
-	familyHeaders := generator.ExtractMetricFamilyHeaders(metricFamilies)
-
-	if isAllNamespaces(b.namespaces) {
-		store := metricsstore.NewMetricsStore(
-			familyHeaders,
-			composedMetricGenFuncs,
-		)
-		listWatcher := listWatchFunc(b.kruiseClient, v1.NamespaceAll)
-		b.startReflector(expectedType, store, listWatcher, useAPIServerCache)
-		return []*metricsstore.MetricsStore{store}
-	}
-
-	stores := make([]*metricsstore.MetricsStore, 0, len(b.namespaces))
-	for _, ns := range b.namespaces {
-		store := metricsstore.NewMetricsStore(
-			familyHeaders,
-			composedMetricGenFuncs,
-		)
-		listWatcher := listWatchFunc(b.kruiseClient, ns)
-		b.startReflector(expectedType, store, listWatcher, useAPIServerCache)
-		stores = append(stores, store)
+) []*MetricsStore {
+	metricFamilies = filterMetricFamilies(b.allowDenyList, metricFamilies)
+	composedMetricGenFuncs := composeMetricGenFuncs(metricFamilies)
+
+	return buildReflectedStores(b, expectedType, func(ns string) cache.ListerWatcher {
+		return listWatchFunc(b.kruiseClient, ns)
+	}, useAPIServerCache, func() (*MetricsStore, cache.Store) {
+		store, reflectorStore := b.newMetricsStore(metricFamilies, composedMetricGenFuncs)
+		if b.keepState {
+			store.WithState(isNamespaced(expectedType))
+		}
+		rollouts := &rolloutStore{Store: reflectorStore, tracker: b.rollouts}
+		go rollouts.run(b.ctx)
+		return store, b.observe(expectedType, &transitionStore{Store: rollouts, tracker: b.transitions})
+	})
+}
+
+// observe wraps the store of the objects of the given type so that they are
+// also passed to the observers of that type, e.g. the workloads summary.
+func (b *Builder) observe(expectedType interface{}, s cache.Store) cache.Store {
+	switch expectedType.(type) {
+	case *appsv1alpha1.CloneSet, *appsv1beta1.StatefulSet, *appsv1alpha1.DaemonSet:
+		if b.summary != nil {
+			s = newObservedStore[metav1.Object](s, b.summary)
+		}
+		if b.slos != nil {
+			s = newObservedStore[metav1.Object](s, b.slos)
+		}
+	case *appsv1alpha1.SidecarSet:
+		if b.injections != nil {
+			s = newObservedStore[*appsv1alpha1.SidecarSet](s, b.injections)
+		}
+	case *appsv1alpha1.WorkloadSpread:
+		if b.spreads != nil {
+			s = newObservedStore[*appsv1alpha1.WorkloadSpread](s, b.spreads)
+		}
+	case *policyv1alpha1.PodUnavailableBudget:
+		if b.pubs != nil {
+			s = newObservedStore[*policyv1alpha1.PodUnavailableBudget](s, b.pubs)
+		}
 	}
-
-	return stores
+	return s
 }
 
 func (b *Builder) buildStores(
@@ -302,30 +729,67 @@ func (b *Builder) buildStores(
 	composedMetricGenFuncs := generator.ComposeMetricGenFuncs(metricFamilies)
 	familyHeaders := generator.ExtractMetricFamilyHeaders(metricFamilies)
 
-	if isAllNamespaces(b.namespaces) {
-		store := metricsstore.NewMetricsStore(
-			familyHeaders,
-			composedMetricGenFuncs,
-		)
-		listWatcher := listWatchFunc(b.kubeClient, v1.NamespaceAll)
-		b.startReflector(expectedType, store, listWatcher, useAPIServerCache)
-		return []*metricsstore.MetricsStore{store}
+	return buildReflectedStores(b, expectedType, func(ns string) cache.ListerWatcher {
+		return listWatchFunc(b.kubeClient, ns)
+	}, useAPIServerCache, func() (*metricsstore.MetricsStore, cache.Store) {
+		if b.seriesLimiter == nil {
+			store := metricsstore.NewMetricsStore(familyHeaders, composedMetricGenFuncs)
+			return store, store
+		}
+		owner := b.seriesLimiter.newOwner()
+		store := metricsstore.NewMetricsStore(familyHeaders, b.seriesLimiter.wrap(owner, composedMetricGenFuncs))
+		return store, b.seriesLimiter.newStore(owner, store)
+	})
+}
+
+// buildReflectedStores creates stores with newStore and starts the reflectors
+// filling them, one store per namespace unless all namespaces are watched at
+// once. newStore returns the store together with the cache.Store the
+// reflectors should write to.
+func buildReflectedStores[S any](
+	b *Builder,
+	expectedType interface{},
+	listWatchFunc func(ns string) cache.ListerWatcher,
+	useAPIServerCache bool,
+	newStore func() (S, cache.Store),
+) []S {
+	if b.namespaceWatcher != nil && isNamespaced(expectedType) {
+		store, reflectorStore := newStore()
+		b.namespaceWatcher.register(newNamespacedReflectors(b.ctx, reflectorStore, func(ctx context.Context, s cache.Store, ns string) {
+			b.runReflector(ctx, expectedType, s, listWatchFunc(ns), useAPIServerCache)
+		}))
+		return []S{store}
+	}
+
+	if b.namespaceWatcher != nil || isAllNamespaces(b.namespaces) {
+		store, reflectorStore := newStore()
+		b.startReflector(expectedType, reflectorStore, listWatchFunc(v1.NamespaceAll), useAPIServerCache)
+		return []S{store}
 	}
 
-	stores := make([]*metricsstore.MetricsStore, 0, len(b.namespaces))
+	stores := make([]S, 0, len(b.namespaces))
 	for _, ns := range b.namespaces {
-		store := metricsstore.NewMetricsStore(
-			familyHeaders,
-			composedMetricGenFuncs,
-		)
-		listWatcher := listWatchFunc(b.kubeClient, ns)
-		b.startReflector(expectedType, store, listWatcher, useAPIServerCache)
+		store, reflectorStore := newStore()
+		b.startReflector(expectedType, reflectorStore, listWatchFunc(ns), useAPIServerCache)
 		stores = append(stores, store)
 	}
 
 	return stores
 }
 
+// newMetricsStore returns a new MetricsStore together with the cache.Store the
+// reflector should write to. The latter enforces the series limits if any.
+func (b *Builder) newMetricsStore(families []FamilyGenerator, generateFunc func(interface{}) []metric.FamilyInterface) (*MetricsStore, cache.Store) {
+	if b.seriesLimiter == nil {
+		store := NewMetricsStore(families, generateFunc)
+		return store, store
+	}
+
+	owner := b.seriesLimiter.newOwner()
+	store := NewMetricsStore(families, b.seriesLimiter.wrap(owner, generateFunc))
+	return store, b.seriesLimiter.newStore(owner, store)
+}
+
 // startReflector starts a Kubernetes client-go reflector with the given
 // listWatcher and registers it with the given store.
 func (b *Builder) startReflector(
@@ -333,10 +797,26 @@ func (b *Builder) startReflector(
 	store cache.Store,
 	listWatcher cache.ListerWatcher,
 	useAPIServerCache bool,
+) {
+	b.runReflector(b.ctx, expectedType, store, listWatcher, useAPIServerCache)
+}
+
+// runReflector starts a reflector like startReflector which stops when the
+// given context is done.
+func (b *Builder) runReflector(
+	ctx context.Context,
+	expectedType interface{},
+	store cache.Store,
+	listWatcher cache.ListerWatcher,
+	useAPIServerCache bool,
 ) {
 	instrumentedListWatch := watch.NewInstrumentedListerWatcher(listWatcher, b.listWatchMetrics, reflect.TypeOf(expectedType).String(), useAPIServerCache)
-	reflector := cache.NewReflector(sharding.NewShardedListWatch(b.shard, b.totalShards, instrumentedListWatch), expectedType, store, 0)
-	go reflector.Run(b.ctx.Done())
+	var lw cache.ListerWatcher = instrumentedListWatch
+	if _, ok := listWatcher.(unshardedListWatch); !ok {
+		lw = newShardedListWatch(b.shard, b.totalShards, b.shardFunc, instrumentedListWatch)
+	}
+	reflector := cache.NewReflector(lw, expectedType, store, 0)
+	go reflector.Run(ctx.Done())
 }
 
 // isAllNamespaces checks if the given slice of namespaces
@@ -0,0 +1,114 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"testing"
+
+	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestWithResourceSelectors(t *testing.T) {
+	tests := []struct {
+		name           string
+		labelSelectors map[string]string
+		fieldSelectors map[string]string
+		wantErr        bool
+		wantLabel      map[string]string
+		wantField      map[string]string
+	}{
+		{
+			name:           "per resource selectors",
+			labelSelectors: map[string]string{"clonesets": "monitoring.example.com/enabled=true"},
+			fieldSelectors: map[string]string{"statefulsets": "metadata.namespace!=kube-system"},
+			wantLabel:      map[string]string{"clonesets": "monitoring.example.com/enabled=true", "statefulsets": ""},
+			wantField:      map[string]string{"clonesets": "", "statefulsets": "metadata.namespace!=kube-system"},
+		},
+		{
+			name:           "wildcard selectors",
+			labelSelectors: map[string]string{"*": "team=a", "clonesets": "team=b"},
+			fieldSelectors: map[string]string{"*": "metadata.namespace!=kube-system"},
+			wantLabel:      map[string]string{"clonesets": "team=b", "statefulsets": "team=a"},
+			wantField:      map[string]string{"clonesets": "metadata.namespace!=kube-system", "statefulsets": "metadata.namespace!=kube-system"},
+		},
+		{
+			name:           "unknown resource",
+			labelSelectors: map[string]string{"deployments": "team=a"},
+			wantErr:        true,
+		},
+		{
+			name:           "invalid label selector",
+			labelSelectors: map[string]string{"clonesets": "team in a"},
+			wantErr:        true,
+		},
+		{
+			name:           "invalid field selector",
+			fieldSelectors: map[string]string{"clonesets": "metadata.name"},
+			wantErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := NewBuilder()
+			err := b.WithResourceSelectors(test.labelSelectors, test.fieldSelectors)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+			if test.wantErr {
+				return
+			}
+
+			for resource, wantLabel := range test.wantLabel {
+				var gotLabel, gotField string
+				listWatch := b.selectedListWatch(resource, func(_ kruiseclientset.Interface, _ string, labelSelector, fieldSelector string) cache.ListerWatcher {
+					gotLabel, gotField = labelSelector, fieldSelector
+					return nil
+				})
+				listWatch(nil, "")
+				if gotLabel != wantLabel {
+					t.Errorf("expected label selector %q for %s, got %q", wantLabel, resource, gotLabel)
+				}
+				if gotField != test.wantField[resource] {
+					t.Errorf("expected field selector %q for %s, got %q", test.wantField[resource], resource, gotField)
+				}
+			}
+		})
+	}
+}
+
+func TestWithEnabledResources(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		resources []string
+		wantErr   bool
+	}{
+		{name: "resources", resources: []string{"statefulsets", "clonesets"}},
+		{name: "unknown resource", resources: []string{"deployments"}, wantErr: true},
+		{name: "summary without workloads", resources: []string{"workloadssummary"}, wantErr: true},
+		{name: "slos of the workloads", resources: []string{"daemonsets", "workloadslos"}},
+		{name: "slos without workloads", resources: []string{"sidecarsets", "workloadslos"}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewBuilder().WithEnabledResources(test.resources)
+			if (err != nil) != test.wantErr {
+				t.Errorf("expected error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
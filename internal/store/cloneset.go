@@ -28,7 +28,6 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/kube-state-metrics/v2/pkg/metric"
-	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
 )
 
 var (
@@ -45,9 +44,9 @@ var (
 	}
 )
 
-func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []generator.FamilyGenerator {
-	return []generator.FamilyGenerator{
-		*generator.NewFamilyGenerator(
+func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
 			"kruise_cloneset_created",
 			"Unix creation timestamp",
 			metric.Gauge,
@@ -66,7 +65,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_status_replicas",
 			"The number of replicas per cloneset.",
 			metric.Gauge,
@@ -81,7 +80,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_status_replicas_available",
 			"The number of available replicas per cloneset.",
 			metric.Gauge,
@@ -96,7 +95,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_status_replicas_unavailable",
 			"The number of unavailable replicas per cloneset.",
 			metric.Gauge,
@@ -111,7 +110,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_status_replicas_updated",
 			"The number of updated replicas per cloneset.",
 			metric.Gauge,
@@ -126,7 +125,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_status_observed_generation",
 			"The generation observed by the cloneset controller.",
 			metric.Gauge,
@@ -141,32 +140,29 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_status_condition",
 			"The current status conditions of a cloneset.",
 			metric.Gauge,
 			"",
 			wrapCloneSetFunc(func(cs *v1alpha1.CloneSet) *metric.Family {
-				ms := make([]*metric.Metric, len(cs.Status.Conditions)*len(conditionStatuses))
-
-				for i, cs := range cs.Status.Conditions {
-					conditionMetrics := addConditionMetrics(cs.Status)
-
-					for j, m := range conditionMetrics {
-						metric := m
-
-						metric.LabelKeys = []string{"condition", "status"}
-						metric.LabelValues = append([]string{string(cs.Type)}, metric.LabelValues...)
-						ms[i*len(conditionStatuses)+j] = metric
-					}
+				return &metric.Family{
+					Metrics: addStatusConditionMetrics(cloneSetConditions(cs)),
 				}
-
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_cloneset_status_condition_last_transition_time",
+			"Unix timestamp of the last transition of the current status conditions of a cloneset.",
+			metric.Gauge,
+			"",
+			wrapCloneSetFunc(func(cs *v1alpha1.CloneSet) *metric.Family {
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: addConditionLastTransitionTimeMetrics(cloneSetConditions(cs)),
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_spec_replicas",
 			"Number of desired pods for a cloneset.",
 			metric.Gauge,
@@ -181,7 +177,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_spec_strategy_rollingupdate_max_unavailable",
 			"Maximum number of unavailable replicas during a rolling update of a cloneset.",
 			metric.Gauge,
@@ -205,7 +201,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_spec_strategy_rollingupdate_max_surge",
 			"Maximum number of replicas that can be scheduled above the desired number of replicas during a rolling update of a cloneset.",
 			metric.Gauge,
@@ -229,7 +225,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_metadata_generation",
 			"Sequence number representing a specific generation of the desired state.",
 			metric.Gauge,
@@ -244,7 +240,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descCloneSetAnnotationsName,
 			descCloneSetAnnotationsHelp,
 			metric.Gauge,
@@ -262,7 +258,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descCloneSetLabelsName,
 			descCloneSetLabelsHelp,
 			metric.Gauge,
@@ -280,7 +276,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_status_replicas_ready",
 			"The number of ready replicas per cloneset.",
 			metric.Gauge,
@@ -295,7 +291,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_status_replicas_updated_ready",
 			"The number of update and ready replicas per cloneset.",
 			metric.Gauge,
@@ -310,7 +306,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_spec_strategy_partition",
 			"Desired number or percent of Pods in old revisions.",
 			metric.Gauge,
@@ -330,7 +326,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_cloneset_spec_strategy_type",
 			"The type of updateStrategy.",
 			metric.Gauge,
@@ -342,7 +338,7 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 					ms[i] = &metric.Metric{
 						LabelKeys:   []string{"strategy_type"},
 						LabelValues: []string{string(t)},
-						Value:       boolFloat64(cs.Spec.UpdateStrategy.Type == t),
+						Value:       BoolFloat64(cs.Spec.UpdateStrategy.Type == t),
 					}
 				}
 
@@ -354,6 +350,65 @@ func cloneSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []ge
 	}
 }
 
+// cloneSetRolloutProgress returns the rollout progress of a cloneset,
+// the replicas in old revisions are set by the partition.
+func cloneSetRolloutProgress(cs *v1alpha1.CloneSet) rolloutProgress {
+	replicas := int32(1)
+	if cs.Spec.Replicas != nil {
+		replicas = *cs.Spec.Replicas
+	}
+	partition := 0
+	if cs.Spec.UpdateStrategy.Partition != nil {
+		// Errors leave the partition at 0 like the controller.
+		partition, _ = intstr.GetScaledValueFromIntOrPercent(cs.Spec.UpdateStrategy.Partition, int(replicas), true)
+	}
+	return rolloutProgress{
+		target:             max(replicas-int32(partition), 0),
+		updated:            cs.Status.UpdatedReplicas,
+		updatedReady:       cs.Status.UpdatedReadyReplicas,
+		ready:              cs.Status.ReadyReplicas,
+		generation:         cs.Generation,
+		observedGeneration: cs.Status.ObservedGeneration,
+	}
+}
+
+// cloneSetRolloutMetricFamilies returns the rollout families of clonesets.
+func cloneSetRolloutMetricFamilies(tracker *rolloutTracker) []FamilyGenerator {
+	return rolloutMetricFamilies("cloneset", tracker, cloneSetRolloutProgress, wrapCloneSetFunc)
+}
+
+// cloneSetTransitionMetricFamilies returns the transition counters of
+// clonesets.
+func cloneSetTransitionMetricFamilies(tracker *transitionTracker) []FamilyGenerator {
+	return transitionMetricFamilies("cloneset", tracker, wrapCloneSetFunc)
+}
+
+// cloneSetPodUpdateMetricFamilies returns the pod update counters of
+// clonesets.
+func cloneSetPodUpdateMetricFamilies(tracker *transitionTracker) []FamilyGenerator {
+	return podUpdateMetricFamilies("cloneset", tracker, wrapCloneSetFunc)
+}
+
+// cloneSetConditions returns the status conditions of a cloneset.
+func cloneSetConditions(cs *v1alpha1.CloneSet) []statusCondition {
+	conditions := make([]statusCondition, len(cs.Status.Conditions))
+	for i, c := range cs.Status.Conditions {
+		conditions[i] = statusCondition{
+			conditionType:      string(c.Type),
+			status:             c.Status,
+			reason:             c.Reason,
+			lastTransitionTime: c.LastTransitionTime,
+		}
+	}
+	return conditions
+}
+
+// cloneSetConditionReasonMetricFamilies returns the family of the reasons of
+// the status conditions of clonesets.
+func cloneSetConditionReasonMetricFamilies(reasons []string) []FamilyGenerator {
+	return conditionReasonMetricFamilies("cloneset", reasons, cloneSetConditions, wrapCloneSetFunc)
+}
+
 func wrapCloneSetFunc(f func(*v1alpha1.CloneSet) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		cloneset := obj.(*v1alpha1.CloneSet)
@@ -369,12 +424,16 @@ func wrapCloneSetFunc(f func(*v1alpha1.CloneSet) *metric.Family) func(interface{
 	}
 }
 
-func createCloneSetListWatch(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+func createCloneSetListWatch(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().CloneSets(ns).List(context.TODO(), opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().CloneSets(ns).Watch(context.TODO(), opts)
 		},
 	}
@@ -26,7 +26,6 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/kube-state-metrics/v2/pkg/metric"
-	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
 )
 
 var (
@@ -37,9 +36,9 @@ var (
 	descContainerRecreateRequestLabelsDefaultLabels = []string{"namespace", "containerrecreaterequest"}
 )
 
-func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsList []string) []generator.FamilyGenerator {
-	return []generator.FamilyGenerator{
-		*generator.NewFamilyGenerator(
+func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
 			descContainerRecreateRequestAnnotationsName,
 			descContainerRecreateRequestAnnotationsHelp,
 			metric.Gauge,
@@ -57,7 +56,7 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descContainerRecreateRequestLabelsName,
 			descContainerRecreateRequestLabelsHelp,
 			metric.Gauge,
@@ -75,7 +74,7 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_containerrecreaterequest_created",
 			"Unix creation timestamp",
 			metric.Gauge,
@@ -94,7 +93,7 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_containerrecreaterequest_containers_pending",
 			"The number of containers which reached Phase Pending.",
 			metric.Gauge,
@@ -115,7 +114,7 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_containerrecreaterequest_containers_recreating",
 			"The number of containers which reached Phase Recreating.",
 			metric.Gauge,
@@ -136,7 +135,7 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_containerrecreaterequest_containers_succeeded",
 			"The number of containers which reached Phase Succeeded.",
 			metric.Gauge,
@@ -157,7 +156,7 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_containerrecreaterequest_containers_failed",
 			"The number of containers which reached Phase Failed.",
 			metric.Gauge,
@@ -178,7 +177,7 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_containerrecreaterequest_pending",
 			"The number of CRR which reached Phase Pending.",
 			metric.Gauge,
@@ -187,13 +186,13 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				return &metric.Family{
 					Metrics: []*metric.Metric{
 						{
-							Value: boolFloat64(crr.Status.Phase == v1alpha1.ContainerRecreateRequestPending),
+							Value: BoolFloat64(crr.Status.Phase == v1alpha1.ContainerRecreateRequestPending),
 						},
 					},
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_containerrecreaterequest_recreating",
 			"The number of CRR which reached Phase Recreating.",
 			metric.Gauge,
@@ -202,13 +201,13 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				return &metric.Family{
 					Metrics: []*metric.Metric{
 						{
-							Value: boolFloat64(crr.Status.Phase == v1alpha1.ContainerRecreateRequestRecreating),
+							Value: BoolFloat64(crr.Status.Phase == v1alpha1.ContainerRecreateRequestRecreating),
 						},
 					},
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_containerrecreaterequest_completed",
 			"The number of CRR which reached Phase Completed.",
 			metric.Gauge,
@@ -217,7 +216,7 @@ func containerRecreateRequestMetricFamilies(allowAnnotationsList, allowLabelsLis
 				return &metric.Family{
 					Metrics: []*metric.Metric{
 						{
-							Value: boolFloat64(crr.Status.Phase == v1alpha1.ContainerRecreateRequestCompleted),
+							Value: BoolFloat64(crr.Status.Phase == v1alpha1.ContainerRecreateRequestCompleted),
 						},
 					},
 				}
@@ -241,12 +240,16 @@ func wrapContainerRecreateRequestFunc(f func(*v1alpha1.ContainerRecreateRequest)
 	}
 }
 
-func createContainerRecreateRequestListWatch(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+func createContainerRecreateRequestListWatch(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().ContainerRecreateRequests(ns).List(context.TODO(), opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().ContainerRecreateRequests(ns).Watch(context.TODO(), opts)
 		},
 	}
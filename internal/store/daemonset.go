@@ -30,7 +30,6 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/kube-state-metrics/v2/pkg/metric"
-	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
 )
 
 var (
@@ -41,9 +40,9 @@ var (
 	descDaemonSetLabelsDefaultLabels = []string{"namespace", "daemonset"}
 )
 
-func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []generator.FamilyGenerator {
-	return []generator.FamilyGenerator{
-		*generator.NewFamilyGenerator(
+func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
 			"kruise_daemonset_created",
 			"Unix creation timestamp",
 			metric.Gauge,
@@ -62,32 +61,29 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_condition",
 			"The current status conditions of a daemonset.",
 			metric.Gauge,
 			"",
 			wrapDaemonSetFunc(func(ds *v1alpha1.DaemonSet) *metric.Family {
-				ms := make([]*metric.Metric, len(ds.Status.Conditions)*len(conditionStatuses))
-
-				for i, ds := range ds.Status.Conditions {
-					conditionMetrics := addConditionMetrics(ds.Status)
-
-					for j, m := range conditionMetrics {
-						metric := m
-
-						metric.LabelKeys = []string{"condition", "status"}
-						metric.LabelValues = append([]string{string(ds.Type)}, metric.LabelValues...)
-						ms[i*len(conditionStatuses)+j] = metric
-					}
+				return &metric.Family{
+					Metrics: addStatusConditionMetrics(daemonSetConditions(ds)),
 				}
-
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_daemonset_status_condition_last_transition_time",
+			"Unix timestamp of the last transition of the current status conditions of a daemonset.",
+			metric.Gauge,
+			"",
+			wrapDaemonSetFunc(func(ds *v1alpha1.DaemonSet) *metric.Family {
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: addConditionLastTransitionTimeMetrics(daemonSetConditions(ds)),
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_spec_strategy_rollingupdate_max_surge",
 			"Maximum number of replicas that can be scheduled above the desired number of replicas during a rolling update of a daemonset.",
 			metric.Gauge,
@@ -111,7 +107,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_spec_strategy_partition",
 			"Desired number or percent of Pods in old revisions.",
 			metric.Gauge,
@@ -131,7 +127,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_spec_strategy_type",
 			"The type of updateStrategy.",
 			metric.Gauge,
@@ -148,7 +144,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 			}),
 		),
 
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_current_number_scheduled",
 			"The number of nodes running at least one daemon pod and are supposed to.",
 			metric.Gauge,
@@ -163,7 +159,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_desired_number_scheduled",
 			"The number of nodes that should be running the daemon pod.",
 			metric.Gauge,
@@ -178,7 +174,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_number_available",
 			"The number of nodes that should be running the daemon pod and have one or more of the daemon pod running and available",
 			metric.Gauge,
@@ -193,7 +189,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_number_misscheduled",
 			"The number of nodes running a daemon pod but are not supposed to.",
 			metric.Gauge,
@@ -208,7 +204,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_number_ready",
 			"The number of nodes that should be running the daemon pod and have one or more of the daemon pod running and ready.",
 			metric.Gauge,
@@ -223,7 +219,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_number_unavailable",
 			"The number of nodes that should be running the daemon pod and have none of the daemon pod running and available",
 			metric.Gauge,
@@ -238,7 +234,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_observed_generation",
 			"The most recent generation observed by the daemon set controller.",
 			metric.Gauge,
@@ -253,7 +249,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_status_updated_number_scheduled",
 			"The total number of nodes that are running updated daemon pod",
 			metric.Gauge,
@@ -268,7 +264,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_daemonset_metadata_generation",
 			"Sequence number representing a specific generation of the desired state.",
 			metric.Gauge,
@@ -283,7 +279,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descDaemonSetAnnotationsName,
 			descDaemonSetAnnotationsHelp,
 			metric.Gauge,
@@ -301,7 +297,7 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descDaemonSetLabelsName,
 			descDaemonSetLabelsHelp,
 			metric.Gauge,
@@ -322,6 +318,51 @@ func daemonSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []g
 	}
 }
 
+// daemonSetRolloutProgress returns the rollout progress of an advanced
+// daemonset, the nodes in old revisions are set by the partition.
+func daemonSetRolloutProgress(ds *v1alpha1.DaemonSet) rolloutProgress {
+	partition := int32(0)
+	if ds.Spec.UpdateStrategy.RollingUpdate != nil && ds.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		partition = *ds.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
+	return rolloutProgress{
+		target:  max(ds.Status.DesiredNumberScheduled-partition, 0),
+		updated: ds.Status.UpdatedNumberScheduled,
+		// Advanced daemonsets don't count their updated and ready pods,
+		// the updated pods are ready at the latest when all pods are.
+		updatedReady:       min(ds.Status.UpdatedNumberScheduled, ds.Status.NumberReady),
+		ready:              ds.Status.NumberReady,
+		generation:         ds.Generation,
+		observedGeneration: ds.Status.ObservedGeneration,
+	}
+}
+
+// daemonSetRolloutMetricFamilies returns the rollout families of advanced
+// daemonsets.
+func daemonSetRolloutMetricFamilies(tracker *rolloutTracker) []FamilyGenerator {
+	return rolloutMetricFamilies("daemonset", tracker, daemonSetRolloutProgress, wrapDaemonSetFunc)
+}
+
+// daemonSetConditions returns the status conditions of a daemonset.
+func daemonSetConditions(ds *v1alpha1.DaemonSet) []statusCondition {
+	conditions := make([]statusCondition, len(ds.Status.Conditions))
+	for i, c := range ds.Status.Conditions {
+		conditions[i] = statusCondition{
+			conditionType:      string(c.Type),
+			status:             c.Status,
+			reason:             c.Reason,
+			lastTransitionTime: c.LastTransitionTime,
+		}
+	}
+	return conditions
+}
+
+// daemonSetConditionReasonMetricFamilies returns the family of the reasons of
+// the status conditions of advanced daemonsets.
+func daemonSetConditionReasonMetricFamilies(reasons []string) []FamilyGenerator {
+	return conditionReasonMetricFamilies("daemonset", reasons, daemonSetConditions, wrapDaemonSetFunc)
+}
+
 func wrapDaemonSetFunc(f func(*v1alpha1.DaemonSet) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		daemonset := obj.(*v1alpha1.DaemonSet)
@@ -337,12 +378,16 @@ func wrapDaemonSetFunc(f func(*v1alpha1.DaemonSet) *metric.Family) func(interfac
 	}
 }
 
-func createDaemonSetListWatch(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+func createDaemonSetListWatch(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().DaemonSets(ns).List(context.TODO(), opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().DaemonSets(ns).Watch(context.TODO(), opts)
 		},
 	}
@@ -0,0 +1,198 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"sync"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	policyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// eventReasonOther is the reason of the events whose reason is not allowed.
+const eventReasonOther = "Other"
+
+// eventSeries identifies a series of the event counter.
+type eventSeries struct {
+	kind, namespace, name, reason, eventType string
+}
+
+// eventState is the state of an event the counter was incremented from.
+type eventState struct {
+	series eventSeries
+	count  int32
+}
+
+// eventHandler is notified of the new occurrences of the events of the Kruise
+// objects, e.g. to infer metrics of the objects from their events.
+type eventHandler interface {
+	handleEvent(e *v1.Event, occurrences int32)
+}
+
+// eventCounter counts the events of the Kruise objects by object, reason and
+// type. The series of an object are dropped once none of its events is left,
+// so that the cardinality is bounded by the events kept by the apiserver.
+type eventCounter struct {
+	collectorWriter
+	counter *prometheus.CounterVec
+	reasons sets.Set[string]
+	// handlers are notified of the new occurrences of the events.
+	handlers []eventHandler
+
+	// Protects events and refs
+	mutex sync.Mutex
+	// events holds the events seen by UID.
+	events map[types.UID]eventState
+	// refs holds the number of events seen of every series.
+	refs map[eventSeries]int
+}
+
+func newEventCounter(reasons []string) *eventCounter {
+	c := &eventCounter{
+		counter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kruise_object_events_total",
+			Help: "Number of occurrences of the events of the Kruise objects, by reason and type. The reasons which are not allowed are counted as Other.",
+		}, []string{"kind", "namespace", "name", "reason", "type"}),
+		reasons: sets.New(reasons...),
+		events:  map[types.UID]eventState{},
+		refs:    map[eventSeries]int{},
+	}
+	c.collectorWriter = newCollectorWriter("events", c.counter, "kruise_object_events_total")
+	return c
+}
+
+// isKruiseGroup returns whether the objects of the given API version are
+// Kruise objects whose events are counted.
+func isKruiseGroup(apiVersion string) bool {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return false
+	}
+	return gv.Group == appsv1alpha1.GroupVersion.Group || gv.Group == policyv1alpha1.GroupVersion.Group
+}
+
+// eventCount returns the number of occurrences of an event, from its count or
+// its series for the events recorded with the events.k8s.io API.
+func eventCount(e *v1.Event) int32 {
+	count := max(e.Count, 1)
+	if e.Series != nil {
+		count = max(count, e.Series.Count)
+	}
+	return count
+}
+
+// update implements uidHandler. It increments the counter of the series of
+// the event by its new occurrences and passes them to the handlers. All
+// occurrences of the events seen for the first time are counted, including
+// those which happened before kruise-state-metrics started. The events of
+// other objects than the Kruise objects are not kept.
+func (c *eventCounter) update(obj interface{}) (bool, error) {
+	e, ok := obj.(*v1.Event)
+	if !ok || !isKruiseGroup(e.InvolvedObject.APIVersion) {
+		return false, nil
+	}
+	series := eventSeries{
+		kind:      e.InvolvedObject.Kind,
+		namespace: e.InvolvedObject.Namespace,
+		name:      e.InvolvedObject.Name,
+		reason:    e.Reason,
+		eventType: e.Type,
+	}
+	if !c.reasons.Has(series.reason) {
+		series.reason = eventReasonOther
+	}
+	count := eventCount(e)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	last, seen := c.events[e.UID]
+	if !seen {
+		c.refs[series]++
+	}
+	if count > last.count {
+		c.counter.WithLabelValues(series.kind, series.namespace, series.name, series.reason, series.eventType).Add(float64(count - last.count))
+		for _, h := range c.handlers {
+			h.handleEvent(e, count-last.count)
+		}
+		last.count = count
+	}
+	c.events[e.UID] = eventState{series: series, count: last.count}
+	return true, nil
+}
+
+// delete implements uidHandler. It forgets a deleted event, and drops its
+// series if it was the last event of the series.
+func (c *eventCounter) delete(uid types.UID) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	last, ok := c.events[uid]
+	if !ok {
+		return nil
+	}
+	delete(c.events, uid)
+
+	c.refs[last.series]--
+	if c.refs[last.series] > 0 {
+		return nil
+	}
+	delete(c.refs, last.series)
+	s := last.series
+	c.counter.DeleteLabelValues(s.kind, s.namespace, s.name, s.reason, s.eventType)
+	return nil
+}
+
+// buildEventStores starts the event reflectors passing the events to the
+// event counter. The counter is written by its own writer, no MetricsStore is
+// returned. The events are sharded by their involved object, see shardKey.
+func (b *Builder) buildEventStores() []*MetricsStore {
+	labelSelector, fieldSelector := b.selectorsFor("events")
+	buildReflectedStores(b, &v1.Event{}, func(ns string) cache.ListerWatcher {
+		return createEventListWatch(b.kubeClient, ns, labelSelector, fieldSelector)
+	}, b.useAPIServerCache, func() (*uidStore, cache.Store) {
+		s := newUIDStore(b.events)
+		return s, s
+	})
+	return nil
+}
+
+func createEventListWatch(kubeClient clientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
+			return kubeClient.CoreV1().Events(ns).List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
+			return kubeClient.CoreV1().Events(ns).Watch(context.TODO(), opts)
+		},
+	}
+}
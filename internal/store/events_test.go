@@ -0,0 +1,141 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testEvent returns an event of the given count about the cloneset name.
+func testEvent(uid, apiVersion, name, reason string, count int32) *v1.Event {
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: uid, UID: types.UID(uid)},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion: apiVersion,
+			Kind:       "CloneSet",
+			Namespace:  "ns1",
+			Name:       name,
+		},
+		Reason: reason,
+		Type:   v1.EventTypeWarning,
+		Count:  count,
+	}
+}
+
+// eventSamples returns the samples of the event counter.
+func eventSamples(c *eventCounter) []string {
+	buf := &bytes.Buffer{}
+	c.WriteAll(buf)
+	var samples []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "kruise_object_events_total{") {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
+
+func TestEventStore(t *testing.T) {
+	counter := newEventCounter([]string{"FailedCreate", "FailedUpdate"})
+	store := newUIDStore(counter)
+
+	seriesEvent := testEvent("e4", "apps.kruise.io/v1alpha1", "cs2", "FailedUpdate", 0)
+	seriesEvent.Series = &v1.EventSeries{Count: 4}
+	if err := store.Replace([]interface{}{
+		testEvent("e1", "apps.kruise.io/v1alpha1", "cs1", "FailedCreate", 3),
+		testEvent("e2", "apps.kruise.io/v1alpha1", "cs1", "Unknown", 1),
+		testEvent("e3", "apps/v1", "cs1", "FailedCreate", 1),
+		seriesEvent,
+	}, "1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*v1.Event{
+		// Occurrences seen again are not counted again.
+		testEvent("e1", "apps.kruise.io/v1alpha1", "cs1", "FailedCreate", 5),
+		testEvent("e1", "apps.kruise.io/v1alpha1", "cs1", "FailedCreate", 5),
+		testEvent("e5", "apps.kruise.io/v1alpha1", "cs1", "FailedCreate", 1),
+		testEvent("e6", "policy.kruise.io/v1alpha1", "cs1", "Other", 1),
+	} {
+		if err := store.Update(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		`kruise_object_events_total{kind="CloneSet",name="cs1",namespace="ns1",reason="FailedCreate",type="Warning"} 6`,
+		`kruise_object_events_total{kind="CloneSet",name="cs1",namespace="ns1",reason="Other",type="Warning"} 2`,
+		`kruise_object_events_total{kind="CloneSet",name="cs2",namespace="ns1",reason="FailedUpdate",type="Warning"} 4`,
+	}
+	if diff := cmp.Diff(want, eventSamples(counter)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+	// The events of other objects are not tracked.
+	if store.uids.Has("e3") {
+		t.Error("expected the event of a non-Kruise object not to be tracked")
+	}
+
+	// The series are kept while one of their events is left.
+	if err := store.Delete(testEvent("e1", "apps.kruise.io/v1alpha1", "cs1", "FailedCreate", 5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Replace([]interface{}{
+		testEvent("e2", "apps.kruise.io/v1alpha1", "cs1", "Unknown", 1),
+		testEvent("e5", "apps.kruise.io/v1alpha1", "cs1", "FailedCreate", 2),
+	}, "2"); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		`kruise_object_events_total{kind="CloneSet",name="cs1",namespace="ns1",reason="FailedCreate",type="Warning"} 7`,
+		`kruise_object_events_total{kind="CloneSet",name="cs1",namespace="ns1",reason="Other",type="Warning"} 2`,
+	}
+	if diff := cmp.Diff(want, eventSamples(counter)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+	if len(counter.events) != 2 || len(counter.refs) != 2 {
+		t.Errorf("expected the deleted events to be forgotten, got %d events of %d series", len(counter.events), len(counter.refs))
+	}
+}
+
+func TestEventMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := fake.NewSimpleClientset(testEvent("e1", "apps.kruise.io/v1alpha1", "cs1", "FailedCreate", 2))
+	b := newTestBuilder(t, ctx, kubeClient, kruisefake.NewSimpleClientset(), "events")
+	b.WithEventReasons([]string{"FailedCreate"})
+	writers := b.Build()
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_object_events_total{kind="CloneSet",name="cs1",namespace="ns1",reason="FailedCreate",type="Warning"} 2`)
+	})
+
+	if _, err := kubeClient.CoreV1().Events("ns1").Update(ctx, testEvent("e1", "apps.kruise.io/v1alpha1", "cs1", "FailedCreate", 3), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_object_events_total{kind="CloneSet",name="cs1",namespace="ns1",reason="FailedCreate",type="Warning"} 3`)
+	})
+}
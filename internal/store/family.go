@@ -0,0 +1,146 @@
+/*
+Copyright 2026 The Kruise Authors.
+Copyright 2019 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ksmtypes "k8s.io/kube-state-metrics/v2/pkg/builder/types"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
+)
+
+const (
+	unitSeconds = "seconds"
+
+	counterSuffix = "_total"
+	createdSuffix = "_created"
+)
+
+// FamilyGenerator extends generator.FamilyGenerator with the metadata of the
+// family only exposed in the OpenMetrics format.
+type FamilyGenerator struct {
+	generator.FamilyGenerator
+	// Unit is the unit of the family, e.g. seconds. The name of the family
+	// must end with it.
+	Unit string
+	// Start returns when the counters of the family started counting for an
+	// object, exposed in their _created samples. The counters of the
+	// families without Start count from the creation of the object.
+	Start func(obj interface{}) time.Time
+}
+
+// newFamilyGenerator creates a FamilyGenerator without unit.
+func newFamilyGenerator(name string, help string, metricType metric.Type, deprecatedVersion string, generateFunc func(obj interface{}) *metric.Family) FamilyGenerator {
+	return newFamilyGeneratorWithUnit(name, help, metricType, "", deprecatedVersion, generateFunc)
+}
+
+// newFamilyGeneratorWithUnit creates a FamilyGenerator of the given unit.
+func newFamilyGeneratorWithUnit(name string, help string, metricType metric.Type, unit string, deprecatedVersion string, generateFunc func(obj interface{}) *metric.Family) FamilyGenerator {
+	f := FamilyGenerator{
+		FamilyGenerator: *generator.NewFamilyGenerator(name, help, metricType, deprecatedVersion, generateFunc),
+		Unit:            unit,
+	}
+	if unit != "" && !strings.HasSuffix(f.openMetricsName(), "_"+unit) {
+		panic(fmt.Sprintf("metric family %s does not end with its unit %s", name, unit))
+	}
+	return f
+}
+
+// openMetricsName returns the name of the family in the OpenMetrics format,
+// which does not include the suffix of the counter samples.
+func (f *FamilyGenerator) openMetricsName() string {
+	if f.Type == metric.Counter {
+		return strings.TrimSuffix(f.Name, counterSuffix)
+	}
+	return f.Name
+}
+
+// header returns the HELP and TYPE lines of the family in the Prometheus text
+// format.
+func (f *FamilyGenerator) header() string {
+	header := strings.Builder{}
+	header.WriteString("# HELP ")
+	header.WriteString(f.Name)
+	header.WriteByte(' ')
+	header.WriteString(f.Help)
+	header.WriteByte('\n')
+	header.WriteString("# TYPE ")
+	header.WriteString(f.Name)
+	header.WriteByte(' ')
+	header.WriteString(string(f.Type))
+
+	return header.String()
+}
+
+var escapeOpenMetricsHelp = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// openMetricsHeader returns the HELP, TYPE and UNIT lines of the family in
+// the OpenMetrics format.
+func (f *FamilyGenerator) openMetricsHeader() string {
+	name := f.openMetricsName()
+
+	header := strings.Builder{}
+	header.WriteString("# HELP ")
+	header.WriteString(name)
+	header.WriteByte(' ')
+	escapeOpenMetricsHelp.WriteString(&header, f.Help)
+	header.WriteByte('\n')
+	header.WriteString("# TYPE ")
+	header.WriteString(name)
+	header.WriteByte(' ')
+	header.WriteString(string(f.Type))
+	if f.Unit != "" {
+		header.WriteString("\n# UNIT ")
+		header.WriteString(name)
+		header.WriteByte(' ')
+		header.WriteString(f.Unit)
+	}
+
+	return header.String()
+}
+
+// filterMetricFamilies returns the families included by the allow- and
+// denylist.
+func filterMetricFamilies(l ksmtypes.AllowDenyLister, families []FamilyGenerator) []FamilyGenerator {
+	filtered := []FamilyGenerator{}
+
+	for _, f := range families {
+		if l.IsIncluded(f.Name) {
+			filtered = append(filtered, f)
+		}
+	}
+
+	return filtered
+}
+
+// composeMetricGenFuncs returns a function that composes the metric
+// generation functions of the families into a single one.
+func composeMetricGenFuncs(families []FamilyGenerator) func(obj interface{}) []metric.FamilyInterface {
+	return func(obj interface{}) []metric.FamilyInterface {
+		result := make([]metric.FamilyInterface, len(families))
+
+		for i := range families {
+			result[i] = families[i].Generate(obj)
+		}
+
+		return result
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"io"
+	"regexp"
+)
+
+// FilterableWriter is a MetricsWriter for the stores of a single resource
+// which can write a subset of its metrics.
+type FilterableWriter interface {
+	OpenMetricsWriter
+	Resource() string
+	FamilyNames() []string
+	WriteFiltered(w io.Writer, openMetrics bool, f Filter)
+}
+
+// Filter selects the metrics written by a FilterableWriter. The zero value
+// selects all metrics.
+type Filter struct {
+	// Resources selects the resources to write, all if empty.
+	Resources map[string]struct{}
+	// Family selects the metric families to write by name, all if nil.
+	Family *regexp.Regexp
+	// Namespace selects the objects to write by namespace, all if empty.
+	Namespace string
+}
+
+// IsZero returns whether the filter selects all metrics.
+func (f Filter) IsZero() bool {
+	return len(f.Resources) == 0 && f.Family == nil && f.Namespace == ""
+}
+
+func (f Filter) includesResource(resource string) bool {
+	if len(f.Resources) == 0 {
+		return true
+	}
+	_, ok := f.Resources[resource]
+	return ok
+}
+
+func (f Filter) includesFamily(name string) bool {
+	return f.Family == nil || f.Family.MatchString(name)
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-api/apps/v1beta1"
+	policyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
+	"github.com/prometheus/common/expfmt"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+var goldenCreated = metav1.NewTime(time.Unix(1500000000, 0))
+
+func goldenObjectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:         "ns1",
+		Name:              name,
+		UID:               types.UID("uid-" + name),
+		Generation:        3,
+		CreationTimestamp: goldenCreated,
+		Labels:            map[string]string{"app": name},
+		Annotations:       map[string]string{"owner": "team-a"},
+	}
+}
+
+// goldenStores lists a representative object for every store.
+var goldenStores = map[string]struct {
+	families func(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator
+	obj      interface{}
+}{
+	"broadcastjobs": {
+		families: broadcastJobMetricFamilies,
+		obj: &v1alpha1.BroadcastJob{
+			ObjectMeta: goldenObjectMeta("bj1"),
+			Spec: v1alpha1.BroadcastJobSpec{
+				Parallelism: ptr.To(intstr.FromInt32(2)),
+				CompletionPolicy: v1alpha1.CompletionPolicy{
+					Type:                    v1alpha1.Always,
+					ActiveDeadlineSeconds:   ptr.To[int64](600),
+					TTLSecondsAfterFinished: ptr.To[int32](3600),
+				},
+			},
+			Status: v1alpha1.BroadcastJobStatus{
+				Active:    1,
+				Succeeded: 2,
+				Failed:    1,
+				Desired:   4,
+				Conditions: []v1alpha1.JobCondition{
+					{Type: v1alpha1.JobComplete, Status: v1.ConditionFalse, LastTransitionTime: goldenCreated},
+				},
+			},
+		},
+	},
+	"clonesets": {
+		families: withTrackedMetricFamilies(cloneSetMetricFamilies, func(r *rolloutTracker, t *transitionTracker) []FamilyGenerator {
+			return append(cloneSetRolloutMetricFamilies(r), cloneSetTransitionMetricFamilies(t)...)
+		}),
+		obj: &v1alpha1.CloneSet{
+			ObjectMeta: goldenObjectMeta("cs1"),
+			Spec: v1alpha1.CloneSetSpec{
+				Replicas: ptr.To[int32](5),
+				UpdateStrategy: v1alpha1.CloneSetUpdateStrategy{
+					Type:           v1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType,
+					Partition:      ptr.To(intstr.FromInt32(1)),
+					MaxSurge:       ptr.To(intstr.FromString("20%")),
+					MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+				},
+			},
+			Status: v1alpha1.CloneSetStatus{
+				ObservedGeneration:   3,
+				Replicas:             5,
+				ReadyReplicas:        4,
+				AvailableReplicas:    4,
+				UpdatedReplicas:      3,
+				UpdatedReadyReplicas: 3,
+				Conditions: []v1alpha1.CloneSetCondition{
+					{Type: v1alpha1.CloneSetConditionFailedScale, Status: v1.ConditionFalse, LastTransitionTime: goldenCreated},
+				},
+			},
+		},
+	},
+	"containerrecreaterequests": {
+		families: containerRecreateRequestMetricFamilies,
+		obj: &v1alpha1.ContainerRecreateRequest{
+			ObjectMeta: goldenObjectMeta("crr1"),
+			Spec: v1alpha1.ContainerRecreateRequestSpec{
+				PodName:    "pod1",
+				Containers: []v1alpha1.ContainerRecreateRequestContainer{{Name: "app"}, {Name: "sidecar"}},
+			},
+			Status: v1alpha1.ContainerRecreateRequestStatus{
+				Phase: v1alpha1.ContainerRecreateRequestRecreating,
+				ContainerRecreateStates: []v1alpha1.ContainerRecreateRequestContainerRecreateState{
+					{Name: "app", Phase: v1alpha1.ContainerRecreateRequestSucceeded},
+					{Name: "sidecar", Phase: v1alpha1.ContainerRecreateRequestRecreating},
+				},
+			},
+		},
+	},
+	"daemonsets": {
+		families: withTrackedMetricFamilies(daemonSetMetricFamilies, func(r *rolloutTracker, _ *transitionTracker) []FamilyGenerator {
+			return daemonSetRolloutMetricFamilies(r)
+		}),
+		obj: &v1alpha1.DaemonSet{
+			ObjectMeta: goldenObjectMeta("ds1"),
+			Spec: v1alpha1.DaemonSetSpec{
+				UpdateStrategy: v1alpha1.DaemonSetUpdateStrategy{
+					Type: v1alpha1.RollingUpdateDaemonSetStrategyType,
+					RollingUpdate: &v1alpha1.RollingUpdateDaemonSet{
+						MaxSurge:  ptr.To(intstr.FromInt32(0)),
+						Partition: ptr.To[int32](2),
+					},
+				},
+			},
+			Status: v1alpha1.DaemonSetStatus{
+				ObservedGeneration:     3,
+				CurrentNumberScheduled: 3,
+				DesiredNumberScheduled: 3,
+				NumberMisscheduled:     0,
+				NumberReady:            2,
+				NumberAvailable:        2,
+				NumberUnavailable:      1,
+				UpdatedNumberScheduled: 1,
+				Conditions: []appsv1.DaemonSetCondition{
+					{Type: "FailedPlacement", Status: v1.ConditionFalse, LastTransitionTime: goldenCreated},
+				},
+			},
+		},
+	},
+	"podunavailablebudgets": {
+		families: podUnavailableBudgetMetricFamilies,
+		obj: &policyv1alpha1.PodUnavailableBudget{
+			ObjectMeta: goldenObjectMeta("pub1"),
+			Spec: policyv1alpha1.PodUnavailableBudgetSpec{
+				Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+			},
+			Status: policyv1alpha1.PodUnavailableBudgetStatus{
+				ObservedGeneration: 3,
+				DisruptedPods:      map[string]metav1.Time{"web-1": goldenCreated},
+				UnavailablePods:    map[string]metav1.Time{"web-2": goldenCreated},
+				UnavailableAllowed: 0,
+				CurrentAvailable:   3,
+				DesiredAvailable:   4,
+				TotalReplicas:      5,
+			},
+		},
+	},
+	"sidecarsets": {
+		families: withTrackedMetricFamilies(sidecarSetMetricFamilies, func(r *rolloutTracker, _ *transitionTracker) []FamilyGenerator {
+			return sidecarSetRolloutMetricFamilies(r)
+		}),
+		obj: &v1alpha1.SidecarSet{
+			ObjectMeta: func() metav1.ObjectMeta {
+				m := goldenObjectMeta("scs1")
+				m.Namespace = ""
+				return m
+			}(),
+			Spec: v1alpha1.SidecarSetSpec{
+				Namespace: "ns1",
+				Containers: []v1alpha1.SidecarContainer{{
+					Container:       v1.Container{Name: "sidecar"},
+					PodInjectPolicy: v1alpha1.BeforeAppContainerType,
+					UpgradeStrategy: v1alpha1.SidecarContainerUpgradeStrategy{
+						UpgradeType: v1alpha1.SidecarContainerColdUpgrade,
+					},
+					ShareVolumePolicy: v1alpha1.ShareVolumePolicy{Type: v1alpha1.ShareVolumePolicyDisabled},
+				}},
+				UpdateStrategy: v1alpha1.SidecarSetUpdateStrategy{
+					Type:           v1alpha1.RollingUpdateSidecarSetStrategyType,
+					Partition:      ptr.To(intstr.FromInt32(1)),
+					MaxUnavailable: ptr.To(intstr.FromString("50%")),
+				},
+			},
+			Status: v1alpha1.SidecarSetStatus{
+				ObservedGeneration: 3,
+				MatchedPods:        4,
+				UpdatedPods:        2,
+				ReadyPods:          4,
+				UpdatedReadyPods:   2,
+			},
+		},
+	},
+	"statefulsets": {
+		families: withTrackedMetricFamilies(statefulSetMetricFamilies, func(r *rolloutTracker, t *transitionTracker) []FamilyGenerator {
+			return append(statefulSetRolloutMetricFamilies(r), statefulSetTransitionMetricFamilies(t)...)
+		}),
+		obj: &v1beta1.StatefulSet{
+			ObjectMeta: goldenObjectMeta("sts1"),
+			Spec: v1beta1.StatefulSetSpec{
+				Replicas:        ptr.To[int32](3),
+				ReserveOrdinals: []intstr.IntOrString{intstr.FromInt32(1)},
+				UpdateStrategy: v1beta1.StatefulSetUpdateStrategy{
+					Type: "RollingUpdate",
+					RollingUpdate: &v1beta1.RollingUpdateStatefulSetStrategy{
+						MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+					},
+				},
+			},
+			Status: v1beta1.StatefulSetStatus{
+				ObservedGeneration: 3,
+				Replicas:           3,
+				ReadyReplicas:      3,
+				AvailableReplicas:  3,
+				CurrentReplicas:    2,
+				UpdatedReplicas:    1,
+				CurrentRevision:    "sts1-6d4f",
+				UpdateRevision:     "sts1-7c9b",
+				Conditions: []appsv1.StatefulSetCondition{
+					{Type: "FailedCreatePod", Status: v1.ConditionFalse, LastTransitionTime: goldenCreated},
+				},
+			},
+		},
+	},
+	"workloadspreads": {
+		families: workloadSpreadMetricFamilies,
+		obj: &v1alpha1.WorkloadSpread{
+			ObjectMeta: goldenObjectMeta("ws1"),
+			Spec: v1alpha1.WorkloadSpreadSpec{
+				ScheduleStrategy: v1alpha1.WorkloadSpreadScheduleStrategy{
+					Type: v1alpha1.AdaptiveWorkloadSpreadScheduleStrategyType,
+				},
+			},
+		},
+	},
+}
+
+// withTrackedMetricFamilies appends the families of a workload generated
+// from the state tracked across its updates to its families, like the
+// builder does.
+func withTrackedMetricFamilies(
+	families func(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator,
+	tracked func(*rolloutTracker, *transitionTracker) []FamilyGenerator,
+) func(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return func(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+		clock := clocktesting.NewFakePassiveClock(goldenCreated.Time)
+		return append(families(allowAnnotationsList, allowLabelsList), tracked(newRolloutTracker(clock), newTransitionTracker(clock))...)
+	}
+}
+
+func TestGoldenStores(t *testing.T) {
+	for _, resource := range availableResources() {
+		// The events are aggregated by the event counter, see TestEventStore,
+		// and the workloads by the summary and the SLOs, see
+		// TestWorkloadsSummary and TestWorkloadSLOs.
+		if resource == "events" || resource == workloadsSummaryResource || resource == workloadSLOsResource {
+			continue
+		}
+		if _, ok := goldenStores[resource]; !ok {
+			t.Errorf("expected a golden test for store %s", resource)
+		}
+	}
+
+	for resource, test := range goldenStores {
+		t.Run(resource, func(t *testing.T) {
+			families := test.families([]string{"owner"}, []string{"app"})
+			store := NewMetricsStore(families, composeMetricGenFuncs(families))
+			if err := store.Add(test.obj); err != nil {
+				t.Fatal(err)
+			}
+
+			text := &bytes.Buffer{}
+			store.WriteAll(text)
+			if _, err := new(expfmt.TextParser).TextToMetricFamilies(strings.NewReader(withoutFamilies(text.String(), goldenInvalidFamilies...))); err != nil {
+				t.Errorf("expected valid Prometheus text format: %v", err)
+			}
+			compareGolden(t, filepath.Join("testdata", resource+".txt"), text.String())
+
+			openMetrics := &bytes.Buffer{}
+			store.WriteAllOpenMetrics(openMetrics)
+			openMetrics.WriteString("# EOF\n")
+			compareGolden(t, filepath.Join("testdata", resource+".openmetrics.txt"), openMetrics.String())
+		})
+	}
+}
+
+// goldenInvalidFamilies are not valid Prometheus text format and are left out
+// of the format check.
+var goldenInvalidFamilies = []string{
+	// Repeats the namespace label.
+	"kruise_sidecarset_spec_namespcace",
+}
+
+func withoutFamilies(s string, families ...string) string {
+	lines := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		keep := true
+		for _, f := range families {
+			if strings.HasPrefix(line, f+"{") || strings.HasPrefix(line, f+" ") || strings.HasPrefix(line, "# HELP "+f+" ") || strings.HasPrefix(line, "# TYPE "+f+" ") {
+				keep = false
+			}
+		}
+		if keep {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func compareGolden(t *testing.T, path, got string) {
+	t.Helper()
+
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create the golden file", err)
+	}
+	if diff := cmp.Diff(strings.Split(string(want), "\n"), strings.Split(got, "\n")); diff != "" {
+		t.Errorf("unexpected output for %s (-want, +got):\n%s", path, diff)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/openkruise/kruise-api/apps/pub"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+)
+
+const (
+	updateTypeInPlace  = "in_place"
+	updateTypeRecreate = "recreate"
+)
+
+// inPlaceUpdateDurationBuckets are the buckets of the in-place update
+// duration histograms, from 1 second to about 34 minutes.
+var inPlaceUpdateDurationBuckets = prometheus.ExponentialBuckets(1, 2, 12)
+
+// podUpdateState is the state of a pod of a workload its updates are derived
+// from.
+type podUpdateState struct {
+	owner    types.UID
+	resource string
+	// revision is the controller-revision-hash label of the pod.
+	revision string
+	// inPlaceRevision is the revision of the last in-place update of the pod,
+	// from its apps.kruise.io/inplace-update-state annotation.
+	inPlaceRevision string
+	// inPlaceReady is whether the last in-place update is complete.
+	inPlaceReady bool
+}
+
+// podOwnerResource returns the resource of the workload controlling a pod
+// whose updates are counted.
+func podOwnerResource(ref *metav1.OwnerReference) (string, bool) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil || gv.Group != v1alpha1.GroupVersion.Group {
+		return "", false
+	}
+	switch ref.Kind {
+	case "CloneSet":
+		return "clonesets", true
+	case "StatefulSet":
+		return "statefulsets", true
+	}
+	return "", false
+}
+
+// newPodUpdateState returns the update state of a pod controlled by a
+// CloneSet or an Advanced StatefulSet, and the in-place update state if any.
+func newPodUpdateState(pod *v1.Pod) (*podUpdateState, *pub.InPlaceUpdateState, bool) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil, nil, false
+	}
+	resource, ok := podOwnerResource(ref)
+	if !ok {
+		return nil, nil, false
+	}
+
+	state := &podUpdateState{
+		owner:    ref.UID,
+		resource: resource,
+		revision: pod.Labels[appsv1.ControllerRevisionHashLabelKey],
+	}
+	v, ok := pub.GetInPlaceUpdateState(pod)
+	if !ok {
+		return state, nil, true
+	}
+	inPlace := &pub.InPlaceUpdateState{}
+	if err := json.Unmarshal([]byte(v), inPlace); err != nil || inPlace.Revision == "" {
+		return state, nil, true
+	}
+	state.inPlaceRevision = inPlace.Revision
+	for _, c := range pod.Status.Conditions {
+		// The condition may still be true from the previous update.
+		if c.Type == pub.InPlaceUpdateReady && c.Status == v1.ConditionTrue && !c.LastTransitionTime.Before(&inPlace.UpdateTimestamp) {
+			state.inPlaceReady = true
+		}
+	}
+	return state, inPlace, true
+}
+
+// updatePod counts the in-place updates of the pods of the workloads and
+// observes their duration once complete. The first version of a pod only
+// sets the state updates are counted from.
+func (t *transitionTracker) updatePod(pod *v1.Pod) {
+	current, inPlace, ok := newPodUpdateState(pod)
+	if !ok {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	last, seen := t.pods[pod.UID]
+	t.pods[pod.UID] = current
+	if !seen || current.inPlaceRevision == "" {
+		return
+	}
+	w, ok := t.workloads[current.owner]
+	if !ok {
+		return
+	}
+
+	if current.inPlaceRevision != last.inPlaceRevision {
+		w.inPlaceUpdates++
+	} else if last.inPlaceReady {
+		return
+	}
+	if current.inPlaceReady {
+		for _, c := range pod.Status.Conditions {
+			if c.Type == pub.InPlaceUpdateReady {
+				t.inPlaceDurations[current.resource].histogram.WithLabelValues(pod.Namespace).Observe(c.LastTransitionTime.Sub(inPlace.UpdateTimestamp.Time).Seconds())
+			}
+		}
+	}
+}
+
+// deletePod counts the deletion of a pod in an old revision during a rollout
+// as a recreate update of its workload, if the workload recreates the pods it
+// cannot update in place and its desired replicas did not decrease during the
+// rollout, so that the pods deleted by scaling down are not counted.
+func (t *transitionTracker) deletePod(uid types.UID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	last, ok := t.pods[uid]
+	if !ok {
+		return
+	}
+	delete(t.pods, uid)
+
+	w, ok := t.workloads[last.owner]
+	if !ok || last.revision == "" || w.last.updateRevision == "" {
+		return
+	}
+	if last.revision != w.last.updateRevision && w.last.progress.inProgress() &&
+		w.last.inPlaceIfPossible && w.last.replicas >= w.rolloutReplicas {
+		w.recreateUpdates++
+	}
+}
+
+// podUpdateMetricFamilies returns the counters of the pod updates of a kind
+// of workloads, which count from when the workloads were first observed.
+func podUpdateMetricFamilies[T metav1.Object](
+	kind string,
+	tracker *transitionTracker,
+	wrap func(func(T) *metric.Family) func(interface{}) *metric.Family,
+) []FamilyGenerator {
+	families := []FamilyGenerator{
+		newFamilyGenerator(
+			"kruise_"+kind+"_pod_updates_total",
+			"Number of pods updated in place, or deleted in an old revision during a rollout of the InPlaceIfPossible strategy without scaling down to be recreated, seen since the object was first observed.",
+			metric.Counter,
+			"",
+			wrap(func(obj T) *metric.Family {
+				counts := tracker.counts(obj.GetUID())
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   []string{"update_type"},
+							LabelValues: []string{updateTypeInPlace},
+							Value:       counts.inPlaceUpdates,
+						},
+						{
+							LabelKeys:   []string{"update_type"},
+							LabelValues: []string{updateTypeRecreate},
+							Value:       counts.recreateUpdates,
+						},
+					},
+				}
+			}),
+		),
+	}
+	for i := range families {
+		families[i].Start = tracker.since
+	}
+	return families
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openkruise/kruise-api/apps/pub"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+)
+
+var inPlaceUpdateTime = metav1.NewTime(time.Unix(1700000000, 0))
+
+// testCloneSetPod returns a pod of the cloneset cs1 in the given revision,
+// updated in place to inPlaceRevision if not empty and ready after the given
+// duration if not negative.
+func testCloneSetPod(name, revision, inPlaceRevision string, readyAfter time.Duration) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1",
+			Name:      name,
+			UID:       types.UID(name),
+			Labels:    map[string]string{appsv1.ControllerRevisionHashLabelKey: revision},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       "CloneSet",
+				Name:       "cs1",
+				UID:        "cs1",
+				Controller: ptr.To(true),
+			}},
+		},
+	}
+	if inPlaceRevision == "" {
+		return pod
+	}
+	state, _ := json.Marshal(pub.InPlaceUpdateState{Revision: inPlaceRevision, UpdateTimestamp: inPlaceUpdateTime})
+	pod.Annotations = map[string]string{pub.InPlaceUpdateStateKey: string(state)}
+	condition := v1.PodCondition{Type: pub.InPlaceUpdateReady, Status: v1.ConditionFalse, LastTransitionTime: inPlaceUpdateTime}
+	if readyAfter >= 0 {
+		condition.Status = v1.ConditionTrue
+		condition.LastTransitionTime = metav1.NewTime(inPlaceUpdateTime.Add(readyAfter))
+	}
+	pod.Status.Conditions = []v1.PodCondition{condition}
+	return pod
+}
+
+func TestPodUpdates(t *testing.T) {
+	tracker := newTransitionTracker(clocktesting.NewFakePassiveClock(time.Now()))
+	families := cloneSetPodUpdateMetricFamilies(tracker)
+	store := NewMetricsStore(families, composeMetricGenFuncs(families))
+	workloads := &transitionStore{Store: store, tracker: tracker}
+	pods := newPodStore(tracker)
+
+	if err := workloads.Add(transitionCloneSet("cs1", 3, 3, "r1")); err != nil {
+		t.Fatal(err)
+	}
+	// The updates of the pods of unknown workloads and the in-place updates
+	// seen at startup are not counted.
+	orphan := func(revision string) *v1.Pod {
+		pod := testCloneSetPod("orphan", revision, revision, time.Second)
+		pod.OwnerReferences[0].UID = "cs2"
+		return pod
+	}
+	if err := pods.Replace([]interface{}{
+		testCloneSetPod("p1", "r1", "", -1),
+		testCloneSetPod("p2", "r1", "", -1),
+		testCloneSetPod("p3", "r1", "r1", time.Second),
+		orphan("r1"),
+	}, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pods.Update(orphan("r2")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rollout updating p1 in place, p2 in place twice and recreating p3.
+	if err := workloads.Update(transitionCloneSet("cs1", 3, 0, "r2")); err != nil {
+		t.Fatal(err)
+	}
+	for _, pod := range []*v1.Pod{
+		testCloneSetPod("p1", "r2", "r2", -1),
+		testCloneSetPod("p1", "r2", "r2", 5*time.Second),
+		testCloneSetPod("p1", "r2", "r2", 5*time.Second),
+		testCloneSetPod("p2", "r2", "r2", 40*time.Second),
+	} {
+		if err := pods.Update(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pods.Delete(testCloneSetPod("p3", "r1", "r1", time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pods.Add(testCloneSetPod("p4", "r2", "", -1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pods.Update(testCloneSetPod("p2", "r3", "r3", 2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	// Deletions after the rollout are not updates.
+	if err := workloads.Update(transitionCloneSet("cs1", 3, 3, "r3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := pods.Replace([]interface{}{
+		testCloneSetPod("p2", "r3", "r3", 2*time.Second),
+		testCloneSetPod("p5", "r3", "", -1),
+		testCloneSetPod("p6", "r3", "", -1),
+	}, "2"); err != nil {
+		t.Fatal(err)
+	}
+	// Neither are the deletions of a scale-down during a rollout.
+	if err := workloads.Update(transitionCloneSet("cs1", 3, 0, "r4")); err != nil {
+		t.Fatal(err)
+	}
+	if err := workloads.Update(transitionCloneSet("cs1", 1, 0, "r4")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"p5", "p6"} {
+		if err := pods.Delete(testCloneSetPod(name, "r3", "", -1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := tracker.counts("cs1"), (transitionCounts{scaleDown: 1, rolloutsCompleted: 1, inPlaceUpdates: 3, recreateUpdates: 1}); got != want {
+		t.Errorf("expected counts %+v, got %+v", want, got)
+	}
+	if got := tracker.counts("cs2"); got != (transitionCounts{}) {
+		t.Errorf("expected no counts of the unknown workload, got %+v", got)
+	}
+	out := writeStore(store)
+	for _, sample := range []string{
+		`kruise_cloneset_pod_updates_total{namespace="ns1",cloneset="cs1",update_type="in_place"} 3`,
+		`kruise_cloneset_pod_updates_total{namespace="ns1",cloneset="cs1",update_type="recreate"} 1`,
+	} {
+		if !strings.Contains(out, sample+"\n") {
+			t.Errorf("expected sample %s in:\n%s", sample, out)
+		}
+	}
+
+	histogram := &bytes.Buffer{}
+	tracker.inPlaceDurations["clonesets"].WriteAll(histogram)
+	for _, sample := range []string{
+		`kruise_cloneset_inplace_update_duration_seconds_bucket{namespace="ns1",le="4"} 1`,
+		`kruise_cloneset_inplace_update_duration_seconds_bucket{namespace="ns1",le="8"} 2`,
+		`kruise_cloneset_inplace_update_duration_seconds_bucket{namespace="ns1",le="64"} 3`,
+		`kruise_cloneset_inplace_update_duration_seconds_count{namespace="ns1"} 3`,
+	} {
+		if !strings.Contains(histogram.String(), sample+"\n") {
+			t.Errorf("expected sample %s in:\n%s", sample, histogram)
+		}
+	}
+	if len(tracker.pods) != 1 {
+		t.Errorf("expected the deleted pods to be forgotten, got %d pods", len(tracker.pods))
+	}
+}
+
+func TestPodMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs := transitionCloneSet("cs1", 1, 1, "r1")
+	pod := testCloneSetPod("p1", "r1", "", -1)
+	kubeClient := fake.NewSimpleClientset(pod)
+	kruiseClient := kruisefake.NewSimpleClientset(cs)
+
+	b := newTestBuilder(t, ctx, kubeClient, kruiseClient, "clonesets")
+	b.WithPodMetrics(true)
+	writers := b.Build()
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_cloneset_pod_updates_total{namespace="ns1",cloneset="cs1",update_type="in_place"} 0`)
+	})
+
+	if _, err := kubeClient.CoreV1().Pods("ns1").Update(ctx, testCloneSetPod("p1", "r2", "r2", 3*time.Second), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_cloneset_inplace_update_duration_seconds_count{namespace="ns1"} 1`)
+	})
+	// The counters are generated with the next version of the cloneset.
+	if _, err := kruiseClient.AppsV1alpha1().CloneSets("ns1").Update(ctx, transitionCloneSet("cs1", 1, 1, "r2"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_cloneset_pod_updates_total{namespace="ns1",cloneset="cs1",update_type="in_place"} 1`)
+	})
+}
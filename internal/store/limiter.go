@@ -0,0 +1,410 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+)
+
+// seriesLimiter caps the number of series a metric family may expose across
+// all objects, as well as the total number of series across all families.
+// Series are accounted when an object is written to a store, objects written
+// first get their series admitted first. When a family of an object goes over
+// the limit its metrics are sorted by labels and the tail is dropped, so that
+// the same object state always exposes the same series. The objects whose
+// series were dropped are written again once series are released, so that
+// they don't stay truncated until their next update. The series of the
+// collectors, which are computed when written, are accounted when written
+// too, see limitCollected.
+type seriesLimiter struct {
+	// writes serializes the writes to the limited stores, so that the
+	// truncated objects written again are never older than the version
+	// written concurrently by their reflector.
+	writes sync.Mutex
+
+	// Protects everything below
+	mutex sync.Mutex
+
+	familyLimit  int
+	familyLimits map[string]int
+	totalLimit   int
+
+	// objects holds the admitted series per family of every object, indexed
+	// by the Kubernetes object id.
+	objects  map[types.UID]*limitedObject
+	families map[string]int
+	total    int
+
+	owners  int
+	dropped *prometheus.CounterVec
+	warned  sets.Set[string]
+
+	// stores holds the store of every owner.
+	stores map[int]cache.Store
+	// truncated holds the last version written of the objects whose series
+	// were dropped, indexed by the Kubernetes object id.
+	truncated map[types.UID]truncatedObject
+	// freed is set when series were released since the truncated objects
+	// were last written again.
+	freed bool
+}
+
+type limitedObject struct {
+	owner  int
+	series map[string]int
+	// dropped holds the series dropped per family at the last write of a
+	// collector.
+	dropped map[string]int
+}
+
+type truncatedObject struct {
+	uid   types.UID
+	store cache.Store
+	obj   interface{}
+}
+
+// newSeriesLimiter returns a seriesLimiter, or nil if no limit is configured.
+func newSeriesLimiter(familyLimit int, familyLimits map[string]int, totalLimit int, dropped *prometheus.CounterVec) *seriesLimiter {
+	limited := familyLimit > 0 || totalLimit > 0
+	for _, limit := range familyLimits {
+		limited = limited || limit > 0
+	}
+	if !limited {
+		return nil
+	}
+
+	return &seriesLimiter{
+		familyLimit:  familyLimit,
+		familyLimits: familyLimits,
+		totalLimit:   totalLimit,
+		objects:      map[types.UID]*limitedObject{},
+		families:     map[string]int{},
+		dropped:      dropped,
+		warned:       sets.New[string](),
+		stores:       map[int]cache.Store{},
+		truncated:    map[types.UID]truncatedObject{},
+	}
+}
+
+// newOwner returns an id identifying the store whose objects are accounted.
+func (l *seriesLimiter) newOwner() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.owners++
+	return l.owners
+}
+
+// newStore returns the store the reflector of the given owner writes to,
+// which enforces the limits on the given store.
+func (l *seriesLimiter) newStore(owner int, store cache.Store) *limitedStore {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.stores[owner] = store
+	return &limitedStore{Store: store, limiter: l, owner: owner}
+}
+
+// wrap returns a metric generation function enforcing the limits on the
+// families generated by f.
+func (l *seriesLimiter) wrap(owner int, f func(interface{}) []metric.FamilyInterface) func(interface{}) []metric.FamilyInterface {
+	return func(obj interface{}) []metric.FamilyInterface {
+		families := f(obj)
+
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			return families
+		}
+		l.limit(owner, o.GetUID(), obj, families)
+
+		return families
+	}
+}
+
+func (l *seriesLimiter) limit(owner int, uid types.UID, obj interface{}, families []metric.FamilyInterface) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	previous := l.objects[uid]
+	l.release(uid)
+
+	truncated := false
+	object := &limitedObject{owner: owner, series: make(map[string]int, len(families))}
+	for _, fi := range families {
+		f, ok := fi.(*metric.Family)
+		if !ok {
+			continue
+		}
+
+		if allowed := l.allowed(f.Name, len(f.Metrics)); allowed < len(f.Metrics) {
+			l.drop(f, allowed)
+			truncated = true
+		}
+
+		object.series[f.Name] = len(f.Metrics)
+		l.families[f.Name] += len(f.Metrics)
+		l.total += len(f.Metrics)
+	}
+	l.objects[uid] = object
+
+	if truncated {
+		l.truncated[uid] = truncatedObject{uid: uid, store: l.stores[owner], obj: obj}
+	}
+	if previous != nil {
+		for family, n := range previous.series {
+			l.freed = l.freed || object.series[family] < n
+		}
+	}
+}
+
+// limitCollected enforces the limits on the families gathered from the
+// collector of the given owner, when they are written. The series of a
+// collector are accounted like those of an object, replaced at every write.
+func (l *seriesLimiter) limitCollected(owner int, families []*dto.MetricFamily) {
+	uid := types.UID("collector/" + strconv.Itoa(owner))
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	previous := l.objects[uid]
+	l.release(uid)
+
+	object := &limitedObject{owner: owner, series: make(map[string]int, len(families)), dropped: map[string]int{}}
+	for _, f := range families {
+		name := f.GetName()
+		if allowed := l.allowed(name, len(f.Metric)); allowed < len(f.Metric) {
+			sort.SliceStable(f.Metric, func(i, j int) bool {
+				return dtoLabelValues(f.Metric[i]) < dtoLabelValues(f.Metric[j])
+			})
+			object.dropped[name] = len(f.Metric) - allowed
+			f.Metric = f.Metric[:allowed]
+			// The same series are dropped at every write, only the newly
+			// dropped ones are counted.
+			dropped := object.dropped[name]
+			if previous != nil {
+				dropped -= previous.dropped[name]
+			}
+			l.countDropped(name, dropped)
+		}
+
+		object.series[name] = len(f.Metric)
+		l.families[name] += len(f.Metric)
+		l.total += len(f.Metric)
+	}
+	l.objects[uid] = object
+
+	if previous != nil {
+		for family, n := range previous.series {
+			l.freed = l.freed || object.series[family] < n
+		}
+	}
+}
+
+// dtoLabelValues returns the label values of a gathered metric, the order
+// collected metrics are dropped in.
+func dtoLabelValues(m *dto.Metric) string {
+	values := make([]string, 0, len(m.Label))
+	for _, label := range m.Label {
+		values = append(values, label.GetValue())
+	}
+	return strings.Join(values, "\xff")
+}
+
+// allowed returns how many of the given number of series of a family are
+// admitted. It must be called with the mutex held.
+func (l *seriesLimiter) allowed(family string, n int) int {
+	allowed := n
+	if limit := l.limitOf(family); limit > 0 {
+		allowed = min(allowed, max(limit-l.families[family], 0))
+	}
+	if l.totalLimit > 0 {
+		allowed = min(allowed, max(l.totalLimit-l.total, 0))
+	}
+	return allowed
+}
+
+func (l *seriesLimiter) limitOf(family string) int {
+	if limit, ok := l.familyLimits[family]; ok {
+		return limit
+	}
+	return l.familyLimit
+}
+
+// drop truncates the family to the given number of metrics, keeping the
+// metrics with the lowest label values.
+func (l *seriesLimiter) drop(f *metric.Family, allowed int) {
+	sort.SliceStable(f.Metrics, func(i, j int) bool {
+		return strings.Join(f.Metrics[i].LabelValues, "\xff") < strings.Join(f.Metrics[j].LabelValues, "\xff")
+	})
+	dropped := len(f.Metrics) - allowed
+	f.Metrics = f.Metrics[:allowed]
+	l.countDropped(f.Name, dropped)
+}
+
+// countDropped counts the series dropped of a family and warns the first time
+// the family goes over its limit.
+func (l *seriesLimiter) countDropped(family string, dropped int) {
+	if l.dropped != nil && dropped > 0 {
+		l.dropped.WithLabelValues(family).Add(float64(dropped))
+	}
+	if !l.warned.Has(family) {
+		l.warned.Insert(family)
+		klog.Warningf("Metric family %s is over its series limit, dropping series", family)
+	}
+}
+
+// release removes the series of the given object from the accounting. It
+// must be called with the mutex held.
+func (l *seriesLimiter) release(uid types.UID) {
+	delete(l.truncated, uid)
+	object, ok := l.objects[uid]
+	if !ok {
+		return
+	}
+	for family, n := range object.series {
+		l.families[family] -= n
+		l.total -= n
+	}
+	delete(l.objects, uid)
+}
+
+// forget releases the series of a deleted object.
+func (l *seriesLimiter) forget(uid types.UID) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.freed = l.freed || l.objects[uid] != nil
+	l.release(uid)
+}
+
+// forgetOwner releases the series of all objects of a store.
+func (l *seriesLimiter) forgetOwner(owner int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for uid, object := range l.objects {
+		if object.owner == owner {
+			l.freed = true
+			l.release(uid)
+		}
+	}
+}
+
+// readmit writes the truncated objects again once series were released, in
+// the order of their ids, so that they get the freed capacity. It must be
+// called with writes held.
+func (l *seriesLimiter) readmit() {
+	l.mutex.Lock()
+	if !l.freed || len(l.truncated) == 0 {
+		l.freed = false
+		l.mutex.Unlock()
+		return
+	}
+	l.freed = false
+	objects := make([]truncatedObject, 0, len(l.truncated))
+	for _, o := range l.truncated {
+		objects = append(objects, o)
+	}
+	l.mutex.Unlock()
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].uid < objects[j].uid })
+	for _, o := range objects {
+		if err := o.store.Add(o.obj); err != nil {
+			klog.ErrorS(err, "Failed to write truncated object again", "uid", o.uid)
+		}
+	}
+}
+
+// reset drops all accounted series, it is called when the stores are rebuilt.
+func (l *seriesLimiter) reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.objects = map[types.UID]*limitedObject{}
+	l.families = map[string]int{}
+	l.total = 0
+	l.truncated = map[types.UID]truncatedObject{}
+	l.freed = false
+}
+
+// limitedStore wraps a metrics store and releases the series accounted by the
+// seriesLimiter when objects leave the store. The truncated objects of all
+// stores are written again after the writes which released series.
+type limitedStore struct {
+	cache.Store
+	limiter *seriesLimiter
+	owner   int
+}
+
+// Add adds an object to the store.
+func (s *limitedStore) Add(obj interface{}) error {
+	s.limiter.writes.Lock()
+	defer s.limiter.writes.Unlock()
+
+	defer s.limiter.readmit()
+	return s.Store.Add(obj)
+}
+
+// Update updates an object of the store.
+func (s *limitedStore) Update(obj interface{}) error {
+	s.limiter.writes.Lock()
+	defer s.limiter.writes.Unlock()
+
+	defer s.limiter.readmit()
+	return s.Store.Update(obj)
+}
+
+// Delete deletes an existing entry in the store and releases its series.
+func (s *limitedStore) Delete(obj interface{}) error {
+	s.limiter.writes.Lock()
+	defer s.limiter.writes.Unlock()
+
+	if err := s.Store.Delete(obj); err != nil {
+		return err
+	}
+
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	s.limiter.forget(o.GetUID())
+	s.limiter.readmit()
+
+	return nil
+}
+
+// Replace releases all series of the store before replacing its contents.
+func (s *limitedStore) Replace(list []interface{}, resourceVersion string) error {
+	s.limiter.writes.Lock()
+	defer s.limiter.writes.Unlock()
+
+	defer s.limiter.readmit()
+	s.limiter.forgetOwner(s.owner)
+	return s.Store.Replace(list, resourceVersion)
+}
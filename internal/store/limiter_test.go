@@ -0,0 +1,215 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+)
+
+// fanOutFamilies returns a family exposing one series per label of the object.
+func fanOutFamilies() []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
+			"test_fan_out",
+			"Test family with one series per label.",
+			metric.Gauge,
+			"",
+			func(obj interface{}) *metric.Family {
+				o := obj.(*metav1.ObjectMeta)
+				ms := []*metric.Metric{}
+				for k := range o.Labels {
+					ms = append(ms, &metric.Metric{
+						LabelKeys:   []string{"name", "key"},
+						LabelValues: []string{o.Name, k},
+						Value:       1,
+					})
+				}
+				return &metric.Family{Metrics: ms}
+			},
+		),
+	}
+}
+
+func newLimitedTestStore(l *seriesLimiter) (*MetricsStore, *limitedStore) {
+	families := fanOutFamilies()
+	owner := l.newOwner()
+	store := NewMetricsStore(families, l.wrap(owner, composeMetricGenFuncs(families)))
+	return store, l.newStore(owner, store)
+}
+
+func testObject(name string, keys ...string) *metav1.ObjectMeta {
+	labels := map[string]string{}
+	for _, k := range keys {
+		labels[k] = "v"
+	}
+	return &metav1.ObjectMeta{Name: name, UID: types.UID(name), Labels: labels}
+}
+
+func writeStore(s *MetricsStore) string {
+	buf := &bytes.Buffer{}
+	s.WriteAll(buf)
+	return buf.String()
+}
+
+func TestSeriesLimiter(t *testing.T) {
+	if l := newSeriesLimiter(0, map[string]int{"test_fan_out": 0}, 0, nil); l != nil {
+		t.Fatalf("expected no limiter without limits")
+	}
+
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"family"})
+	l := newSeriesLimiter(0, map[string]int{"test_fan_out": 3}, 0, dropped)
+	store, reflectorStore := newLimitedTestStore(l)
+
+	if err := reflectorStore.Add(testObject("a", "d", "c", "b", "e")); err != nil {
+		t.Fatal(err)
+	}
+	got := writeStore(store)
+	for _, want := range []string{`key="b"`, `key="c"`, `key="d"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s to be kept, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `key="e"`) {
+		t.Errorf("expected key e to be dropped, got:\n%s", got)
+	}
+	if v := testutil.ToFloat64(dropped.WithLabelValues("test_fan_out")); v != 1 {
+		t.Errorf("expected 1 dropped series, got %v", v)
+	}
+
+	// The family is full, a second object gets no series.
+	if err := reflectorStore.Add(testObject("b", "x")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(writeStore(store), `name="b"`) {
+		t.Errorf("expected series of object b to be dropped")
+	}
+
+	// Deleting the first object releases its series, which are given to the
+	// truncated object without waiting for its next update.
+	if err := reflectorStore.Delete(testObject("a")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(writeStore(store), `name="b"`) {
+		t.Errorf("expected series of object b to be exposed after object a was deleted")
+	}
+
+	// Replace releases the series of objects which are gone.
+	if err := reflectorStore.Replace([]interface{}{testObject("c", "1", "2", "3")}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(writeStore(store), `name="c"`); got != 3 {
+		t.Errorf("expected 3 series of object c, got %d", got)
+	}
+}
+
+func TestSeriesLimiterTotal(t *testing.T) {
+	l := newSeriesLimiter(0, nil, 4, nil)
+	storeA, reflectorStoreA := newLimitedTestStore(l)
+	storeB, reflectorStoreB := newLimitedTestStore(l)
+
+	if err := reflectorStoreA.Add(testObject("a", "1", "2", "3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := reflectorStoreB.Add(testObject("b", "1", "2", "3")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Count(writeStore(storeA), "test_fan_out{"); got != 3 {
+		t.Errorf("expected 3 series in store a, got %d", got)
+	}
+	if got := strings.Count(writeStore(storeB), "test_fan_out{"); got != 1 {
+		t.Errorf("expected 1 series in store b, got %d", got)
+	}
+}
+
+func TestSeriesLimiterReadmit(t *testing.T) {
+	l := newSeriesLimiter(0, nil, 4, nil)
+	storeA, reflectorStoreA := newLimitedTestStore(l)
+	storeB, reflectorStoreB := newLimitedTestStore(l)
+
+	if err := reflectorStoreA.Add(testObject("a", "1", "2", "3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := reflectorStoreB.Add(testObject("b", "1", "2", "3")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shrinking an object of another store frees capacity for the truncated
+	// object.
+	if err := reflectorStoreA.Update(testObject("a", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(writeStore(storeA), "test_fan_out{"); got != 1 {
+		t.Errorf("expected 1 series in store a, got %d", got)
+	}
+	if got := strings.Count(writeStore(storeB), "test_fan_out{"); got != 3 {
+		t.Errorf("expected 3 series in store b, got %d", got)
+	}
+
+	// The objects which are no longer truncated are not written again.
+	if err := reflectorStoreA.Update(testObject("a")); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.truncated) != 0 {
+		t.Errorf("expected no truncated objects, got %v", l.truncated)
+	}
+}
+
+func TestSeriesLimiterCollectors(t *testing.T) {
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"family"})
+	l := newSeriesLimiter(0, map[string]int{"kruise_sidecarset_pod_not_injected": 2}, 3, dropped)
+	injections := newSidecarSetInjections(func(string) (map[string]string, bool) { return nil, false }, (&workloadOwners{}).resolve)
+	injections.limitSeries(l)
+	injections.updateObject(injectionTestSidecarSet("web", "h1"))
+	for _, name := range []string{"p1", "p2", "p3"} {
+		injections.updatePod(injectionTestPod("ns1", name, "web", "cs1", ""))
+	}
+
+	// The collected series are limited like those of the objects, the same
+	// series are dropped at every write.
+	for i := 0; i < 2; i++ {
+		var notInjected []string
+		for _, sample := range collectorSamples(injections) {
+			if strings.HasPrefix(sample, "kruise_sidecarset_pod_not_injected{") {
+				notInjected = append(notInjected, sample)
+			}
+		}
+		if len(notInjected) != 2 || !strings.Contains(notInjected[0], `pod="p1"`) || !strings.Contains(notInjected[1], `pod="p2"`) {
+			t.Errorf("expected the series of p1 and p2 to be kept, got %v", notInjected)
+		}
+		if got := testutil.ToFloat64(dropped.WithLabelValues("kruise_sidecarset_pod_not_injected")); got != 1 {
+			t.Errorf("expected 1 dropped series, got %v", got)
+		}
+	}
+
+	// The collected series count towards the total limit.
+	store, reflectorStore := newLimitedTestStore(l)
+	if err := reflectorStore.Add(testObject("a", "1", "2")); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(writeStore(store), "test_fan_out{"); got != 0 {
+		t.Errorf("expected no series left for the objects, got %d", got)
+	}
+}
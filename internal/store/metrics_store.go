@@ -0,0 +1,473 @@
+/*
+Copyright 2026 The Kruise Authors.
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/v2/pkg/metrics_store"
+)
+
+// OpenMetricsWriter is a metricsstore.MetricsWriter which can also write its
+// metrics in the OpenMetrics format.
+type OpenMetricsWriter interface {
+	metricsstore.MetricsWriter
+	// WriteAllOpenMetrics writes out the metrics in the OpenMetrics format,
+	// without the terminating EOF.
+	WriteAllOpenMetrics(w io.Writer)
+}
+
+var (
+	_ OpenMetricsWriter = &MetricsStore{}
+	_ OpenMetricsWriter = &MultiStoreMetricsWriter{}
+	_ FilterableWriter  = &MultiStoreMetricsWriter{}
+)
+
+// MetricsStore implements the k8s.io/client-go/tools/cache.Store interface
+// like the MetricsStore of kube-state-metrics. It additionally knows the
+// metadata of its metric families, so that it can write both the Prometheus
+// text and the OpenMetrics format.
+type MetricsStore struct {
+	// Protects metrics and openMetrics
+	mutex sync.RWMutex
+	// metrics is a map indexed by Kubernetes object id, containing a slice of
+	// metric families, containing a slice of metrics in the Prometheus text
+	// format.
+	metrics map[types.UID][][]byte
+	// openMetrics holds the families of every object whose OpenMetrics
+	// format differs from the Prometheus text format, nil for the others.
+	openMetrics map[types.UID][][]byte
+	// namespaces holds the namespace of every object, empty for cluster
+	// scoped objects.
+	namespaces map[types.UID]string
+	// states holds the structured metrics of every object if keepState is
+	// set.
+	states     map[types.UID]*ObjectState
+	keepState  bool
+	namespaced bool
+	// listener is notified of the changes of states, resource is the
+	// resource of the objects.
+	listener StateListener
+	resource string
+
+	headers            []string
+	openMetricsHeaders []string
+	families           []FamilyGenerator
+	hasCounters        bool
+
+	// generateMetricsFunc generates metrics based on a given Kubernetes object
+	// and returns them grouped by metric family.
+	generateMetricsFunc func(interface{}) []metric.FamilyInterface
+}
+
+// NewMetricsStore returns a new MetricsStore for the given families.
+func NewMetricsStore(families []FamilyGenerator, generateFunc func(interface{}) []metric.FamilyInterface) *MetricsStore {
+	s := &MetricsStore{
+		generateMetricsFunc: generateFunc,
+		headers:             make([]string, len(families)),
+		openMetricsHeaders:  make([]string, len(families)),
+		families:            families,
+		metrics:             map[types.UID][][]byte{},
+		openMetrics:         map[types.UID][][]byte{},
+		namespaces:          map[types.UID]string{},
+		states:              map[types.UID]*ObjectState{},
+		namespaced:          true,
+	}
+	for i := range families {
+		s.headers[i] = families[i].header()
+		s.openMetricsHeaders[i] = families[i].openMetricsHeader()
+		s.hasCounters = s.hasCounters || families[i].Type == metric.Counter
+	}
+	return s
+}
+
+// WithState makes the store keep the structured metrics of every object next
+// to their text representation, namespaced tells whether the objects are
+// namespaced. It must be called before objects are added.
+func (s *MetricsStore) WithState(namespaced bool) *MetricsStore {
+	s.keepState = true
+	s.namespaced = namespaced
+	return s
+}
+
+// setStateListener sets the listener notified of the state changes of the
+// objects of the given resource. It has no effect unless the state is kept.
+func (s *MetricsStore) setStateListener(resource string, l StateListener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.resource = resource
+	s.listener = l
+}
+
+// notify notifies the listener of a state change, it must be called with the
+// mutex held.
+func (s *MetricsStore) notify(eventType watch.EventType, old, new *ObjectState) {
+	if s.listener == nil {
+		return
+	}
+	s.listener(StateEvent{Type: eventType, Resource: s.resource, Old: old, New: new})
+}
+
+// Implementing k8s.io/client-go/tools/cache.Store interface
+
+// Add inserts adds to the MetricsStore by calling the metrics generator functions and
+// adding the generated metrics to the metrics map that underlies the MetricStore.
+func (s *MetricsStore) Add(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	families := s.generateMetricsFunc(obj)
+	familyStrings := make([][]byte, len(families))
+
+	for i, f := range families {
+		familyStrings[i] = f.ByteSlice()
+	}
+
+	s.metrics[o.GetUID()] = familyStrings
+	s.namespaces[o.GetUID()] = o.GetNamespace()
+	if s.keepState {
+		state := newObjectState(o, families)
+		old, ok := s.states[o.GetUID()]
+		s.states[o.GetUID()] = state
+		if ok {
+			s.notify(watch.Modified, old, state)
+		} else {
+			s.notify(watch.Added, nil, state)
+		}
+	}
+
+	if s.hasCounters {
+		s.openMetrics[o.GetUID()] = s.openMetricsCounters(obj, families, o.GetCreationTimestamp())
+	}
+
+	return nil
+}
+
+// openMetricsCounters returns the counter families in the OpenMetrics format.
+// Every counter sample is followed by a _created sample holding the start of
+// the family for the object, its creation timestamp unless the family has a
+// Start function.
+func (s *MetricsStore) openMetricsCounters(obj interface{}, families []metric.FamilyInterface, creationTimestamp metav1.Time) [][]byte {
+	familyStrings := make([][]byte, len(families))
+
+	for i, fi := range families {
+		if s.families[i].Type != metric.Counter {
+			continue
+		}
+		name := s.families[i].openMetricsName()
+		created := creationTimestamp
+		if start := s.families[i].Start; start != nil {
+			created = metav1.NewTime(start(obj))
+		}
+
+		b := strings.Builder{}
+		fi.Inspect(func(f metric.Family) {
+			for _, m := range f.Metrics {
+				b.WriteString(name)
+				b.WriteString(counterSuffix)
+				m.Write(&b)
+				if !created.IsZero() {
+					b.WriteString(name)
+					b.WriteString(createdSuffix)
+					(&metric.Metric{
+						LabelKeys:   m.LabelKeys,
+						LabelValues: m.LabelValues,
+						Value:       float64(created.Unix()),
+					}).Write(&b)
+				}
+			}
+		})
+		familyStrings[i] = []byte(b.String())
+	}
+
+	return familyStrings
+}
+
+// Update updates the existing entry in the MetricsStore.
+func (s *MetricsStore) Update(obj interface{}) error {
+	// TODO: For now, just call Add, in the future one could check if the resource version changed?
+	return s.Add(obj)
+}
+
+// Delete deletes an existing entry in the MetricsStore.
+func (s *MetricsStore) Delete(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.metrics, o.GetUID())
+	delete(s.openMetrics, o.GetUID())
+	delete(s.namespaces, o.GetUID())
+	if old, ok := s.states[o.GetUID()]; ok {
+		delete(s.states, o.GetUID())
+		s.notify(watch.Deleted, old, nil)
+	}
+
+	return nil
+}
+
+// List implements the List method of the store interface.
+func (s *MetricsStore) List() []interface{} {
+	return nil
+}
+
+// ListKeys implements the ListKeys method of the store interface.
+func (s *MetricsStore) ListKeys() []string {
+	return nil
+}
+
+// Get implements the Get method of the store interface.
+func (s *MetricsStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// GetByKey implements the GetByKey method of the store interface.
+func (s *MetricsStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// Replace will delete the contents of the store, using instead the
+// given list. The objects which are not in the list any more are reported
+// as deleted to the listener, the others as modified.
+func (s *MetricsStore) Replace(list []interface{}, _ string) error {
+	s.mutex.Lock()
+	s.metrics = map[types.UID][][]byte{}
+	s.openMetrics = map[types.UID][][]byte{}
+	s.namespaces = map[types.UID]string{}
+	oldStates := s.states
+	s.states = make(map[types.UID]*ObjectState, len(oldStates))
+	// Keep the previous states so that Add reports the listed objects as
+	// modified.
+	for uid, state := range oldStates {
+		s.states[uid] = state
+	}
+	s.mutex.Unlock()
+
+	listed := make(map[types.UID]struct{}, len(list))
+	for _, o := range list {
+		err := s.Add(o)
+		if err != nil {
+			return err
+		}
+		if m, err := meta.Accessor(o); err == nil {
+			listed[m.GetUID()] = struct{}{}
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for uid, old := range oldStates {
+		if _, ok := listed[uid]; !ok {
+			delete(s.states, uid)
+			s.notify(watch.Deleted, old, nil)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of objects in the store.
+func (s *MetricsStore) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.metrics)
+}
+
+// States returns the state of all objects in the store, nil unless the
+// state is kept.
+func (s *MetricsStore) States() []*ObjectState {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.keepState {
+		return nil
+	}
+	states := make([]*ObjectState, 0, len(s.states))
+	for _, state := range s.states {
+		states = append(states, state)
+	}
+	return states
+}
+
+// Resync implements the Resync method of the store interface.
+func (s *MetricsStore) Resync() error {
+	return nil
+}
+
+// WriteAll writes all metrics of the store into the given writer, zipped with the
+// help text of each metric family.
+func (s *MetricsStore) WriteAll(w io.Writer) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for i, help := range s.headers {
+		w.Write([]byte(help))
+		w.Write([]byte{'\n'})
+		s.writeFamily(w, i, false, "")
+	}
+}
+
+// WriteAllOpenMetrics writes all metrics of the store into the given writer
+// in the OpenMetrics format.
+func (s *MetricsStore) WriteAllOpenMetrics(w io.Writer) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for i, help := range s.openMetricsHeaders {
+		w.Write([]byte(help))
+		w.Write([]byte{'\n'})
+		s.writeFamily(w, i, true, "")
+	}
+}
+
+// writeFamily writes the metrics of the i-th family of all objects, or only
+// of the objects in the given namespace if it is not empty. It must be called
+// with the mutex held.
+func (s *MetricsStore) writeFamily(w io.Writer, i int, openMetrics bool, namespace string) {
+	openMetrics = openMetrics && s.families[i].Type == metric.Counter
+	for uid, metricFamilies := range s.metrics {
+		if namespace != "" && s.namespaces[uid] != namespace {
+			continue
+		}
+		if openMetrics {
+			w.Write(s.openMetrics[uid][i])
+			continue
+		}
+		w.Write(metricFamilies[i])
+	}
+}
+
+// MultiStoreMetricsWriter is a struct that holds multiple MetricsStore(s) and
+// implements the MetricsWriter interface.
+// It should be used with stores which have the same metric headers.
+//
+// MultiStoreMetricsWriter writes out metrics from the underlying stores so that
+// metrics with the same name coming from different stores end up grouped together.
+// It also ensures that the metric headers are only written out once.
+type MultiStoreMetricsWriter struct {
+	resource string
+	stores   []*MetricsStore
+}
+
+// NewMultiStoreMetricsWriter creates a new MultiStoreMetricsWriter.
+func NewMultiStoreMetricsWriter(stores []*MetricsStore) *MultiStoreMetricsWriter {
+	return &MultiStoreMetricsWriter{
+		stores: stores,
+	}
+}
+
+// NewResourceMetricsWriter creates a new MultiStoreMetricsWriter for the
+// stores of the given resource, which can be filtered.
+func NewResourceMetricsWriter(resource string, stores []*MetricsStore) *MultiStoreMetricsWriter {
+	return &MultiStoreMetricsWriter{
+		resource: resource,
+		stores:   stores,
+	}
+}
+
+// WriteAll writes out metrics from the underlying stores to the given writer.
+//
+// WriteAll writes metrics so that the ones with the same name
+// are grouped together when written out.
+func (m MultiStoreMetricsWriter) WriteAll(w io.Writer) {
+	m.WriteFiltered(w, false, Filter{})
+}
+
+// WriteAllOpenMetrics is WriteAll in the OpenMetrics format.
+func (m MultiStoreMetricsWriter) WriteAllOpenMetrics(w io.Writer) {
+	m.WriteFiltered(w, true, Filter{})
+}
+
+// Resource returns the resource of the stores.
+func (m MultiStoreMetricsWriter) Resource() string {
+	return m.resource
+}
+
+// FamilyNames returns the names of the metric families of the stores.
+func (m MultiStoreMetricsWriter) FamilyNames() []string {
+	if len(m.stores) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(m.stores[0].families))
+	for _, f := range m.stores[0].families {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// Namespaced returns whether the resource is namespaced.
+func (m MultiStoreMetricsWriter) Namespaced() bool {
+	return len(m.stores) == 0 || m.stores[0].namespaced
+}
+
+// States returns the state of the objects of all stores.
+func (m MultiStoreMetricsWriter) States() []*ObjectState {
+	var states []*ObjectState
+	for _, s := range m.stores {
+		states = append(states, s.States()...)
+	}
+	return states
+}
+
+// WriteFiltered writes the metrics selected by the filter like WriteAll, or
+// like WriteAllOpenMetrics if openMetrics is set.
+func (m MultiStoreMetricsWriter) WriteFiltered(w io.Writer, openMetrics bool, f Filter) {
+	if len(m.stores) == 0 || !f.includesResource(m.resource) {
+		return
+	}
+
+	for _, s := range m.stores {
+		s.mutex.RLock()
+		defer func(s *MetricsStore) {
+			s.mutex.RUnlock()
+		}(s)
+	}
+
+	headers := m.stores[0].headers
+	if openMetrics {
+		headers = m.stores[0].openMetricsHeaders
+	}
+	for i, help := range headers {
+		if !f.includesFamily(m.stores[0].families[i].Name) {
+			continue
+		}
+		w.Write([]byte(help))
+		w.Write([]byte{'\n'})
+		for _, s := range m.stores {
+			s.writeFamily(w, i, openMetrics, f.Namespace)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+)
+
+func counterTestFamilies() []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGeneratorWithUnit(
+			"test_restarts_seconds_total",
+			"Test counter with a \"quoted\" help.",
+			metric.Counter,
+			unitSeconds,
+			"",
+			func(obj interface{}) *metric.Family {
+				o := obj.(*metav1.ObjectMeta)
+				return &metric.Family{Metrics: []*metric.Metric{{
+					LabelKeys:   []string{"name"},
+					LabelValues: []string{o.Name},
+					Value:       float64(o.Generation),
+				}}}
+			},
+		),
+	}
+}
+
+func TestMetricsStoreOpenMetricsCounters(t *testing.T) {
+	families := counterTestFamilies()
+	s := NewMetricsStore(families, composeMetricGenFuncs(families))
+	if err := s.Add(&metav1.ObjectMeta{Name: "a", UID: types.UID("a"), Generation: 2, CreationTimestamp: goldenCreated}); err != nil {
+		t.Fatal(err)
+	}
+	// Objects without creation timestamp have no _created sample.
+	if err := s.Add(&metav1.ObjectMeta{Name: "b", UID: types.UID("b"), Generation: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(&metav1.ObjectMeta{UID: types.UID("b")}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 1 {
+		t.Errorf("expected 1 object in the store, got %d", s.Len())
+	}
+
+	text := &bytes.Buffer{}
+	s.WriteAll(text)
+	wantText := `# HELP test_restarts_seconds_total Test counter with a "quoted" help.
+# TYPE test_restarts_seconds_total counter
+test_restarts_seconds_total{name="a"} 2
+`
+	if diff := cmp.Diff(wantText, text.String()); diff != "" {
+		t.Errorf("unexpected text format (-want, +got):\n%s", diff)
+	}
+
+	openMetrics := &bytes.Buffer{}
+	NewMultiStoreMetricsWriter([]*MetricsStore{s}).WriteAllOpenMetrics(openMetrics)
+	wantOpenMetrics := `# HELP test_restarts_seconds Test counter with a \"quoted\" help.
+# TYPE test_restarts_seconds counter
+# UNIT test_restarts_seconds seconds
+test_restarts_seconds_total{name="a"} 2
+test_restarts_seconds_created{name="a"} 1.5e+09
+`
+	if diff := cmp.Diff(wantOpenMetrics, openMetrics.String()); diff != "" {
+		t.Errorf("unexpected OpenMetrics format (-want, +got):\n%s", diff)
+	}
+
+	if err := s.Replace(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	openMetrics.Reset()
+	s.WriteAllOpenMetrics(openMetrics)
+	if want := "# HELP test_restarts_seconds Test counter with a \\\"quoted\\\" help.\n# TYPE test_restarts_seconds counter\n# UNIT test_restarts_seconds seconds\n"; openMetrics.String() != want {
+		t.Errorf("expected only headers after replace, got:\n%s", openMetrics.String())
+	}
+}
+
+func TestFamilyGeneratorUnit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic for a family not ending with its unit")
+		}
+	}()
+	newFamilyGeneratorWithUnit("test_duration", "", metric.Gauge, unitSeconds, "", nil)
+}
+
+func TestMetricsStoreStateListener(t *testing.T) {
+	families := counterTestFamilies()
+	s := NewMetricsStore(families, composeMetricGenFuncs(families)).WithState(false)
+	var events []string
+	s.setStateListener("tests", func(e StateEvent) {
+		old, new := "-", "-"
+		if e.Old != nil {
+			old = fmt.Sprintf("%s=%v", e.Old.Name, e.Old.Families[0].Metrics[0].Value)
+		}
+		if e.New != nil {
+			new = fmt.Sprintf("%s=%v", e.New.Name, e.New.Families[0].Metrics[0].Value)
+		}
+		events = append(events, fmt.Sprintf("%s %s %s %s", e.Type, e.Resource, old, new))
+	})
+
+	for _, step := range []func() error{
+		func() error { return s.Add(&metav1.ObjectMeta{Name: "a", UID: "a", Generation: 1}) },
+		func() error { return s.Update(&metav1.ObjectMeta{Name: "a", UID: "a", Generation: 2}) },
+		func() error { return s.Add(&metav1.ObjectMeta{Name: "b", UID: "b", Generation: 1}) },
+		func() error { return s.Delete(&metav1.ObjectMeta{Name: "b", UID: "b"}) },
+		func() error { return s.Add(&metav1.ObjectMeta{Name: "c", UID: "c", Generation: 1}) },
+		// Objects missing from the new list are reported as deleted.
+		func() error {
+			return s.Replace([]interface{}{&metav1.ObjectMeta{Name: "a", UID: "a", Generation: 3}}, "")
+		},
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"ADDED tests - a=1",
+		"MODIFIED tests a=1 a=2",
+		"ADDED tests - b=1",
+		"DELETED tests b=1 -",
+		"ADDED tests - c=1",
+		"MODIFIED tests a=2 a=3",
+		"DELETED tests c=1 -",
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("unexpected events (-want, +got):\n%s", diff)
+	}
+	if states := s.States(); len(states) != 1 || states[0].Name != "a" {
+		t.Errorf("expected only the state of a, got %v", states)
+	}
+}
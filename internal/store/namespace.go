@@ -0,0 +1,320 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"sync"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+)
+
+// namespaceWatcher watches the namespaces matching a label selector and
+// starts or stops the per namespace reflectors of the registered resources
+// as namespaces are created, relabeled or deleted.
+type namespaceWatcher struct {
+	kubeClient clientset.Interface
+	selector   labels.Selector
+	// allowed restricts the selected namespaces further if not empty.
+	allowed sets.Set[string]
+
+	// Protects resources and active
+	mutex     sync.Mutex
+	resources []*namespacedReflectors
+	active    sets.Set[string]
+}
+
+func newNamespaceWatcher(kubeClient clientset.Interface, selector labels.Selector, allowed []string) *namespaceWatcher {
+	w := &namespaceWatcher{
+		kubeClient: kubeClient,
+		selector:   selector,
+		allowed:    sets.New[string](),
+		active:     sets.New[string](),
+	}
+	if !isAllNamespaces(allowed) {
+		w.allowed.Insert(allowed...)
+	}
+	return w
+}
+
+// register adds a resource whose reflectors follow the selected namespaces.
+func (w *namespaceWatcher) register(r *namespacedReflectors) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.resources = append(w.resources, r)
+	for ns := range w.active {
+		r.start(ns)
+	}
+}
+
+// run watches the namespaces until the context is done.
+func (w *namespaceWatcher) run(ctx context.Context) {
+	selector := w.selector.String()
+	listWatch := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = selector
+			return w.kubeClient.CoreV1().Namespaces().List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = selector
+			return w.kubeClient.CoreV1().Namespaces().Watch(ctx, opts)
+		},
+	}
+
+	informer := cache.NewSharedIndexInformer(listWatch, &v1.Namespace{}, 0, cache.Indexers{})
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.sync(obj.(*v1.Namespace))
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			w.sync(obj.(*v1.Namespace))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(*v1.Namespace); ok {
+				w.deactivate(ns.Name)
+			}
+		},
+	})
+	informer.Run(ctx.Done())
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for ns := range w.active {
+		for _, r := range w.resources {
+			r.stop(ns)
+		}
+	}
+	w.active = sets.New[string]()
+}
+
+func (w *namespaceWatcher) sync(ns *v1.Namespace) {
+	if ns.DeletionTimestamp == nil && w.selector.Matches(labels.Set(ns.Labels)) && (w.allowed.Len() == 0 || w.allowed.Has(ns.Name)) {
+		w.activate(ns.Name)
+		return
+	}
+	w.deactivate(ns.Name)
+}
+
+func (w *namespaceWatcher) activate(ns string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.active.Has(ns) {
+		return
+	}
+	klog.Infof("Namespace %s matches the namespace selector, starting reflectors", ns)
+	w.active.Insert(ns)
+	for _, r := range w.resources {
+		r.start(ns)
+	}
+}
+
+func (w *namespaceWatcher) deactivate(ns string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.active.Has(ns) {
+		return
+	}
+	klog.Infof("Namespace %s no longer matches the namespace selector, stopping reflectors", ns)
+	w.active.Delete(ns)
+	for _, r := range w.resources {
+		r.stop(ns)
+	}
+}
+
+// namespacedReflectors runs one reflector per namespace of a resource. All
+// reflectors write into the same store, each through a namespaceScopedStore
+// so that the objects of a namespace can be removed on their own.
+type namespacedReflectors struct {
+	ctx            context.Context
+	store          cache.Store
+	startReflector func(ctx context.Context, store cache.Store, ns string)
+
+	// Protects namespaces
+	mutex      sync.Mutex
+	namespaces map[string]*namespaceScopedStore
+}
+
+func newNamespacedReflectors(ctx context.Context, store cache.Store, startReflector func(ctx context.Context, store cache.Store, ns string)) *namespacedReflectors {
+	return &namespacedReflectors{
+		ctx:            ctx,
+		store:          store,
+		startReflector: startReflector,
+		namespaces:     map[string]*namespaceScopedStore{},
+	}
+}
+
+func (r *namespacedReflectors) start(ns string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.namespaces[ns]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	s := &namespaceScopedStore{uidStore: newUIDStore(sharedStore{Store: r.store}), cancel: cancel}
+	r.namespaces[ns] = s
+	r.startReflector(ctx, s, ns)
+}
+
+func (r *namespacedReflectors) stop(ns string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	s, ok := r.namespaces[ns]
+	if !ok {
+		return
+	}
+	delete(r.namespaces, ns)
+	s.close()
+}
+
+// namespaceScopedStore tracks the objects a single reflector wrote into a
+// shared store. Closing it stops the reflector and deletes its objects.
+type namespaceScopedStore struct {
+	*uidStore
+	cancel func()
+}
+
+// sharedStore writes the objects of a namespaceScopedStore to the shared
+// store, which is keyed by UID.
+type sharedStore struct {
+	cache.Store
+}
+
+// update implements uidHandler.
+func (s sharedStore) update(obj interface{}) (bool, error) {
+	return true, s.Store.Add(obj)
+}
+
+// delete implements uidHandler.
+func (s sharedStore) delete(uid types.UID) error {
+	return s.Store.Delete(&metav1.ObjectMeta{UID: uid})
+}
+
+// close stops the reflector and removes all its objects from the shared store.
+func (s *namespaceScopedStore) close() {
+	s.cancel()
+	if err := s.uidStore.close(); err != nil {
+		klog.Errorf("Failed to delete the objects of a namespace: %v", err)
+	}
+}
+
+// isNamespaced returns whether the objects of the given type live in a
+// namespace. Cluster scoped objects are not subject to namespace selection.
+func isNamespaced(expectedType interface{}) bool {
+	switch expectedType.(type) {
+	case *appsv1alpha1.SidecarSet:
+		return false
+	}
+	return true
+}
+
+// objectLabels keeps the labels of all objects of a cluster-scoped resource,
+// e.g. of the namespaces to evaluate the namespace selectors of the
+// SidecarSets.
+type objectLabels struct {
+	informer cache.SharedIndexInformer
+}
+
+func newNamespaceLabels(kubeClient clientset.Interface) *objectLabels {
+	return &objectLabels{
+		informer: cache.NewSharedIndexInformer(&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return kubeClient.CoreV1().Namespaces().List(context.TODO(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return kubeClient.CoreV1().Namespaces().Watch(context.TODO(), opts)
+			},
+		}, &v1.Namespace{}, 0, cache.Indexers{}),
+	}
+}
+
+// newNodeLabels returns the labels of the nodes, which only watches their
+// metadata.
+func newNodeLabels(metadataClient metadata.Interface) *objectLabels {
+	return &objectLabels{
+		informer: cache.NewSharedIndexInformer(
+			createMetadataListWatch(metadataClient, nodesResource, metav1.NamespaceAll),
+			&metav1.PartialObjectMetadata{}, 0, cache.Indexers{},
+		),
+	}
+}
+
+// run watches the objects until the context is done.
+func (l *objectLabels) run(ctx context.Context) {
+	l.informer.Run(ctx.Done())
+}
+
+// notify calls f with the name of the objects which are added, relabeled or
+// deleted. It must be called before run.
+func (l *objectLabels) notify(f func(name string)) {
+	l.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if o, err := meta.Accessor(obj); err == nil {
+				f(o.GetName())
+			}
+		},
+		UpdateFunc: func(oldObj, obj interface{}) {
+			oldO, err := meta.Accessor(oldObj)
+			if err != nil {
+				return
+			}
+			if o, err := meta.Accessor(obj); err == nil && !labels.Equals(oldO.GetLabels(), o.GetLabels()) {
+				f(o.GetName())
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if o, err := meta.Accessor(obj); err == nil {
+				f(o.GetName())
+			}
+		},
+	})
+}
+
+// get returns the labels of the object of the given name, if it was seen.
+func (l *objectLabels) get(name string) (map[string]string, bool) {
+	obj, ok, err := l.informer.GetStore().GetByKey(name)
+	if err != nil || !ok {
+		return nil, false
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, false
+	}
+	return o.GetLabels(), true
+}
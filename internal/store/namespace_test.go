@@ -0,0 +1,147 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/kube-state-metrics/v2/pkg/allowdenylist"
+	metricsstore "k8s.io/kube-state-metrics/v2/pkg/metrics_store"
+	"k8s.io/kube-state-metrics/v2/pkg/options"
+	"k8s.io/utils/ptr"
+)
+
+func newTestNamespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func newTestCloneSet(namespace, name string) *v1alpha1.CloneSet {
+	return &v1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID(namespace + "/" + name)},
+		Spec:       v1alpha1.CloneSetSpec{Replicas: ptr.To[int32](1)},
+	}
+}
+
+// newTestBuilder returns a Builder using the given fake clients which exposes
+// the metrics of the given resources.
+func newTestBuilder(t *testing.T, ctx context.Context, kubeClient *fake.Clientset, kruiseClient *kruisefake.Clientset, resources ...string) *Builder {
+	t.Helper()
+
+	allowDenyList, err := allowdenylist.New(options.MetricSet{}, options.MetricSet{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuilder()
+	b.WithMetrics(prometheus.NewRegistry())
+	if err := b.WithEnabledResources(resources); err != nil {
+		t.Fatal(err)
+	}
+	b.WithNamespaces(options.DefaultNamespaces)
+	b.WithAllowDenyList(allowDenyList)
+	b.WithKubeClient(kubeClient)
+	b.WithKruiseClient(kruiseClient)
+	b.WithMetadataClient(metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme()))
+	b.WithKruiseStoresFunc(b.DefaultKruiseStoresFunc(), false)
+	b.WithSharding(0, 1)
+	b.WithContext(ctx)
+	return b
+}
+
+func writeAll(writers []metricsstore.MetricsWriter) string {
+	buf := &bytes.Buffer{}
+	for _, w := range writers {
+		w.WriteAll(buf)
+	}
+	return buf.String()
+}
+
+func waitForOutput(t *testing.T, writers []metricsstore.MetricsWriter, cond func(string) bool) {
+	t.Helper()
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return cond(writeAll(writers)), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected output:\n%s", writeAll(writers))
+	}
+}
+
+func TestNamespaceSelector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := fake.NewSimpleClientset(
+		newTestNamespace("tenant-a", map[string]string{"tenant": "true"}),
+		newTestNamespace("tenant-b", map[string]string{"tenant": "true"}),
+		newTestNamespace("kube-system", nil),
+	)
+	kruiseClient := kruisefake.NewSimpleClientset(
+		newTestCloneSet("tenant-a", "cs"),
+		newTestCloneSet("tenant-b", "cs"),
+		newTestCloneSet("kube-system", "cs"),
+	)
+
+	b := newTestBuilder(t, ctx, kubeClient, kruiseClient, "clonesets")
+	if err := b.WithNamespaceSelector("tenant=true"); err != nil {
+		t.Fatal(err)
+	}
+	writers := b.Build()
+
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `namespace="tenant-a"`) && strings.Contains(out, `namespace="tenant-b"`)
+	})
+	if strings.Contains(writeAll(writers), `namespace="kube-system"`) {
+		t.Fatalf("expected no metrics of namespace kube-system")
+	}
+
+	// Relabeling a namespace removes its series.
+	if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, newTestNamespace("tenant-a", nil), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return !strings.Contains(out, `namespace="tenant-a"`) && strings.Contains(out, `namespace="tenant-b"`)
+	})
+
+	// New matching namespaces are picked up.
+	if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, newTestNamespace("kube-system", map[string]string{"tenant": "true"}), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `namespace="kube-system"`)
+	})
+
+	// Deleting a namespace removes its series.
+	if err := kubeClient.CoreV1().Namespaces().Delete(ctx, "tenant-b", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return !strings.Contains(out, `namespace="tenant-b"`)
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// objectObserver keeps what it needs of the objects written to the
+// observedStores, e.g. to join them with the pods.
+type objectObserver[T metav1.Object] interface {
+	// updateObject is called for every new version of an object written to
+	// the store.
+	updateObject(obj T)
+	// deleteObject is called when an object is deleted.
+	deleteObject(uid types.UID)
+}
+
+// observerHandler passes the objects of type T to an observer.
+type observerHandler[T metav1.Object] struct {
+	observer objectObserver[T]
+}
+
+// update implements uidHandler.
+func (h observerHandler[T]) update(obj interface{}) (bool, error) {
+	o, ok := obj.(T)
+	if !ok {
+		return false, nil
+	}
+	h.observer.updateObject(o)
+	return true, nil
+}
+
+// delete implements uidHandler.
+func (h observerHandler[T]) delete(uid types.UID) error {
+	h.observer.deleteObject(uid)
+	return nil
+}
+
+// observedStore passes the objects written to the store to an observer
+// before writing them to the underlying store.
+type observedStore struct {
+	cache.Store
+	observed *uidStore
+}
+
+func newObservedStore[T metav1.Object](store cache.Store, observer objectObserver[T]) *observedStore {
+	return &observedStore{
+		Store:    store,
+		observed: newUIDStore(observerHandler[T]{observer: observer}),
+	}
+}
+
+// Add passes the object to the observer and adds it to the store.
+func (s *observedStore) Add(obj interface{}) error {
+	if err := s.observed.Add(obj); err != nil {
+		return err
+	}
+	return s.Store.Add(obj)
+}
+
+// Update passes the object to the observer and updates it in the store.
+func (s *observedStore) Update(obj interface{}) error {
+	if err := s.observed.Update(obj); err != nil {
+		return err
+	}
+	return s.Store.Update(obj)
+}
+
+// Delete notifies the observer of the deletion of the object and deletes it
+// from the store.
+func (s *observedStore) Delete(obj interface{}) error {
+	if err := s.observed.Delete(obj); err != nil {
+		return err
+	}
+	return s.Store.Delete(obj)
+}
+
+// Replace notifies the observer of the deletion of the objects which are not
+// in the list any more, passes it the listed objects and replaces the
+// contents of the store.
+func (s *observedStore) Replace(list []interface{}, resourceVersion string) error {
+	if err := s.observed.Replace(list, resourceVersion); err != nil {
+		return err
+	}
+	return s.Store.Replace(list, resourceVersion)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	// replicaSetsResource is the resource of the ReplicaSets, whose metadata
+	// is watched to resolve the pods of the Deployments to their Deployment.
+	replicaSetsResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	// nodesResource is the resource of the nodes, whose metadata is watched
+	// to resolve the nodes the pods run on to the subsets of the
+	// WorkloadSpreads.
+	nodesResource = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+)
+
+// podHandler is notified of the pods written by the pod reflectors, from
+// which metrics of other objects are derived.
+type podHandler interface {
+	// updatePod is called for every new version of a pod.
+	updatePod(pod *v1.Pod)
+	// deletePod is called when a pod is deleted.
+	deletePod(uid types.UID)
+}
+
+// podHandlers passes the pods written by the pod reflectors to the handlers,
+// which keep what they need.
+type podHandlers []podHandler
+
+// newPodStore returns the store of the pod reflectors, which doesn't keep the
+// pods but passes them to the handlers.
+func newPodStore(handlers ...podHandler) *uidStore {
+	return newUIDStore(podHandlers(handlers))
+}
+
+// update implements uidHandler.
+func (h podHandlers) update(obj interface{}) (bool, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return false, nil
+	}
+	for _, handler := range h {
+		handler.updatePod(pod)
+	}
+	return true, nil
+}
+
+// delete implements uidHandler.
+func (h podHandlers) delete(uid types.UID) error {
+	for _, handler := range h {
+		handler.deletePod(uid)
+	}
+	return nil
+}
+
+// discardingStore implements the read methods of cache.Store for the stores
+// which don't keep the objects written to them.
+type discardingStore struct{}
+
+// List implements the cache.Store interface, the objects are not kept.
+func (discardingStore) List() []interface{} {
+	return nil
+}
+
+// ListKeys implements the cache.Store interface, the objects are not kept.
+func (discardingStore) ListKeys() []string {
+	return nil
+}
+
+// Get implements the cache.Store interface, the objects are not kept.
+func (discardingStore) Get(_ interface{}) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// GetByKey implements the cache.Store interface, the objects are not kept.
+func (discardingStore) GetByKey(_ string) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// Resync implements the cache.Store interface.
+func (discardingStore) Resync() error {
+	return nil
+}
+
+// buildPodWatch starts the pod reflectors passing the pods to the handlers.
+// The pods are not sharded, every shard watches all pods, as the metrics
+// derived from them belong to objects of any shard.
+func (b *Builder) buildPodWatch(handlers ...podHandler) {
+	buildReflectedStores(b, &v1.Pod{}, func(ns string) cache.ListerWatcher {
+		return unshardedListWatch{createPodListWatch(b.kubeClient, ns)}
+	}, b.useAPIServerCache, func() (*uidStore, cache.Store) {
+		s := newPodStore(handlers...)
+		return s, s
+	})
+}
+
+func createPodListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return kubeClient.CoreV1().Pods(ns).List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return kubeClient.CoreV1().Pods(ns).Watch(context.TODO(), opts)
+		},
+	}
+}
+
+// workloadOwners resolves the owners of the pods up to their workload, i.e.
+// the ReplicaSets controlled by a Deployment to the Deployment, from the
+// metadata of the ReplicaSets. The owners are left as is until the metadata
+// of their ReplicaSet is seen.
+type workloadOwners struct {
+	// stores holds the metadata of the ReplicaSets, one store per watched
+	// namespace.
+	stores []cache.Store
+}
+
+// resolve returns the kind and name of the workload of the pods controlled by
+// the owner of the given kind and name.
+func (o *workloadOwners) resolve(namespace, kind, name string) (string, string) {
+	if kind != "ReplicaSet" {
+		return kind, name
+	}
+	for _, s := range o.stores {
+		obj, ok, err := s.GetByKey(namespace + "/" + name)
+		if err != nil || !ok {
+			continue
+		}
+		rs, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		if ref := metav1.GetControllerOfNoCopy(rs); ref != nil && ref.Kind == "Deployment" {
+			return ref.Kind, ref.Name
+		}
+	}
+	return kind, name
+}
+
+// buildWorkloadOwners starts the reflectors of the metadata of the
+// ReplicaSets the workload owners are resolved from.
+func (b *Builder) buildWorkloadOwners(owners *workloadOwners) {
+	owners.stores = buildReflectedStores(b, &metav1.PartialObjectMetadata{}, func(ns string) cache.ListerWatcher {
+		return unshardedListWatch{createMetadataListWatch(b.metadataClient, replicaSetsResource, ns)}
+	}, b.useAPIServerCache, func() (cache.Store, cache.Store) {
+		s := cache.NewStore(cache.MetaNamespaceKeyFunc)
+		return s, s
+	})
+}
+
+func createMetadataListWatch(metadataClient metadata.Interface, resource schema.GroupVersionResource, ns string) cache.ListerWatcher {
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return metadataClient.Resource(resource).Namespace(ns).List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return metadataClient.Resource(resource).Namespace(ns).Watch(context.TODO(), opts)
+		},
+	}
+}
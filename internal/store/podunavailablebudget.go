@@ -0,0 +1,256 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"sort"
+
+	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
+	"github.com/openkruise/kruise-api/policy/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+)
+
+var (
+	descPodUnavailableBudgetAnnotationsName     = "kruise_pub_annotations"
+	descPodUnavailableBudgetAnnotationsHelp     = "Kruise annotations converted to Prometheus labels."
+	descPodUnavailableBudgetLabelsName          = "kruise_pub_labels"
+	descPodUnavailableBudgetLabelsHelp          = "Kruise labels converted to Prometheus labels."
+	descPodUnavailableBudgetLabelsDefaultLabels = []string{"namespace", "podunavailablebudget"}
+)
+
+func podUnavailableBudgetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
+			descPodUnavailableBudgetAnnotationsName,
+			descPodUnavailableBudgetAnnotationsHelp,
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				annotationKeys, annotationValues := createPrometheusLabelKeysValues("annotation", pub.Annotations, allowAnnotationsList)
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   annotationKeys,
+							LabelValues: annotationValues,
+							Value:       1,
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			descPodUnavailableBudgetLabelsName,
+			descPodUnavailableBudgetLabelsHelp,
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				labelKeys, labelValues := createPrometheusLabelKeysValues("label", pub.Labels, allowLabelsList)
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   labelKeys,
+							LabelValues: labelValues,
+							Value:       1,
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_pub_created",
+			"Unix creation timestamp",
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				ms := []*metric.Metric{}
+
+				if !pub.CreationTimestamp.IsZero() {
+					ms = append(ms, &metric.Metric{
+						Value: float64(pub.CreationTimestamp.Unix()),
+					})
+				}
+
+				return &metric.Family{
+					Metrics: ms,
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_pub_metadata_generation",
+			"Sequence number representing a specific generation of the desired state for the podunavailablebudget.",
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: float64(pub.ObjectMeta.Generation),
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_pub_status_observed_generation",
+			"The generation observed by the podunavailablebudget controller.",
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: float64(pub.Status.ObservedGeneration),
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_pub_status_unavailable_allowed",
+			"The number of pod unavailable operations which are currently allowed.",
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: float64(pub.Status.UnavailableAllowed),
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_pub_status_current_available",
+			"The number of currently available pods protected by the podunavailablebudget.",
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: float64(pub.Status.CurrentAvailable),
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_pub_status_desired_available",
+			"The minimum number of available pods desired by the podunavailablebudget.",
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: float64(pub.Status.DesiredAvailable),
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_pub_status_total_replicas",
+			"The total number of pods counted by the podunavailablebudget.",
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: float64(pub.Status.TotalReplicas),
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_pub_unavailable_pod",
+			"Pods currently counted as unavailable by the podunavailablebudget, disrupted by a deletion or an eviction, or unavailable because of an in-place update.",
+			metric.Gauge,
+			"",
+			wrapPodUnavailableBudgetFunc(func(pub *v1alpha1.PodUnavailableBudget) *metric.Family {
+				ms := []*metric.Metric{}
+				for _, s := range []struct {
+					state string
+					pods  map[string]metav1.Time
+				}{
+					{state: "disrupted", pods: pub.Status.DisruptedPods},
+					{state: "unavailable", pods: pub.Status.UnavailablePods},
+				} {
+					for _, pod := range sortedMapKeys(s.pods) {
+						ms = append(ms, &metric.Metric{
+							LabelKeys:   []string{"pod", "state"},
+							LabelValues: []string{pod, s.state},
+							Value:       1,
+						})
+					}
+				}
+				return &metric.Family{
+					Metrics: ms,
+				}
+			}),
+		),
+	}
+}
+
+// sortedMapKeys returns the keys of the map in order, so that the series are
+// written in a stable order.
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func wrapPodUnavailableBudgetFunc(f func(*v1alpha1.PodUnavailableBudget) *metric.Family) func(interface{}) *metric.Family {
+	return func(obj interface{}) *metric.Family {
+		pub := obj.(*v1alpha1.PodUnavailableBudget)
+
+		metricFamily := f(pub)
+
+		for _, m := range metricFamily.Metrics {
+			m.LabelKeys = append(descPodUnavailableBudgetLabelsDefaultLabels, m.LabelKeys...)
+			m.LabelValues = append([]string{pub.Namespace, pub.Name}, m.LabelValues...)
+		}
+
+		return metricFamily
+	}
+}
+
+func createPodUnavailableBudgetListWatch(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
+			return kruiseClient.PolicyV1alpha1().PodUnavailableBudgets(ns).List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
+			return kruiseClient.PolicyV1alpha1().PodUnavailableBudgets(ns).Watch(context.TODO(), opts)
+		},
+	}
+}
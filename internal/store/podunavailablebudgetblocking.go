@@ -0,0 +1,200 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"sync"
+	"time"
+
+	policyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// pubOperationAllowed is the result of the operations let through by a
+	// PodUnavailableBudget, which recorded their pod in its status.
+	pubOperationAllowed = "allowed"
+	// pubOperationDenied is the result of the operations a
+	// PodUnavailableBudget recorded a warning event for.
+	pubOperationDenied = "denied"
+)
+
+// blockingPUB is what the blocking tracker needs of a PodUnavailableBudget.
+type blockingPUB struct {
+	namespace string
+	name      string
+	// blockedSince is when no unavailable operation was allowed any more,
+	// zero while the podunavailablebudget is not blocked.
+	blockedSince time.Time
+	// pods holds the disrupted and unavailable pods of the last status.
+	pods sets.Set[string]
+}
+
+// isBlocked returns whether the PodUnavailableBudget denies the operations
+// making its pods unavailable. The Kruise webhook allows all operations of the
+// PodUnavailableBudgets which desire no available pods.
+func isBlocked(pub *policyv1alpha1.PodUnavailableBudget) bool {
+	return pub.Status.UnavailableAllowed <= 0 && pub.Status.DesiredAvailable > 0
+}
+
+// unavailablePods returns the pods a PodUnavailableBudget counts as
+// unavailable, keyed by operation.
+func unavailablePods(pub *policyv1alpha1.PodUnavailableBudget) sets.Set[string] {
+	pods := sets.New[string]()
+	for pod := range pub.Status.DisruptedPods {
+		pods.Insert("disrupted/" + pod)
+	}
+	for pod := range pub.Status.UnavailablePods {
+		pods.Insert("unavailable/" + pod)
+	}
+	return pods
+}
+
+// pubBlocking measures how long the PodUnavailableBudgets have been blocking
+// the operations on their pods, and counts the operations they let through
+// or denied. It is the objectObserver of the PodUnavailableBudgets and an
+// eventHandler of the event counter.
+type pubBlocking struct {
+	collectorWriter
+	clock clock.PassiveClock
+
+	blockedDesc *prometheus.Desc
+	operations  *prometheus.CounterVec
+
+	// Protects pubs
+	mutex sync.Mutex
+	pubs  map[types.UID]*blockingPUB
+}
+
+func newPUBBlocking(c clock.PassiveClock) *pubBlocking {
+	p := &pubBlocking{
+		clock: c,
+		blockedDesc: prometheus.NewDesc(
+			"kruise_pub_blocked_seconds",
+			"Seconds since the podunavailablebudget allows no more unavailable pods, 0 when it is not blocking.",
+			descPodUnavailableBudgetLabelsDefaultLabels, nil,
+		),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kruise_pub_protected_operations_total",
+			Help: "Number of operations on the pods protected by a podunavailablebudget, allowed as inferred from the growth of its disrupted and unavailable pods, or denied as inferred from its warning events.",
+		}, append(descPodUnavailableBudgetLabelsDefaultLabels, "result")),
+		pubs: map[types.UID]*blockingPUB{},
+	}
+	p.collectorWriter = newCollectorWriter("podunavailablebudgets", p,
+		"kruise_pub_blocked_seconds",
+		"kruise_pub_protected_operations_total",
+	)
+	return p
+}
+
+// updateObject implements objectObserver. The pods recorded since the last
+// version of the PodUnavailableBudget are counted as allowed operations, the
+// pods of the first version seen are not.
+func (p *pubBlocking) updateObject(pub *policyv1alpha1.PodUnavailableBudget) {
+	pods := unavailablePods(pub)
+	now := p.clock.Now()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	b, ok := p.pubs[pub.UID]
+	if !ok {
+		b = &blockingPUB{namespace: pub.Namespace, name: pub.Name}
+		p.pubs[pub.UID] = b
+		// Create the series so that the first operations are not missed
+		// by rate().
+		p.operations.WithLabelValues(pub.Namespace, pub.Name, pubOperationAllowed)
+		p.operations.WithLabelValues(pub.Namespace, pub.Name, pubOperationDenied)
+	} else if added := pods.Difference(b.pods).Len(); added > 0 {
+		p.operations.WithLabelValues(b.namespace, b.name, pubOperationAllowed).Add(float64(added))
+	}
+	b.pods = pods
+
+	switch {
+	case !isBlocked(pub):
+		b.blockedSince = time.Time{}
+	case b.blockedSince.IsZero():
+		b.blockedSince = now
+	}
+}
+
+// deleteObject implements objectObserver.
+func (p *pubBlocking) deleteObject(uid types.UID) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.forget(uid)
+}
+
+// forget drops the series of a PodUnavailableBudget. The mutex must be held.
+func (p *pubBlocking) forget(uid types.UID) {
+	b, ok := p.pubs[uid]
+	if !ok {
+		return
+	}
+	delete(p.pubs, uid)
+	p.operations.DeleteLabelValues(b.namespace, b.name, pubOperationAllowed)
+	p.operations.DeleteLabelValues(b.namespace, b.name, pubOperationDenied)
+}
+
+// handleEvent implements eventHandler. The occurrences of the warning events
+// of the PodUnavailableBudgets are counted as denied operations. The events
+// are sharded by their involved object, so they are seen by the shard of the
+// PodUnavailableBudget.
+func (p *pubBlocking) handleEvent(e *v1.Event, occurrences int32) {
+	if e.Type != v1.EventTypeWarning || e.InvolvedObject.Kind != "PodUnavailableBudget" {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	b, ok := p.pubs[e.InvolvedObject.UID]
+	if !ok {
+		return
+	}
+	p.operations.WithLabelValues(b.namespace, b.name, pubOperationDenied).Add(float64(occurrences))
+}
+
+// Describe implements prometheus.Collector.
+func (p *pubBlocking) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.blockedDesc
+	p.operations.Describe(ch)
+}
+
+// Collect implements prometheus.Collector. The blocked seconds are computed
+// when they are collected, so that they grow while the status of the
+// PodUnavailableBudgets doesn't change.
+func (p *pubBlocking) Collect(ch chan<- prometheus.Metric) {
+	now := p.clock.Now()
+
+	p.mutex.Lock()
+	for _, b := range p.pubs {
+		var blocked float64
+		if !b.blockedSince.IsZero() {
+			blocked = now.Sub(b.blockedSince).Seconds()
+		}
+		ch <- prometheus.MustNewConstMetric(p.blockedDesc, prometheus.GaugeValue, blocked, b.namespace, b.name)
+	}
+	p.mutex.Unlock()
+
+	p.operations.Collect(ch)
+}
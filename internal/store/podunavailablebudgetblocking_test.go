@@ -0,0 +1,155 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	policyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// blockingTestPUB returns a podunavailablebudget allowing the given number of
+// unavailable pods, with the given disrupted pods.
+func blockingTestPUB(unavailableAllowed int32, disrupted ...string) *policyv1alpha1.PodUnavailableBudget {
+	pub := &policyv1alpha1.PodUnavailableBudget{
+		ObjectMeta: testObjectMeta("ns1", "pub1"),
+		Status: policyv1alpha1.PodUnavailableBudgetStatus{
+			UnavailableAllowed: unavailableAllowed,
+			DesiredAvailable:   2,
+		},
+	}
+	if len(disrupted) > 0 {
+		pub.Status.DisruptedPods = map[string]metav1.Time{}
+		for _, pod := range disrupted {
+			pub.Status.DisruptedPods[pod] = metav1.Now()
+		}
+	}
+	return pub
+}
+
+// blockingTestEvent returns a warning event of the podunavailablebudget pub1.
+func blockingTestEvent(uid string, count int32) *v1.Event {
+	e := testEvent(uid, policyv1alpha1.GroupVersion.String(), "pub1", "Denied", count)
+	e.InvolvedObject.Kind = "PodUnavailableBudget"
+	e.InvolvedObject.UID = "ns1/pub1"
+	return e
+}
+
+func TestPUBBlocking(t *testing.T) {
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+	blocking := newPUBBlocking(clock)
+	events := newEventCounter(nil)
+	events.handlers = append(events.handlers, blocking)
+	store := newObservedStore[*policyv1alpha1.PodUnavailableBudget](cache.NewStore(cache.MetaNamespaceKeyFunc), blocking)
+
+	// The pods disrupted before the podunavailablebudget was seen are not
+	// counted.
+	if err := store.Add(blockingTestPUB(1, "p1")); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(time.Minute))
+	if err := store.Update(blockingTestPUB(0, "p1", "p2")); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(time.Minute))
+	if err := store.Update(blockingTestPUB(0, "p2", "p3")); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(time.Minute))
+	events.update(blockingTestEvent("e1", 2))
+	events.update(blockingTestEvent("e1", 3))
+	// Not a denial.
+	normal := blockingTestEvent("e2", 1)
+	normal.Type = v1.EventTypeNormal
+	events.update(normal)
+
+	want := []string{
+		`kruise_pub_blocked_seconds{namespace="ns1",podunavailablebudget="pub1"} 120`,
+		`kruise_pub_protected_operations_total{namespace="ns1",podunavailablebudget="pub1",result="allowed"} 2`,
+		`kruise_pub_protected_operations_total{namespace="ns1",podunavailablebudget="pub1",result="denied"} 3`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(blocking)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// Blocking again starts over.
+	if err := store.Update(blockingTestPUB(1, "p3")); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(time.Minute))
+	if err := store.Update(blockingTestPUB(0, "p3")); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(time.Minute))
+	want[0] = `kruise_pub_blocked_seconds{namespace="ns1",podunavailablebudget="pub1"} 60`
+	if diff := cmp.Diff(want, collectorSamples(blocking)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// The podunavailablebudgets desiring no available pods don't block.
+	pub := blockingTestPUB(0, "p3")
+	pub.Status.DesiredAvailable = 0
+	if err := store.Update(pub); err != nil {
+		t.Fatal(err)
+	}
+	want[0] = `kruise_pub_blocked_seconds{namespace="ns1",podunavailablebudget="pub1"} 0`
+	if diff := cmp.Diff(want, collectorSamples(blocking)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	if err := store.Replace(nil, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := collectorSamples(blocking); len(got) != 0 {
+		t.Errorf("expected no samples of the deleted podunavailablebudget, got %v", got)
+	}
+}
+
+func TestPUBBlockingMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := fake.NewSimpleClientset()
+	kruiseClient := kruisefake.NewSimpleClientset(blockingTestPUB(1))
+	b := newTestBuilder(t, ctx, kubeClient, kruiseClient, "podunavailablebudgets", "events")
+	writers := b.Build()
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_pub_blocked_seconds{namespace="ns1",podunavailablebudget="pub1"} 0`)
+	})
+
+	if _, err := kruiseClient.PolicyV1alpha1().PodUnavailableBudgets("ns1").UpdateStatus(ctx, blockingTestPUB(0, "p1"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().Events("ns1").Create(ctx, blockingTestEvent("e1", 1), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_pub_unavailable_pod{namespace="ns1",podunavailablebudget="pub1",pod="p1",state="disrupted"} 1`) &&
+			strings.Contains(out, `kruise_pub_protected_operations_total{namespace="ns1",podunavailablebudget="pub1",result="allowed"} 1`) &&
+			strings.Contains(out, `kruise_pub_protected_operations_total{namespace="ns1",podunavailablebudget="pub1",result="denied"} 1`)
+	})
+}
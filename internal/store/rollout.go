@@ -0,0 +1,319 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+	"k8s.io/utils/clock"
+)
+
+// rolloutRefreshInterval is how often the metrics of the workloads whose
+// rollout is in progress are generated again, so that their stalled seconds
+// grow while their status doesn't change.
+const rolloutRefreshInterval = 15 * time.Second
+
+// rolloutProgress is the progress of the rollout of a workload derived from
+// its status.
+type rolloutProgress struct {
+	// target is the number of replicas to update, as allowed by the
+	// partition.
+	target             int32
+	updated            int32
+	updatedReady       int32
+	ready              int32
+	generation         int64
+	observedGeneration int64
+}
+
+// inProgress returns whether the controller has not observed the last
+// generation yet or not all target replicas are updated and ready.
+func (p rolloutProgress) inProgress() bool {
+	return p.observedGeneration < p.generation || p.updated < p.target || p.updatedReady < p.target
+}
+
+// ratio returns the ratio of the updated and ready replicas to the target.
+func (p rolloutProgress) ratio() float64 {
+	if p.target <= 0 || p.updatedReady >= p.target {
+		return 1
+	}
+	return float64(p.updatedReady) / float64(p.target)
+}
+
+// rolloutTracker remembers when the rollouts in progress last moved, across
+// the updates of the objects.
+type rolloutTracker struct {
+	clock clock.PassiveClock
+
+	// Protects rollouts
+	mutex sync.Mutex
+	// rollouts holds the rollouts in progress, indexed by the Kubernetes
+	// object id.
+	rollouts map[types.UID]*trackedRollout
+}
+
+type trackedRollout struct {
+	progress     rolloutProgress
+	lastProgress time.Time
+	// obj is the last version of the object written to store, whose
+	// metrics are generated again while the rollout is in progress.
+	obj   interface{}
+	store *rolloutStore
+}
+
+func newRolloutTracker(c clock.PassiveClock) *rolloutTracker {
+	return &rolloutTracker{
+		clock:    c,
+		rollouts: map[types.UID]*trackedRollout{},
+	}
+}
+
+// observe records the progress of the rollout of an object and returns the
+// number of seconds since the updated, ready or observed generation of the
+// rollout in progress last moved, 0 if the rollout is complete. Rollouts
+// seen for the first time start moving now.
+func (t *rolloutTracker) observe(uid types.UID, p rolloutProgress) float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !p.inProgress() {
+		delete(t.rollouts, uid)
+		return 0
+	}
+
+	now := t.clock.Now()
+	r, ok := t.rollouts[uid]
+	if !ok {
+		t.rollouts[uid] = &trackedRollout{progress: p, lastProgress: now}
+		return 0
+	}
+	if r.progress != p {
+		r.progress = p
+		r.lastProgress = now
+	}
+	return now.Sub(r.lastProgress).Seconds()
+}
+
+// remember records the object and the store of a rollout in progress.
+func (t *rolloutTracker) remember(obj interface{}, s *rolloutStore) {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if r, ok := t.rollouts[o.GetUID()]; ok {
+		r.obj = obj
+		r.store = s
+	}
+}
+
+// forget forgets the rollout of an object.
+func (t *rolloutTracker) forget(uid types.UID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.rollouts, uid)
+}
+
+// retain forgets the rollouts of the objects of the store which are not
+// kept.
+func (t *rolloutTracker) retain(s *rolloutStore, keep map[types.UID]struct{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for uid, r := range t.rollouts {
+		if _, ok := keep[uid]; r.store == s && !ok {
+			delete(t.rollouts, uid)
+		}
+	}
+}
+
+// objects returns the objects of the rollouts in progress of the store.
+func (t *rolloutTracker) objects(s *rolloutStore) []interface{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var objects []interface{}
+	for _, r := range t.rollouts {
+		if r.store == s {
+			objects = append(objects, r.obj)
+		}
+	}
+	return objects
+}
+
+// rolloutStore is the store written by a reflector. It records the objects
+// whose rollout is in progress in the tracker so that their metrics are
+// generated again every rolloutRefreshInterval, and forgets them when they
+// are deleted.
+type rolloutStore struct {
+	cache.Store
+	tracker *rolloutTracker
+
+	// mutex serializes the writes of the reflector and the refreshes, so that
+	// an object is never overwritten by a previous version.
+	mutex sync.Mutex
+}
+
+// Add adds the object to the store and remembers its rollout.
+func (s *rolloutStore) Add(obj interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.Store.Add(obj); err != nil {
+		return err
+	}
+	s.tracker.remember(obj, s)
+	return nil
+}
+
+// Update updates the object in the store and remembers its rollout.
+func (s *rolloutStore) Update(obj interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.Store.Update(obj); err != nil {
+		return err
+	}
+	s.tracker.remember(obj, s)
+	return nil
+}
+
+// Delete deletes the object from the store and forgets its rollout.
+func (s *rolloutStore) Delete(obj interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.Store.Delete(obj); err != nil {
+		return err
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	s.tracker.forget(o.GetUID())
+	return nil
+}
+
+// Replace replaces the contents of the store and forgets the rollouts of
+// the objects which are not in the list any more.
+func (s *rolloutStore) Replace(list []interface{}, resourceVersion string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.Store.Replace(list, resourceVersion); err != nil {
+		return err
+	}
+	keep := make(map[types.UID]struct{}, len(list))
+	for _, obj := range list {
+		if o, err := meta.Accessor(obj); err == nil {
+			keep[o.GetUID()] = struct{}{}
+		}
+		s.tracker.remember(obj, s)
+	}
+	s.tracker.retain(s, keep)
+	return nil
+}
+
+// refresh generates the metrics of the objects whose rollout is in progress
+// again.
+func (s *rolloutStore) refresh() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, obj := range s.tracker.objects(s) {
+		if err := s.Store.Update(obj); err != nil {
+			klog.Errorf("Failed to refresh the rollout metrics: %v", err)
+		}
+	}
+}
+
+// run refreshes the store every rolloutRefreshInterval until the context is
+// done.
+func (s *rolloutStore) run(ctx context.Context) {
+	wait.Until(s.refresh, rolloutRefreshInterval, ctx.Done())
+}
+
+// rolloutMetricFamilies returns the families of the rollouts of a kind of
+// workloads, whose progress is derived from their status by progress.
+func rolloutMetricFamilies[T metav1.Object](
+	kind string,
+	tracker *rolloutTracker,
+	progress func(T) rolloutProgress,
+	wrap func(func(T) *metric.Family) func(interface{}) *metric.Family,
+) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
+			"kruise_"+kind+"_rollout_progress_ratio",
+			"Ratio of the updated and ready replicas to the replicas to update as allowed by the partition, 1 when the rollout is complete.",
+			metric.Gauge,
+			"",
+			wrap(func(obj T) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: progress(obj).ratio(),
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_"+kind+"_rollout_in_progress",
+			"Whether the controller has not observed the last generation yet or not all replicas to update are updated and ready.",
+			metric.Gauge,
+			"",
+			wrap(func(obj T) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: BoolFloat64(progress(obj).inProgress()),
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGeneratorWithUnit(
+			"kruise_"+kind+"_rollout_stalled_seconds",
+			"Seconds since the updated, ready or observed generation of a rollout in progress last changed, 0 when the rollout is complete.",
+			metric.Gauge,
+			unitSeconds,
+			"",
+			wrap(func(obj T) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: tracker.observe(obj.GetUID(), progress(obj)),
+						},
+					},
+				}
+			}),
+		),
+	}
+}
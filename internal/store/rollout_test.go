@@ -0,0 +1,159 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+)
+
+func rolloutCloneSet(name string, updatedReady int32) *v1alpha1.CloneSet {
+	return &v1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name, UID: types.UID(name), Generation: 2},
+		Spec:       v1alpha1.CloneSetSpec{Replicas: ptr.To[int32](4)},
+		Status: v1alpha1.CloneSetStatus{
+			ObservedGeneration:   2,
+			UpdatedReplicas:      4,
+			UpdatedReadyReplicas: updatedReady,
+			ReadyReplicas:        4,
+		},
+	}
+}
+
+// rolloutSamples returns the rollout samples of the store.
+func rolloutSamples(s *MetricsStore) []string {
+	var samples []string
+	for _, line := range strings.Split(writeStore(s), "\n") {
+		if strings.HasPrefix(line, "kruise_cloneset_rollout_") {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
+
+func TestRolloutStore(t *testing.T) {
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+	tracker := newRolloutTracker(clock)
+	families := cloneSetRolloutMetricFamilies(tracker)
+	store := NewMetricsStore(families, composeMetricGenFuncs(families))
+	rollouts := &rolloutStore{Store: store, tracker: tracker}
+
+	if err := rollouts.Add(rolloutCloneSet("cs1", 2)); err != nil {
+		t.Fatal(err)
+	}
+	// The stalled seconds grow with the refreshes while the status doesn't
+	// change.
+	clock.SetTime(clock.Now().Add(time.Minute))
+	rollouts.refresh()
+	want := []string{
+		`kruise_cloneset_rollout_progress_ratio{namespace="ns1",cloneset="cs1"} 0.5`,
+		`kruise_cloneset_rollout_in_progress{namespace="ns1",cloneset="cs1"} 1`,
+		`kruise_cloneset_rollout_stalled_seconds{namespace="ns1",cloneset="cs1"} 60`,
+	}
+	if diff := cmp.Diff(want, rolloutSamples(store)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// Progress resets the stalled seconds.
+	clock.SetTime(clock.Now().Add(time.Minute))
+	if err := rollouts.Update(rolloutCloneSet("cs1", 3)); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(30 * time.Second))
+	rollouts.refresh()
+	want = []string{
+		`kruise_cloneset_rollout_progress_ratio{namespace="ns1",cloneset="cs1"} 0.75`,
+		`kruise_cloneset_rollout_in_progress{namespace="ns1",cloneset="cs1"} 1`,
+		`kruise_cloneset_rollout_stalled_seconds{namespace="ns1",cloneset="cs1"} 30`,
+	}
+	if diff := cmp.Diff(want, rolloutSamples(store)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// Complete rollouts are not refreshed any more.
+	if err := rollouts.Update(rolloutCloneSet("cs1", 4)); err != nil {
+		t.Fatal(err)
+	}
+	if got := tracker.objects(rollouts); len(got) != 0 {
+		t.Errorf("expected no rollout in progress, got %d", len(got))
+	}
+	want = []string{
+		`kruise_cloneset_rollout_progress_ratio{namespace="ns1",cloneset="cs1"} 1`,
+		`kruise_cloneset_rollout_in_progress{namespace="ns1",cloneset="cs1"} 0`,
+		`kruise_cloneset_rollout_stalled_seconds{namespace="ns1",cloneset="cs1"} 0`,
+	}
+	if diff := cmp.Diff(want, rolloutSamples(store)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// Deleted objects and objects left out of a relist are forgotten.
+	if err := rollouts.Replace([]interface{}{rolloutCloneSet("cs2", 1), rolloutCloneSet("cs3", 1)}, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(tracker.objects(rollouts)); got != 2 {
+		t.Errorf("expected 2 rollouts in progress, got %d", got)
+	}
+	if err := rollouts.Delete(rolloutCloneSet("cs2", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rollouts.Replace([]interface{}{rolloutCloneSet("cs1", 1)}, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := tracker.objects(rollouts); len(got) != 1 || got[0].(*v1alpha1.CloneSet).Name != "cs1" {
+		t.Errorf("expected only the rollout of cs1 in progress, got %v", got)
+	}
+}
+
+func TestRolloutProgress(t *testing.T) {
+	partitioned := rolloutCloneSet("cs1", 1)
+	partitioned.Spec.UpdateStrategy.Partition = ptr.To(intstr.FromString("50%"))
+	partitioned.Status.UpdatedReplicas = 2
+	unobserved := rolloutCloneSet("cs1", 4)
+	unobserved.Generation = 3
+
+	for _, test := range []struct {
+		name       string
+		cs         *v1alpha1.CloneSet
+		ratio      float64
+		inProgress bool
+	}{
+		{name: "partition", cs: partitioned, ratio: 0.5, inProgress: true},
+		{name: "unobserved generation", cs: unobserved, ratio: 1, inProgress: true},
+		{name: "complete", cs: rolloutCloneSet("cs1", 4), ratio: 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			families := cloneSetRolloutMetricFamilies(newRolloutTracker(clocktesting.NewFakePassiveClock(time.Now())))
+			m := families[0].Generate(test.cs)
+			if m.Metrics[0].Value != test.ratio {
+				t.Errorf("expected ratio %v, got %v", test.ratio, m.Metrics[0].Value)
+			}
+			m = families[1].Generate(test.cs)
+			if got := m.Metrics[0].Value == 1; got != test.inProgress {
+				t.Errorf("expected in progress %v, got %v", test.inProgress, got)
+			}
+		})
+	}
+}
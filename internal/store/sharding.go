@@ -0,0 +1,179 @@
+/*
+Copyright 2026 The Kruise Authors.
+Copyright 2019 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"hash/fnv"
+	"sort"
+
+	jump "github.com/dgryski/go-jump"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ShardingJump assigns objects with the jump consistent hash, the
+	// algorithm used by kube-state-metrics.
+	ShardingJump = "jump"
+	// ShardingRendezvous assigns objects with rendezvous (highest random
+	// weight) hashing.
+	ShardingRendezvous = "rendezvous"
+)
+
+// shardFunc returns the shard of the object with the given uid.
+type shardFunc func(uid types.UID, totalShards int) int32
+
+var shardingAlgorithms = map[string]shardFunc{
+	ShardingJump:       jumpShard,
+	ShardingRendezvous: rendezvousShard,
+}
+
+// ShardingAlgorithms returns the names of the available sharding algorithms.
+func ShardingAlgorithms() []string {
+	names := make([]string, 0, len(shardingAlgorithms))
+	for name := range shardingAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jumpShard is the jump consistent hash of the uid. Growing the number of
+// shards from N to N+1 moves 1/(N+1) of the objects, all to the new shard.
+func jumpShard(uid types.UID, totalShards int) int32 {
+	h := fnv.New64a()
+	h.Write([]byte(uid))
+	return jump.Hash(h.Sum64(), totalShards)
+}
+
+// rendezvousShard picks the shard with the highest weight for the uid.
+// Growing the number of shards from N to N+1 moves 1/(N+1) of the objects,
+// like jumpShard. It costs O(N) per object but the assignment of an object
+// only depends on the weights of the shards, not on their count.
+func rendezvousShard(uid types.UID, totalShards int) int32 {
+	h := fnv.New64a()
+	h.Write([]byte(uid))
+	key := h.Sum64()
+
+	var shard int32
+	var best uint64
+	for i := 0; i < totalShards; i++ {
+		if w := mix64(key ^ mix64(uint64(i))); i == 0 || w > best {
+			shard, best = int32(i), w
+		}
+	}
+	return shard
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// unshardedListWatch marks the cache.ListerWatchers of the objects every
+// shard watches, like the pods, as the metrics derived from them belong to
+// objects of any shard.
+type unshardedListWatch struct {
+	cache.ListerWatcher
+}
+
+type shardedListWatch struct {
+	shard       int32
+	totalShards int
+	shardFunc   shardFunc
+	lw          cache.ListerWatcher
+}
+
+// newShardedListWatch returns a cache.ListerWatcher which only keeps the
+// objects of the given shard. In the case of no sharding needed, it returns
+// the provided cache.ListerWatcher.
+func newShardedListWatch(shard int32, totalShards int, f shardFunc, lw cache.ListerWatcher) cache.ListerWatcher {
+	if shard == 0 && totalShards == 1 {
+		return lw
+	}
+
+	return &shardedListWatch{shard: shard, totalShards: totalShards, shardFunc: f, lw: lw}
+}
+
+func (s *shardedListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
+	list, err := s.lw.List(options)
+	if err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	metaObj, err := meta.ListAccessor(list)
+	if err != nil {
+		return nil, err
+	}
+	res := &metav1.List{
+		Items: []runtime.RawExtension{},
+	}
+	for _, item := range items {
+		a, err := meta.Accessor(item)
+		if err != nil {
+			return nil, err
+		}
+		if s.keep(a) {
+			res.Items = append(res.Items, runtime.RawExtension{Object: item})
+		}
+	}
+	res.ListMeta.ResourceVersion = metaObj.GetResourceVersion()
+
+	return res, nil
+}
+
+func (s *shardedListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	w, err := s.lw.Watch(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return watch.Filter(w, func(in watch.Event) (out watch.Event, keep bool) {
+		a, err := meta.Accessor(in.Object)
+		if err != nil {
+			return in, true
+		}
+
+		return in, s.keep(a)
+	}), nil
+}
+
+func (s *shardedListWatch) keep(o metav1.Object) bool {
+	return s.shardFunc(shardKey(o), s.totalShards) == s.shard
+}
+
+// shardKey returns the uid an object is sharded by. The events are sharded by
+// their involved object, so that the events of an object are kept by a single
+// shard, the one of the object itself.
+func shardKey(o metav1.Object) types.UID {
+	if e, ok := o.(*v1.Event); ok && e.InvolvedObject.UID != "" {
+		return e.InvolvedObject.UID
+	}
+	return o.GetUID()
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+	"hash/fnv"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const shardingTestObjects = 20000
+
+func shardingTestUIDs() []types.UID {
+	uids := make([]types.UID, shardingTestObjects)
+	for i := range uids {
+		uids[i] = types.UID(fmt.Sprintf("6f1e0c1c-%04x-4b5e-9a7d-%012x", i%0xffff, i))
+	}
+	return uids
+}
+
+// moduloShard is the naive hash modulo total shards assignment, only used to
+// compare the churn of the sharding algorithms against.
+func moduloShard(uid types.UID, totalShards int) int32 {
+	h := fnv.New64a()
+	h.Write([]byte(uid))
+	return int32(h.Sum64() % uint64(totalShards))
+}
+
+// movedFraction returns the fraction of objects assigned to another shard
+// when the number of shards grows from n to n+1.
+func movedFraction(f shardFunc, uids []types.UID, n int) float64 {
+	moved := 0
+	for _, uid := range uids {
+		if f(uid, n) != f(uid, n+1) {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(uids))
+}
+
+func TestShardingChurn(t *testing.T) {
+	uids := shardingTestUIDs()
+
+	for _, name := range ShardingAlgorithms() {
+		f := shardingAlgorithms[name]
+		for n := 1; n <= 10; n++ {
+			got := movedFraction(f, uids, n)
+			want := 1 / float64(n+1)
+			t.Logf("%s: %d -> %d shards moves %.3f of the objects (ideal %.3f, modulo %.3f)", name, n, n+1, got, want, movedFraction(moduloShard, uids, n))
+			if got < want*0.9 || got > want*1.1 {
+				t.Errorf("%s: expected about %.3f of the objects to move from %d to %d shards, got %.3f", name, want, n, n+1, got)
+			}
+		}
+	}
+}
+
+func TestShardingOnlyMovesToNewShard(t *testing.T) {
+	uids := shardingTestUIDs()
+
+	for _, name := range ShardingAlgorithms() {
+		f := shardingAlgorithms[name]
+		for n := 1; n <= 10; n++ {
+			for _, uid := range uids {
+				if before, after := f(uid, n), f(uid, n+1); before != after && after != int32(n) {
+					t.Fatalf("%s: object %s moved from shard %d to existing shard %d when growing to %d shards", name, uid, before, after, n+1)
+				}
+			}
+		}
+	}
+}
+
+func TestShardingBalance(t *testing.T) {
+	uids := shardingTestUIDs()
+
+	for _, name := range ShardingAlgorithms() {
+		f := shardingAlgorithms[name]
+		for _, n := range []int{2, 3, 5, 8} {
+			counts := make([]int, n)
+			for _, uid := range uids {
+				shard := f(uid, n)
+				if shard < 0 || int(shard) >= n {
+					t.Fatalf("%s: shard %d out of range for %d shards", name, shard, n)
+				}
+				counts[shard]++
+			}
+			want := float64(len(uids)) / float64(n)
+			for shard, count := range counts {
+				if float64(count) < want*0.9 || float64(count) > want*1.1 {
+					t.Errorf("%s: expected about %.0f objects on shard %d of %d, got %d", name, want, shard, n, count)
+				}
+			}
+		}
+	}
+}
+
+func TestShardedListWatchEvents(t *testing.T) {
+	var events []runtime.Object
+	for i := 0; i < 20; i++ {
+		events = append(events, &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "ns1", Name: fmt.Sprintf("cs1.%d", i), UID: types.UID(fmt.Sprintf("event-%d", i))},
+			InvolvedObject: v1.ObjectReference{Kind: "CloneSet", Namespace: "ns1", Name: "cs1", UID: "cs1"},
+		})
+	}
+	kubeClient := fake.NewSimpleClientset(events...)
+
+	kept := 0
+	for shard := int32(0); shard < 3; shard++ {
+		lw := newShardedListWatch(shard, 3, jumpShard, createEventListWatch(kubeClient, metav1.NamespaceAll, "", ""))
+		list, err := lw.List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(items); n != 0 && n != len(events) {
+			t.Errorf("expected the events of an object to be kept by a single shard, shard %d kept %d of them", shard, n)
+		}
+		kept += len(items)
+	}
+	if kept != len(events) {
+		t.Errorf("expected all the events to be kept once, got %d", kept)
+	}
+}
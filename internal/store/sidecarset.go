@@ -29,7 +29,6 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/kube-state-metrics/v2/pkg/metric"
-	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
 )
 
 var (
@@ -40,9 +39,9 @@ var (
 	descSidecarSetLabelsDefaultLabels = []string{"namespace", "sidecarset"}
 )
 
-func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []generator.FamilyGenerator {
-	return []generator.FamilyGenerator{
-		*generator.NewFamilyGenerator(
+func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
 			"kruise_sidecarset_created",
 			"Unix creation timestamp",
 			metric.Gauge,
@@ -61,7 +60,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_status_replicas_matched",
 			"The number of matched replicas per sidecarset.",
 			metric.Gauge,
@@ -76,7 +75,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_status_replicas_updated",
 			"The number of updated replicas per sidecarset.",
 			metric.Gauge,
@@ -91,7 +90,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_status_replicas_ready",
 			"The number of ready  replicas per sidecarset.",
 			metric.Gauge,
@@ -106,7 +105,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_status_observed_generation",
 			"The generation observed by the sidecarset controller.",
 			metric.Gauge,
@@ -121,7 +120,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_status_replicas_updated_ready",
 			"The number of update and ready replicas per sidecarset.",
 			metric.Gauge,
@@ -136,7 +135,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_namespcace",
 			"The namespace matched pods in.",
 			metric.Gauge,
@@ -152,7 +151,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_strategy_rollingupdate_max_unavailable",
 			"Maximum number of unavailable replicas during a rolling update of a sidecarset.",
 			metric.Gauge,
@@ -172,7 +171,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_strategy_partition",
 			"Desired number or percent of Pods in old revisions.",
 			metric.Gauge,
@@ -192,7 +191,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_strategy_type",
 			"The type of updateStrategy.",
 			metric.Gauge,
@@ -208,7 +207,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_metadata_generation",
 			"Sequence number representing a specific generation of the desired state.",
 			metric.Gauge,
@@ -223,7 +222,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descSidecarSetAnnotationsName,
 			descSidecarSetAnnotationsHelp,
 			metric.Gauge,
@@ -241,7 +240,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descSidecarSetLabelsName,
 			descSidecarSetLabelsHelp,
 			metric.Gauge,
@@ -259,7 +258,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_containers_injectpolicy",
 			"The rules that injected SidecarContainer into Pod.spec.containers.",
 			metric.Gauge,
@@ -277,7 +276,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_containers_strategy_type",
 			"The type of containers' upgradeStrategy.",
 			metric.Gauge,
@@ -295,7 +294,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_containers_strategy_hotupgradeemptyimage",
 			"The consistent of sidecar container.",
 			metric.Gauge,
@@ -313,7 +312,7 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_sidecarset_spec_containers_volumepolicy",
 			"The other container's VolumeMounts shared.",
 			metric.Gauge,
@@ -334,6 +333,29 @@ func sidecarSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []
 	}
 }
 
+// sidecarSetRolloutProgress returns the rollout progress of a sidecarset, the
+// matched pods in old revisions are set by the partition.
+func sidecarSetRolloutProgress(sc *v1alpha1.SidecarSet) rolloutProgress {
+	partition := 0
+	if sc.Spec.UpdateStrategy.Partition != nil {
+		// Errors leave the partition at 0 like the controller.
+		partition, _ = intstr.GetScaledValueFromIntOrPercent(sc.Spec.UpdateStrategy.Partition, int(sc.Status.MatchedPods), true)
+	}
+	return rolloutProgress{
+		target:             max(sc.Status.MatchedPods-int32(partition), 0),
+		updated:            sc.Status.UpdatedPods,
+		updatedReady:       sc.Status.UpdatedReadyPods,
+		ready:              sc.Status.ReadyPods,
+		generation:         sc.Generation,
+		observedGeneration: sc.Status.ObservedGeneration,
+	}
+}
+
+// sidecarSetRolloutMetricFamilies returns the rollout families of sidecarsets.
+func sidecarSetRolloutMetricFamilies(tracker *rolloutTracker) []FamilyGenerator {
+	return rolloutMetricFamilies("sidecarset", tracker, sidecarSetRolloutProgress, wrapSidecarSetFunc)
+}
+
 func wrapSidecarSetFunc(f func(*v1alpha1.SidecarSet) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		sidecarset := obj.(*v1alpha1.SidecarSet)
@@ -349,13 +371,17 @@ func wrapSidecarSetFunc(f func(*v1alpha1.SidecarSet) *metric.Family) func(interf
 	}
 }
 
-func createSidecarSetListWatch(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+func createSidecarSetListWatch(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
 	// namespace(ns) unused
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().SidecarSets().List(context.TODO(), opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().SidecarSets().Watch(context.TODO(), opts)
 		},
 	}
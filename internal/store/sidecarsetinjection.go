@@ -0,0 +1,440 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// sidecarSetHashAnnotation is the annotation of the hash of a SidecarSet, and
+// of the hashes of the SidecarSets injected into a pod.
+const sidecarSetHashAnnotation = "kruise.io/sidecarset-hash"
+
+var (
+	descSidecarSetInjectionLabels   = []string{"namespace", "sidecarset", "owner_kind", "owner_name"}
+	descSidecarSetNotInjectedLabels = []string{"namespace", "sidecarset", "pod", "owner_kind", "owner_name"}
+)
+
+// injectionSidecarSet is what the injection coverage needs of a SidecarSet.
+type injectionSidecarSet struct {
+	name      string
+	namespace string
+	selector  labels.Selector
+	// namespaceSelector is nil if the SidecarSet selects no namespaces.
+	namespaceSelector labels.Selector
+	// hash is the latest hash of the SidecarSet, empty until computed by the
+	// Kruise controller.
+	hash string
+}
+
+func newInjectionSidecarSet(sc *v1alpha1.SidecarSet) *injectionSidecarSet {
+	s := &injectionSidecarSet{
+		name:      sc.Name,
+		namespace: sc.Spec.Namespace,
+		selector:  labels.Nothing(),
+		hash:      sc.Annotations[sidecarSetHashAnnotation],
+	}
+	if selector, err := metav1.LabelSelectorAsSelector(sc.Spec.Selector); err == nil && !selector.Empty() {
+		s.selector = selector
+	}
+	if sc.Spec.NamespaceSelector != nil {
+		s.namespaceSelector = labels.Nothing()
+		if selector, err := metav1.LabelSelectorAsSelector(sc.Spec.NamespaceSelector); err == nil {
+			s.namespaceSelector = selector
+		}
+	}
+	return s
+}
+
+// matches returns whether the SidecarSet selects the pod, like the Kruise
+// webhook does. The labels of the namespaces are only looked up for the
+// SidecarSets with a namespace selector.
+func (s *injectionSidecarSet) matches(pod *injectionPod, namespaceLabels func(string) (map[string]string, bool)) bool {
+	if s.namespace != "" && s.namespace != pod.namespace {
+		return false
+	}
+	if s.namespaceSelector != nil {
+		nsLabels, ok := namespaceLabels(pod.namespace)
+		if !ok || !s.namespaceSelector.Matches(labels.Set(nsLabels)) {
+			return false
+		}
+	}
+	return s.selector.Matches(labels.Set(pod.labels))
+}
+
+// injectionPod is what the injection coverage needs of a pod.
+type injectionPod struct {
+	namespace string
+	name      string
+	labels    map[string]string
+	ownerKind string
+	ownerName string
+	// hashes holds the hashes of the SidecarSets injected into the pod by
+	// SidecarSet name.
+	hashes map[string]string
+	// injections holds the contribution of the pod to the counts of the
+	// SidecarSets matching it or injected into it, by SidecarSet UID.
+	injections map[types.UID]podInjection
+}
+
+func newInjectionPod(pod *v1.Pod) *injectionPod {
+	p := &injectionPod{
+		namespace: pod.Namespace,
+		name:      pod.Name,
+		labels:    pod.Labels,
+		ownerKind: "<none>",
+		ownerName: "<none>",
+		hashes:    injectedSidecarSetHashes(pod),
+
+		injections: map[types.UID]podInjection{},
+	}
+	if ref := metav1.GetControllerOf(pod); ref != nil {
+		p.ownerKind = ref.Kind
+		p.ownerName = ref.Name
+	}
+	return p
+}
+
+// injectedSidecarSetHashes returns the hashes of the SidecarSets injected into
+// a pod from its kruise.io/sidecarset-hash annotation.
+func injectedSidecarSetHashes(pod *v1.Pod) map[string]string {
+	v, ok := pod.Annotations[sidecarSetHashAnnotation]
+	if !ok {
+		return nil
+	}
+	var upgrades map[string]struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal([]byte(v), &upgrades); err == nil {
+		hashes := make(map[string]string, len(upgrades))
+		for name, upgrade := range upgrades {
+			hashes[name] = upgrade.Hash
+		}
+		return hashes
+	}
+	// Early Kruise versions stored the hashes directly.
+	var hashes map[string]string
+	if err := json.Unmarshal([]byte(v), &hashes); err == nil {
+		return hashes
+	}
+	return nil
+}
+
+// injectionKey identifies the pods of an owner in a namespace matched by a
+// SidecarSet or injected with its sidecars.
+type injectionKey struct {
+	namespace, sidecarSet, ownerKind, ownerName string
+}
+
+// injectionCounts are the pods of an owner in a namespace matched by a
+// SidecarSet or injected with its sidecars, or the contribution of a single
+// pod to them.
+type injectionCounts struct {
+	matched, injected, latestHash, oldHash float64
+}
+
+func (c *injectionCounts) add(o *injectionCounts, sign float64) {
+	c.matched += sign * o.matched
+	c.injected += sign * o.injected
+	c.latestHash += sign * o.latestHash
+	c.oldHash += sign * o.oldHash
+}
+
+// podInjection is the contribution of a pod to the counts of a SidecarSet.
+type podInjection struct {
+	sidecarSet string
+	counts     injectionCounts
+}
+
+// notInjected returns whether the SidecarSet matches the pod but its sidecars
+// were not injected.
+func (in *podInjection) notInjected() bool {
+	return in.counts.matched > 0 && in.counts.injected == 0
+}
+
+// inject returns the contribution of a pod to the counts of the SidecarSet,
+// false if the SidecarSet neither matches the pod nor was injected into it.
+func (s *injectionSidecarSet) inject(pod *injectionPod, namespaceLabels func(string) (map[string]string, bool)) (podInjection, bool) {
+	matched := s.matches(pod, namespaceLabels)
+	hash, injected := pod.hashes[s.name]
+	if !matched && !injected {
+		return podInjection{}, false
+	}
+	in := podInjection{
+		sidecarSet: s.name,
+		counts: injectionCounts{
+			matched:  BoolFloat64(matched),
+			injected: BoolFloat64(injected),
+		},
+	}
+	if injected && s.hash != "" {
+		in.counts.latestHash = BoolFloat64(hash == s.hash)
+		in.counts.oldHash = BoolFloat64(hash != s.hash)
+	}
+	return in, true
+}
+
+// sidecarSetInjections counts the pods matched by the SidecarSets or injected
+// with their sidecars. The counts are maintained as the SidecarSets, the pods
+// and the labels of the namespaces change, so that collecting them doesn't
+// depend on the number of pods. It is the objectObserver of the SidecarSets
+// and the podHandler of the pods.
+type sidecarSetInjections struct {
+	collectorWriter
+	namespaceLabels func(string) (map[string]string, bool)
+	// workloadOf resolves the owner of the pods up to their workload.
+	workloadOf func(namespace, kind, name string) (string, string)
+
+	matchedDesc     *prometheus.Desc
+	injectedDesc    *prometheus.Desc
+	latestHashDesc  *prometheus.Desc
+	oldHashDesc     *prometheus.Desc
+	notInjectedDesc *prometheus.Desc
+
+	// Protects sidecarSets, pods, counts and notInjected
+	mutex       sync.RWMutex
+	sidecarSets map[types.UID]*injectionSidecarSet
+	pods        map[types.UID]*injectionPod
+	// counts are keyed by the owners of the pods, which are resolved up to
+	// their workload when collected.
+	counts map[injectionKey]*injectionCounts
+	// notInjected holds the pods matched by a SidecarSet which was not
+	// injected into them.
+	notInjected map[types.UID]*injectionPod
+}
+
+func newSidecarSetInjections(
+	namespaceLabels func(string) (map[string]string, bool),
+	workloadOf func(namespace, kind, name string) (string, string),
+) *sidecarSetInjections {
+	i := &sidecarSetInjections{
+		namespaceLabels: namespaceLabels,
+		workloadOf:      workloadOf,
+		matchedDesc: prometheus.NewDesc(
+			"kruise_sidecarset_pods_matched",
+			"Number of pods of a workload in a namespace matched by a sidecarset.",
+			descSidecarSetInjectionLabels, nil,
+		),
+		injectedDesc: prometheus.NewDesc(
+			"kruise_sidecarset_pods_injected",
+			"Number of pods of a workload in a namespace injected with the sidecars of a sidecarset.",
+			descSidecarSetInjectionLabels, nil,
+		),
+		latestHashDesc: prometheus.NewDesc(
+			"kruise_sidecarset_pods_latest_hash",
+			"Number of pods of a workload in a namespace injected with the latest hash of a sidecarset.",
+			descSidecarSetInjectionLabels, nil,
+		),
+		oldHashDesc: prometheus.NewDesc(
+			"kruise_sidecarset_pods_old_hash",
+			"Number of pods of a workload in a namespace injected with an old hash of a sidecarset.",
+			descSidecarSetInjectionLabels, nil,
+		),
+		notInjectedDesc: prometheus.NewDesc(
+			"kruise_sidecarset_pod_not_injected",
+			"Pods matched by a sidecarset which were not injected with its sidecars, e.g. because they were created before the sidecarset.",
+			descSidecarSetNotInjectedLabels, nil,
+		),
+		sidecarSets: map[types.UID]*injectionSidecarSet{},
+		pods:        map[types.UID]*injectionPod{},
+		counts:      map[injectionKey]*injectionCounts{},
+		notInjected: map[types.UID]*injectionPod{},
+	}
+	i.collectorWriter = newCollectorWriter("sidecarsets", i,
+		"kruise_sidecarset_pods_matched",
+		"kruise_sidecarset_pods_injected",
+		"kruise_sidecarset_pods_latest_hash",
+		"kruise_sidecarset_pods_old_hash",
+		"kruise_sidecarset_pod_not_injected",
+	)
+	return i
+}
+
+// updateObject implements objectObserver. The SidecarSet is matched against
+// every pod again.
+func (i *sidecarSetInjections) updateObject(sc *v1alpha1.SidecarSet) {
+	s := newInjectionSidecarSet(sc)
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.sidecarSets[sc.UID] = s
+	for uid, p := range i.pods {
+		i.inject(uid, p, sc.UID, s)
+	}
+}
+
+// deleteObject implements objectObserver.
+func (i *sidecarSetInjections) deleteObject(uid types.UID) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	delete(i.sidecarSets, uid)
+	for podUID, p := range i.pods {
+		i.inject(podUID, p, uid, nil)
+	}
+}
+
+// updatePod implements podHandler. The pods which are done don't run their
+// sidecars any more and are left out.
+func (i *sidecarSetInjections) updatePod(pod *v1.Pod) {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		i.deletePod(pod.UID)
+		return
+	}
+	p := newInjectionPod(pod)
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.remove(pod.UID)
+	i.pods[pod.UID] = p
+	for uid, s := range i.sidecarSets {
+		i.inject(pod.UID, p, uid, s)
+	}
+}
+
+// deletePod implements podHandler.
+func (i *sidecarSetInjections) deletePod(uid types.UID) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.remove(uid)
+}
+
+// updateNamespace matches the SidecarSets with a namespace selector against
+// the pods of a namespace again, after the namespace was added, relabeled or
+// deleted.
+func (i *sidecarSetInjections) updateNamespace(name string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for podUID, p := range i.pods {
+		if p.namespace != name {
+			continue
+		}
+		for uid, s := range i.sidecarSets {
+			if s.namespaceSelector != nil {
+				i.inject(podUID, p, uid, s)
+			}
+		}
+	}
+}
+
+// inject replaces the contribution of a pod to the counts of a SidecarSet,
+// removes it if s is nil. The mutex must be held.
+func (i *sidecarSetInjections) inject(podUID types.UID, p *injectionPod, uid types.UID, s *injectionSidecarSet) {
+	if in, ok := p.injections[uid]; ok {
+		i.count(p, &in, -1)
+		delete(p.injections, uid)
+	}
+	if s != nil {
+		if in, ok := s.inject(p, i.namespaceLabels); ok {
+			p.injections[uid] = in
+			i.count(p, &in, 1)
+		}
+	}
+
+	delete(i.notInjected, podUID)
+	for _, in := range p.injections {
+		if in.notInjected() {
+			i.notInjected[podUID] = p
+			break
+		}
+	}
+}
+
+// remove subtracts the contribution of a pod from the counts. The mutex must
+// be held.
+func (i *sidecarSetInjections) remove(uid types.UID) {
+	p, ok := i.pods[uid]
+	if !ok {
+		return
+	}
+	delete(i.pods, uid)
+	delete(i.notInjected, uid)
+	for _, in := range p.injections {
+		i.count(p, &in, -1)
+	}
+}
+
+// count adds the contribution of a pod to the counts of its owner, and drops
+// the counts once no pod is left. The mutex must be held.
+func (i *sidecarSetInjections) count(p *injectionPod, in *podInjection, sign float64) {
+	k := injectionKey{namespace: p.namespace, sidecarSet: in.sidecarSet, ownerKind: p.ownerKind, ownerName: p.ownerName}
+	c, ok := i.counts[k]
+	if !ok {
+		c = &injectionCounts{}
+		i.counts[k] = c
+	}
+	c.add(&in.counts, sign)
+	if c.matched <= 0 && c.injected <= 0 {
+		delete(i.counts, k)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (i *sidecarSetInjections) Describe(ch chan<- *prometheus.Desc) {
+	ch <- i.matchedDesc
+	ch <- i.injectedDesc
+	ch <- i.latestHashDesc
+	ch <- i.oldHashDesc
+	ch <- i.notInjectedDesc
+}
+
+// Collect implements prometheus.Collector. The owners of the pods are
+// resolved up to their workload when collected, as the owners of the
+// ReplicaSets may be seen after the pods.
+func (i *sidecarSetInjections) Collect(ch chan<- prometheus.Metric) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	counts := make(map[injectionKey]*injectionCounts, len(i.counts))
+	for k, c := range i.counts {
+		k.ownerKind, k.ownerName = i.workloadOf(k.namespace, k.ownerKind, k.ownerName)
+		totals, ok := counts[k]
+		if !ok {
+			totals = &injectionCounts{}
+			counts[k] = totals
+		}
+		totals.add(c, 1)
+	}
+	for k, c := range counts {
+		labelValues := []string{k.namespace, k.sidecarSet, k.ownerKind, k.ownerName}
+		ch <- prometheus.MustNewConstMetric(i.matchedDesc, prometheus.GaugeValue, c.matched, labelValues...)
+		ch <- prometheus.MustNewConstMetric(i.injectedDesc, prometheus.GaugeValue, c.injected, labelValues...)
+		ch <- prometheus.MustNewConstMetric(i.latestHashDesc, prometheus.GaugeValue, c.latestHash, labelValues...)
+		ch <- prometheus.MustNewConstMetric(i.oldHashDesc, prometheus.GaugeValue, c.oldHash, labelValues...)
+	}
+
+	for _, p := range i.notInjected {
+		ownerKind, ownerName := i.workloadOf(p.namespace, p.ownerKind, p.ownerName)
+		for _, in := range p.injections {
+			if in.notInjected() {
+				ch <- prometheus.MustNewConstMetric(i.notInjectedDesc, prometheus.GaugeValue, 1, p.namespace, in.sidecarSet, p.name, ownerKind, ownerName)
+			}
+		}
+	}
+}
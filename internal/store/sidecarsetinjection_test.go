@@ -0,0 +1,236 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+// injectionTestSidecarSet returns a sidecarset of the given latest hash
+// selecting the pods labeled app=web.
+func injectionTestSidecarSet(name, hash string) *v1alpha1.SidecarSet {
+	return &v1alpha1.SidecarSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			UID:         types.UID(name),
+			Annotations: map[string]string{sidecarSetHashAnnotation: hash},
+		},
+		Spec: v1alpha1.SidecarSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			UpdateStrategy: v1alpha1.SidecarSetUpdateStrategy{
+				MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+				Partition:      ptr.To(intstr.FromInt32(0)),
+			},
+		},
+	}
+}
+
+// injectionTestPod returns a pod of the given app, controlled by the cloneset
+// owner if not empty, with the given sidecarset hash annotation if not empty.
+func injectionTestPod(namespace, name, app, owner, hashes string) *v1.Pod {
+	pod := testPod(namespace, name)
+	pod.Labels = map[string]string{"app": app}
+	if owner != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "CloneSet",
+			Name:       owner,
+			Controller: ptr.To(true),
+		}}
+	}
+	if hashes != "" {
+		pod.Annotations = map[string]string{sidecarSetHashAnnotation: hashes}
+	}
+	return pod
+}
+
+func TestSidecarSetInjections(t *testing.T) {
+	namespaces := map[string]map[string]string{"ns2": {"env": "prod"}}
+	owners := &workloadOwners{stores: []cache.Store{cache.NewStore(cache.MetaNamespaceKeyFunc)}}
+	if err := owners.stores[0].Add(&metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ns1",
+		Name:      "api-5d8f",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       "api",
+			Controller: ptr.To(true),
+		}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	injections := newSidecarSetInjections(func(ns string) (map[string]string, bool) {
+		l, ok := namespaces[ns]
+		return l, ok
+	}, owners.resolve)
+	store := newObservedStore[*v1alpha1.SidecarSet](cache.NewStore(cache.MetaNamespaceKeyFunc), injections)
+
+	prod := injectionTestSidecarSet("prod", "h1")
+	prod.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	if err := store.Replace([]interface{}{injectionTestSidecarSet("web", "h2"), prod}, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pods of a Deployment are counted by Deployment.
+	deployed := injectionTestPod("ns1", "api", "web", "", `{"web":{"hash":"h2","sidecarSetName":"web"}}`)
+	deployed.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "api-5d8f", Controller: ptr.To(true)}}
+	done := injectionTestPod("ns1", "done", "web", "cs1", "")
+	done.Status.Phase = v1.PodSucceeded
+	for _, pod := range []*v1.Pod{
+		injectionTestPod("ns1", "latest", "web", "cs1", `{"web":{"hash":"h2","sidecarSetName":"web"}}`),
+		injectionTestPod("ns1", "old", "web", "cs1", `{"web":{"hash":"h1","sidecarSetName":"web"}}`),
+		injectionTestPod("ns1", "missing", "web", "cs1", ""),
+		// Injected, but not matched any more.
+		injectionTestPod("ns1", "relabeled", "db", "cs1", `{"web":{"hash":"h1","sidecarSetName":"web"}}`),
+		// Annotated by an early Kruise version.
+		injectionTestPod("ns2", "standalone", "web", "", `{"web":"h2","prod":"h1"}`),
+		injectionTestPod("ns2", "db", "db", "", ""),
+		deployed,
+		done,
+	} {
+		injections.updatePod(pod)
+	}
+
+	want := []string{
+		`kruise_sidecarset_pod_not_injected{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",pod="missing",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_injected{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 3`,
+		`kruise_sidecarset_pods_injected{namespace="ns1",owner_kind="Deployment",owner_name="api",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_injected{namespace="ns2",owner_kind="<none>",owner_name="<none>",sidecarset="prod"} 1`,
+		`kruise_sidecarset_pods_injected{namespace="ns2",owner_kind="<none>",owner_name="<none>",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_latest_hash{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_latest_hash{namespace="ns1",owner_kind="Deployment",owner_name="api",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_latest_hash{namespace="ns2",owner_kind="<none>",owner_name="<none>",sidecarset="prod"} 1`,
+		`kruise_sidecarset_pods_latest_hash{namespace="ns2",owner_kind="<none>",owner_name="<none>",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_matched{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 3`,
+		`kruise_sidecarset_pods_matched{namespace="ns1",owner_kind="Deployment",owner_name="api",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_matched{namespace="ns2",owner_kind="<none>",owner_name="<none>",sidecarset="prod"} 1`,
+		`kruise_sidecarset_pods_matched{namespace="ns2",owner_kind="<none>",owner_name="<none>",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_old_hash{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 2`,
+		`kruise_sidecarset_pods_old_hash{namespace="ns1",owner_kind="Deployment",owner_name="api",sidecarset="web"} 0`,
+		`kruise_sidecarset_pods_old_hash{namespace="ns2",owner_kind="<none>",owner_name="<none>",sidecarset="prod"} 0`,
+		`kruise_sidecarset_pods_old_hash{namespace="ns2",owner_kind="<none>",owner_name="<none>",sidecarset="web"} 0`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(injections)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// The sidecarsets and pods which are deleted are left out.
+	if err := store.Replace([]interface{}{injectionTestSidecarSet("web", "h1")}, "2"); err != nil {
+		t.Fatal(err)
+	}
+	injections.deletePod("ns1/missing")
+	injections.deletePod("ns1/relabeled")
+	injections.deletePod("ns2/standalone")
+	injections.deletePod("ns1/api")
+	want = []string{
+		`kruise_sidecarset_pods_injected{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 2`,
+		`kruise_sidecarset_pods_latest_hash{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 1`,
+		`kruise_sidecarset_pods_matched{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 2`,
+		`kruise_sidecarset_pods_old_hash{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 1`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(injections)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSidecarSetInjectionsNamespaceLabels(t *testing.T) {
+	namespaces := map[string]map[string]string{"ns1": {}}
+	injections := newSidecarSetInjections(func(ns string) (map[string]string, bool) {
+		l, ok := namespaces[ns]
+		return l, ok
+	}, (&workloadOwners{}).resolve)
+
+	// The pods are seen before the sidecarset, and their namespace is
+	// labeled afterwards.
+	injections.updatePod(injectionTestPod("ns1", "p1", "web", "cs1", ""))
+	prod := injectionTestSidecarSet("prod", "h1")
+	prod.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	injections.updateObject(prod)
+	if samples := collectorSamples(injections); len(samples) != 0 {
+		t.Errorf("expected no samples, got %v", samples)
+	}
+
+	namespaces["ns1"] = map[string]string{"env": "prod"}
+	injections.updateNamespace("ns1")
+	want := []string{
+		`kruise_sidecarset_pod_not_injected{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",pod="p1",sidecarset="prod"} 1`,
+		`kruise_sidecarset_pods_injected{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="prod"} 0`,
+		`kruise_sidecarset_pods_latest_hash{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="prod"} 0`,
+		`kruise_sidecarset_pods_matched{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="prod"} 1`,
+		`kruise_sidecarset_pods_old_hash{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="prod"} 0`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(injections)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	delete(namespaces, "ns1")
+	injections.updateNamespace("ns1")
+	if samples := collectorSamples(injections); len(samples) != 0 {
+		t.Errorf("expected no samples, got %v", samples)
+	}
+}
+
+func TestSidecarSetInjectionMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}},
+		injectionTestPod("ns1", "p1", "web", "cs1", ""),
+	)
+	kruiseClient := kruisefake.NewSimpleClientset(injectionTestSidecarSet("web", "h1"))
+	b := newTestBuilder(t, ctx, kubeClient, kruiseClient, "sidecarsets")
+	b.WithPodMetrics(true)
+	writers := b.Build()
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_sidecarset_pod_not_injected{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",pod="p1",sidecarset="web"} 1`)
+	})
+
+	if _, err := kubeClient.CoreV1().Pods("ns1").Update(ctx, injectionTestPod("ns1", "p1", "web", "cs1", `{"web":{"hash":"h1"}}`), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_sidecarset_pods_latest_hash{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",sidecarset="web"} 1`) &&
+			!strings.Contains(out, "kruise_sidecarset_pod_not_injected{")
+	})
+
+	// The sidecarsets selecting namespaces follow their labels.
+	prod := injectionTestSidecarSet("prod", "h1")
+	prod.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	if _, err := kruiseClient.AppsV1alpha1().SidecarSets().Create(ctx, prod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: map[string]string{"env": "prod"}}}, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_sidecarset_pod_not_injected{namespace="ns1",owner_kind="CloneSet",owner_name="cs1",pod="p1",sidecarset="prod"} 1`)
+	})
+}
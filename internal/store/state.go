@@ -0,0 +1,80 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+)
+
+// StateReader gives structured access to the metrics of the objects of a
+// resource, as opposed to their text representation.
+type StateReader interface {
+	Resource() string
+	Namespaced() bool
+	FamilyNames() []string
+	// States returns the state of all objects. It is nil unless the stores
+	// were built with the state enabled.
+	States() []*ObjectState
+}
+
+// ObjectState holds the metric families generated for an object. It must
+// not be modified once it is stored.
+type ObjectState struct {
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	Families  []metric.Family
+}
+
+// newObjectState returns the state of the object with the given families.
+func newObjectState(o metav1.Object, families []metric.FamilyInterface) *ObjectState {
+	state := &ObjectState{
+		Namespace: o.GetNamespace(),
+		Name:      o.GetName(),
+		Labels:    o.GetLabels(),
+		Families:  make([]metric.Family, len(families)),
+	}
+	for i, f := range families {
+		f.Inspect(func(family metric.Family) {
+			state.Families[i] = family
+		})
+	}
+	return state
+}
+
+// Key returns namespace/name, or name for cluster scoped objects.
+func (s *ObjectState) Key() string {
+	if s.Namespace == "" {
+		return s.Name
+	}
+	return s.Namespace + "/" + s.Name
+}
+
+// StateEvent is a change of the state of an object. Old is nil for added
+// objects and New is nil for deleted objects.
+type StateEvent struct {
+	Type     watch.EventType
+	Resource string
+	Old      *ObjectState
+	New      *ObjectState
+}
+
+// StateListener is notified of the state changes of the objects of the
+// stores. It is called while the store is locked and must not block.
+type StateListener func(StateEvent)
@@ -28,7 +28,6 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/kube-state-metrics/v2/pkg/metric"
-	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
 
 	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
 )
@@ -41,9 +40,9 @@ var (
 	descStatefulSetLabelsDefaultLabels = []string{"namespace", "statefulset"}
 )
 
-func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []generator.FamilyGenerator {
-	return []generator.FamilyGenerator{
-		*generator.NewFamilyGenerator(
+func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
 			"kruise_statefulset_created",
 			"Unix creation timestamp",
 			metric.Gauge,
@@ -62,7 +61,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_replicas",
 			"The number of replicas per statefulset.",
 			metric.Gauge,
@@ -77,7 +76,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_replicas_available",
 			"The number of available replicas per statefulset.",
 			metric.Gauge,
@@ -92,7 +91,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_replicas_current",
 			"The number of current replicas per statefulset.",
 			metric.Gauge,
@@ -107,7 +106,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_replicas_ready",
 			"The number of ready replicas per statefulset.",
 			metric.Gauge,
@@ -122,7 +121,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_replicas_updated",
 			"The number of updated replicas per statefulset.",
 			metric.Gauge,
@@ -137,7 +136,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_observed_generation",
 			"The generation observed by the statefulset controller.",
 			metric.Gauge,
@@ -152,30 +151,29 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_condition",
 			"The current status conditions of a statefulset.",
 			metric.Gauge,
 			"",
 			wrapStatefulSetFunc(func(cs *v1beta1.StatefulSet) *metric.Family {
-				ms := make([]*metric.Metric, len(cs.Status.Conditions)*len(conditionStatuses))
-
-				for i, cs := range cs.Status.Conditions {
-					conditionMetrics := addConditionMetrics(cs.Status)
-
-					for j, m := range conditionMetrics {
-						m.LabelKeys = []string{"condition", "status"}
-						m.LabelValues = append([]string{string(cs.Type)}, m.LabelValues...)
-						ms[i*len(conditionStatuses)+j] = m
-					}
+				return &metric.Family{
+					Metrics: addStatusConditionMetrics(statefulSetConditions(cs)),
 				}
-
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_statefulset_status_condition_last_transition_time",
+			"Unix timestamp of the last transition of the current status conditions of a statefulset.",
+			metric.Gauge,
+			"",
+			wrapStatefulSetFunc(func(cs *v1beta1.StatefulSet) *metric.Family {
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: addConditionLastTransitionTimeMetrics(statefulSetConditions(cs)),
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_replicas",
 			"Number of desired pods for a statefulset.",
 			metric.Gauge,
@@ -194,7 +192,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_metadata_generation",
 			"Sequence number representing a specific generation of the desired state for the statefulset.",
 			metric.Gauge,
@@ -209,7 +207,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_spec_replicas",
 			"Number of desired pods for a statefulset.",
 			metric.Gauge,
@@ -224,7 +222,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_spec_strategy_rollingupdate_max_unavailable",
 			"Maximum number of unavailable replicas during a rolling update of a statefulset.",
 			metric.Gauge,
@@ -244,7 +242,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_spec_reserveordinals",
 			"Maximum number of replicas that can be scheduled above the desired number of replicas during a rolling update of a statefulset.",
 			metric.Gauge,
@@ -265,7 +263,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descStatefulSetAnnotationsName,
 			descStatefulSetAnnotationsHelp,
 			metric.Gauge,
@@ -283,7 +281,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descStatefulSetLabelsName,
 			descStatefulSetLabelsHelp,
 			metric.Gauge,
@@ -301,7 +299,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_current_revision",
 			"Indicates the version of the statefulset used to generate Pods in the sequence [0,currentReplicas).",
 			metric.Gauge,
@@ -318,7 +316,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_status_update_revision",
 			"Indicates the version of the statefulset used to generate Pods in the sequence [replicas-updatedReplicas,replicas)",
 			metric.Gauge,
@@ -335,7 +333,7 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_statefulset_spec_strategy_type",
 			"The type of updateStrategy.",
 			metric.Gauge,
@@ -354,6 +352,65 @@ func statefulSetMetricFamilies(allowAnnotationsList, allowLabelsList []string) [
 	}
 }
 
+// statefulSetRolloutProgress returns the rollout progress of an advanced
+// statefulset, the pods with an ordinal below the partition are not updated.
+func statefulSetRolloutProgress(s *v1beta1.StatefulSet) rolloutProgress {
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	partition := int32(0)
+	if s.Spec.UpdateStrategy.RollingUpdate != nil && s.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		partition = *s.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
+	return rolloutProgress{
+		target:             max(replicas-partition, 0),
+		updated:            s.Status.UpdatedReplicas,
+		updatedReady:       s.Status.UpdatedReadyReplicas,
+		ready:              s.Status.ReadyReplicas,
+		generation:         s.Generation,
+		observedGeneration: s.Status.ObservedGeneration,
+	}
+}
+
+// statefulSetRolloutMetricFamilies returns the rollout families of advanced
+// statefulsets.
+func statefulSetRolloutMetricFamilies(tracker *rolloutTracker) []FamilyGenerator {
+	return rolloutMetricFamilies("statefulset", tracker, statefulSetRolloutProgress, wrapStatefulSetFunc)
+}
+
+// statefulSetTransitionMetricFamilies returns the transition counters of
+// advanced statefulsets.
+func statefulSetTransitionMetricFamilies(tracker *transitionTracker) []FamilyGenerator {
+	return transitionMetricFamilies("statefulset", tracker, wrapStatefulSetFunc)
+}
+
+// statefulSetPodUpdateMetricFamilies returns the pod update counters of
+// advanced statefulsets.
+func statefulSetPodUpdateMetricFamilies(tracker *transitionTracker) []FamilyGenerator {
+	return podUpdateMetricFamilies("statefulset", tracker, wrapStatefulSetFunc)
+}
+
+// statefulSetConditions returns the status conditions of a statefulset.
+func statefulSetConditions(cs *v1beta1.StatefulSet) []statusCondition {
+	conditions := make([]statusCondition, len(cs.Status.Conditions))
+	for i, c := range cs.Status.Conditions {
+		conditions[i] = statusCondition{
+			conditionType:      string(c.Type),
+			status:             c.Status,
+			reason:             c.Reason,
+			lastTransitionTime: c.LastTransitionTime,
+		}
+	}
+	return conditions
+}
+
+// statefulSetConditionReasonMetricFamilies returns the family of the reasons of
+// the status conditions of advanced statefulsets.
+func statefulSetConditionReasonMetricFamilies(reasons []string) []FamilyGenerator {
+	return conditionReasonMetricFamilies("statefulset", reasons, statefulSetConditions, wrapStatefulSetFunc)
+}
+
 func wrapStatefulSetFunc(f func(*v1beta1.StatefulSet) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		statefulset := obj.(*v1beta1.StatefulSet)
@@ -369,12 +426,16 @@ func wrapStatefulSetFunc(f func(*v1beta1.StatefulSet) *metric.Family) func(inter
 	}
 }
 
-func createStatefulSetListWatch(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+func createStatefulSetListWatch(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1beta1().StatefulSets(ns).List(context.TODO(), opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1beta1().StatefulSets(ns).Watch(context.TODO(), opts)
 		},
 	}
@@ -20,15 +20,22 @@ package store
 // TODO: Does this file need to be renamed to not be compiled in production?
 
 import (
+	"bytes"
 	"fmt"
 	"regexp"
 	"sort"
 	"strings"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 
 	"k8s.io/kube-state-metrics/v2/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/v2/pkg/metrics_store"
 )
 
 type generateMetricsTestCase struct {
@@ -160,3 +167,43 @@ func removeUnusedWhitespace(s string) string {
 
 	return strings.Join(trimmedLines, "\n")
 }
+
+// collectorSamples returns the samples written by w, without the HELP and
+// TYPE lines.
+func collectorSamples(w metricsstore.MetricsWriter) []string {
+	buf := &bytes.Buffer{}
+	w.WriteAll(buf)
+	var samples []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
+
+// testObjectMeta returns the metadata of a test object, whose UID is its
+// namespace and name.
+func testObjectMeta(namespace, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID(namespace + "/" + name)}
+}
+
+// testPod returns a pod of the given namespace and name.
+func testPod(namespace, name string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: testObjectMeta(namespace, name)}
+}
+
+// testCloneSet returns a cloneset of the given replicas with the given ready
+// and updated and ready replicas.
+func testCloneSet(namespace, name string, replicas, ready, updatedReady int32) *v1alpha1.CloneSet {
+	return &v1alpha1.CloneSet{
+		ObjectMeta: testObjectMeta(namespace, name),
+		Spec:       v1alpha1.CloneSetSpec{Replicas: ptr.To(replicas)},
+		Status: v1alpha1.CloneSetStatus{
+			Replicas:             replicas,
+			ReadyReplicas:        ready,
+			UpdatedReplicas:      updatedReady,
+			UpdatedReadyReplicas: updatedReady,
+		},
+	}
+}
@@ -0,0 +1,482 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	scaleUp   = "up"
+	scaleDown = "down"
+)
+
+// rolloutDurationBuckets are the buckets of the rollout duration histograms,
+// from 30 seconds to about 17 hours.
+var rolloutDurationBuckets = prometheus.ExponentialBuckets(30, 2, 12)
+
+// transition is the state of a workload its transitions are derived from.
+type transition struct {
+	replicas       int32
+	updateRevision string
+	progress       rolloutProgress
+	// inPlaceIfPossible is whether the pods are updated in place if possible,
+	// and else recreated.
+	inPlaceIfPossible bool
+}
+
+// fullyUpdated returns whether all replicas are updated and ready, whatever
+// the partition.
+func (t transition) fullyUpdated() bool {
+	return t.progress.observedGeneration >= t.progress.generation &&
+		t.progress.updated >= t.replicas &&
+		t.progress.updatedReady >= t.replicas
+}
+
+// transitionOf returns the resource and the transition state of the
+// workloads whose transitions are counted.
+func transitionOf(obj interface{}) (string, transition, bool) {
+	switch o := obj.(type) {
+	case *v1alpha1.CloneSet:
+		return "clonesets", transition{
+			replicas:          ptr.Deref(o.Spec.Replicas, 1),
+			updateRevision:    o.Status.UpdateRevision,
+			progress:          cloneSetRolloutProgress(o),
+			inPlaceIfPossible: o.Spec.UpdateStrategy.Type == v1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType,
+		}, true
+	case *v1beta1.StatefulSet:
+		return "statefulsets", transition{
+			replicas:       ptr.Deref(o.Spec.Replicas, 1),
+			updateRevision: o.Status.UpdateRevision,
+			progress:       statefulSetRolloutProgress(o),
+			inPlaceIfPossible: o.Spec.UpdateStrategy.RollingUpdate != nil &&
+				o.Spec.UpdateStrategy.RollingUpdate.PodUpdatePolicy == v1beta1.InPlaceIfPossiblePodUpdateStrategyType,
+		}, true
+	}
+	return "", transition{}, false
+}
+
+// transitionCounts are the transitions of a workload seen since it was first
+// observed.
+type transitionCounts struct {
+	scaleUp           float64
+	scaleDown         float64
+	rolloutsCompleted float64
+	inPlaceUpdates    float64
+	recreateUpdates   float64
+}
+
+type trackedTransitions struct {
+	transitionCounts
+	last transition
+	// since is when the workload was first observed, which its counters
+	// count from.
+	since time.Time
+	// rolloutStart is when the update revision last changed, zero unless a
+	// rollout is in progress.
+	rolloutStart time.Time
+	// rolloutReplicas is the highest desired replicas seen since the update
+	// revision last changed, or since the workload was first observed.
+	rolloutReplicas int32
+	store           *transitionStore
+}
+
+// transitionTracker counts the scale events and the completed rollouts of
+// the workloads from the updates of the objects written by the reflectors,
+// and observes the duration of the rollouts in histograms per resource. It
+// also counts the updates of the pods of the workloads as a podHandler.
+type transitionTracker struct {
+	clock clock.PassiveClock
+
+	// Protects workloads and pods
+	mutex sync.Mutex
+	// workloads holds the transitions of the workloads, indexed by the
+	// Kubernetes object id.
+	workloads map[types.UID]*trackedTransitions
+	// pods holds the update state of the pods of the workloads, indexed by
+	// the Kubernetes object id.
+	pods             map[types.UID]*podUpdateState
+	durations        map[string]*histogramWriter
+	inPlaceDurations map[string]*histogramWriter
+}
+
+func newTransitionTracker(c clock.PassiveClock) *transitionTracker {
+	t := &transitionTracker{
+		clock:            c,
+		workloads:        map[types.UID]*trackedTransitions{},
+		pods:             map[types.UID]*podUpdateState{},
+		durations:        map[string]*histogramWriter{},
+		inPlaceDurations: map[string]*histogramWriter{},
+	}
+	for resource, kind := range map[string]string{"clonesets": "cloneset", "statefulsets": "statefulset"} {
+		t.durations[resource] = newHistogramWriter(resource, prometheus.HistogramOpts{
+			Name:    "kruise_" + kind + "_rollout_duration_seconds",
+			Help:    "Duration of the completed rollouts from the change of the update revision until all replicas are updated and ready.",
+			Buckets: rolloutDurationBuckets,
+		}, "namespace")
+		t.inPlaceDurations[resource] = newHistogramWriter(resource, prometheus.HistogramOpts{
+			Name:    "kruise_" + kind + "_inplace_update_duration_seconds",
+			Help:    "Duration of the in-place updates of the pods from their update timestamp until their InPlaceUpdateReady condition turned true.",
+			Buckets: inPlaceUpdateDurationBuckets,
+		}, "namespace")
+	}
+	return t
+}
+
+// observe records the transitions of the new version of an object written to
+// the store. The first version of an object only sets the state transitions
+// are counted from.
+func (t *transitionTracker) observe(obj interface{}, s *transitionStore) {
+	resource, current, ok := transitionOf(obj)
+	if !ok {
+		return
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	w, ok := t.workloads[o.GetUID()]
+	if !ok {
+		t.workloads[o.GetUID()] = &trackedTransitions{last: current, since: t.clock.Now(), rolloutReplicas: current.replicas, store: s}
+		return
+	}
+	w.store = s
+
+	now := t.clock.Now()
+	switch {
+	case current.replicas > w.last.replicas:
+		w.scaleUp++
+	case current.replicas < w.last.replicas:
+		w.scaleDown++
+	}
+	// A new update revision starts a new rollout, superseding the one in
+	// progress if any.
+	if current.updateRevision != w.last.updateRevision && w.last.updateRevision != "" {
+		w.rolloutStart = now
+		w.rolloutReplicas = current.replicas
+	}
+	w.rolloutReplicas = max(w.rolloutReplicas, current.replicas)
+	if !w.rolloutStart.IsZero() && current.fullyUpdated() {
+		w.rolloutsCompleted++
+		t.durations[resource].histogram.WithLabelValues(o.GetNamespace()).Observe(now.Sub(w.rolloutStart).Seconds())
+		w.rolloutStart = time.Time{}
+	}
+	w.last = current
+}
+
+// counts returns the transitions of an object.
+func (t *transitionTracker) counts(uid types.UID) transitionCounts {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if w, ok := t.workloads[uid]; ok {
+		return w.transitionCounts
+	}
+	return transitionCounts{}
+}
+
+// since returns when an object was first observed, zero if it is not
+// tracked.
+func (t *transitionTracker) since(obj interface{}) time.Time {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return time.Time{}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if w, ok := t.workloads[o.GetUID()]; ok {
+		return w.since
+	}
+	return time.Time{}
+}
+
+// forget forgets the transitions of an object.
+func (t *transitionTracker) forget(uid types.UID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.workloads, uid)
+}
+
+// retain forgets the transitions of the objects of the store which are not
+// kept.
+func (t *transitionTracker) retain(s *transitionStore, keep map[types.UID]struct{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for uid, w := range t.workloads {
+		if _, ok := keep[uid]; w.store == s && !ok {
+			delete(t.workloads, uid)
+		}
+	}
+}
+
+// transitionStore is the store written by a reflector. It records the
+// transitions of every new version of an object in the tracker before
+// writing it to the store, so that the generated counters include them, and
+// forgets them when the object is deleted.
+type transitionStore struct {
+	cache.Store
+	tracker *transitionTracker
+}
+
+// Add records the transitions of the object and adds it to the store.
+func (s *transitionStore) Add(obj interface{}) error {
+	s.tracker.observe(obj, s)
+	return s.Store.Add(obj)
+}
+
+// Update records the transitions of the object and updates it in the store.
+func (s *transitionStore) Update(obj interface{}) error {
+	s.tracker.observe(obj, s)
+	return s.Store.Update(obj)
+}
+
+// Delete deletes the object from the store and forgets its transitions.
+func (s *transitionStore) Delete(obj interface{}) error {
+	if err := s.Store.Delete(obj); err != nil {
+		return err
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	s.tracker.forget(o.GetUID())
+	return nil
+}
+
+// Replace records the transitions of the listed objects, replaces the
+// contents of the store and forgets the transitions of the objects which are
+// not in the list any more.
+func (s *transitionStore) Replace(list []interface{}, resourceVersion string) error {
+	keep := make(map[types.UID]struct{}, len(list))
+	for _, obj := range list {
+		if o, err := meta.Accessor(obj); err == nil {
+			keep[o.GetUID()] = struct{}{}
+		}
+		s.tracker.observe(obj, s)
+	}
+	if err := s.Store.Replace(list, resourceVersion); err != nil {
+		return err
+	}
+	s.tracker.retain(s, keep)
+	return nil
+}
+
+// transitionMetricFamilies returns the counters of the transitions of a kind
+// of workloads, which count from when the workloads were first observed.
+func transitionMetricFamilies[T metav1.Object](
+	kind string,
+	tracker *transitionTracker,
+	wrap func(func(T) *metric.Family) func(interface{}) *metric.Family,
+) []FamilyGenerator {
+	families := []FamilyGenerator{
+		newFamilyGenerator(
+			"kruise_"+kind+"_scale_events_total",
+			"Number of changes of the desired replicas seen since the object was first observed, by direction.",
+			metric.Counter,
+			"",
+			wrap(func(obj T) *metric.Family {
+				counts := tracker.counts(obj.GetUID())
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   []string{"direction"},
+							LabelValues: []string{scaleUp},
+							Value:       counts.scaleUp,
+						},
+						{
+							LabelKeys:   []string{"direction"},
+							LabelValues: []string{scaleDown},
+							Value:       counts.scaleDown,
+						},
+					},
+				}
+			}),
+		),
+		newFamilyGenerator(
+			"kruise_"+kind+"_rollouts_completed_total",
+			"Number of rollouts seen from the change of the update revision until all replicas were updated and ready.",
+			metric.Counter,
+			"",
+			wrap(func(obj T) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							Value: tracker.counts(obj.GetUID()).rolloutsCompleted,
+						},
+					},
+				}
+			}),
+		),
+	}
+	for i := range families {
+		families[i].Start = tracker.since
+	}
+	return families
+}
+
+// histogramWriter writes a histogram aggregating the objects of a resource,
+// which cannot be generated per object like the families of the stores.
+type histogramWriter struct {
+	collectorWriter
+	histogram *prometheus.HistogramVec
+}
+
+func newHistogramWriter(resource string, opts prometheus.HistogramOpts, labelNames ...string) *histogramWriter {
+	h := &histogramWriter{histogram: prometheus.NewHistogramVec(opts, labelNames)}
+	h.collectorWriter = newCollectorWriter(resource, h.histogram, opts.Name)
+	return h
+}
+
+// collectorWriter writes the families of a Prometheus collector aggregating
+// the objects of a resource.
+type collectorWriter struct {
+	resource string
+	names    []string
+	registry *prometheus.Registry
+	// limiter enforces the series limits on the families when they are
+	// written, if any limit is configured.
+	limiter *seriesLimiter
+	owner   int
+}
+
+func newCollectorWriter(resource string, c prometheus.Collector, names ...string) collectorWriter {
+	w := collectorWriter{
+		resource: resource,
+		names:    names,
+		registry: prometheus.NewRegistry(),
+	}
+	w.registry.MustRegister(c)
+	return w
+}
+
+// allow keeps the families allowed by isIncluded and returns whether any is
+// left.
+func (c *collectorWriter) allow(isIncluded func(string) bool) bool {
+	var names []string
+	for _, name := range c.names {
+		if isIncluded(name) {
+			names = append(names, name)
+		}
+	}
+	c.names = names
+	return len(names) > 0
+}
+
+// limitSeries enforces the limits of the series limiter on the families.
+func (c *collectorWriter) limitSeries(l *seriesLimiter) {
+	c.limiter = l
+	c.owner = l.newOwner()
+}
+
+// WriteAll writes the families in the Prometheus text format.
+func (c *collectorWriter) WriteAll(w io.Writer) {
+	c.WriteFiltered(w, false, Filter{})
+}
+
+// WriteAllOpenMetrics writes the families in the OpenMetrics format.
+func (c *collectorWriter) WriteAllOpenMetrics(w io.Writer) {
+	c.WriteFiltered(w, true, Filter{})
+}
+
+// Resource returns the resource of the objects aggregated by the families.
+func (c *collectorWriter) Resource() string {
+	return c.resource
+}
+
+// FamilyNames returns the names of the families.
+func (c *collectorWriter) FamilyNames() []string {
+	return c.names
+}
+
+// WriteFiltered writes the families selected by the filter, only their series
+// of the namespace of the filter if any. Nothing is written for a family
+// until it has a series.
+func (c *collectorWriter) WriteFiltered(w io.Writer, openMetrics bool, f Filter) {
+	if !f.includesResource(c.resource) {
+		return
+	}
+	gathered, err := c.registry.Gather()
+	if err != nil {
+		return
+	}
+	families := make([]*dto.MetricFamily, 0, len(gathered))
+	for _, family := range gathered {
+		if slices.Contains(c.names, family.GetName()) {
+			families = append(families, family)
+		}
+	}
+	// The limits apply to all series, whatever the filter.
+	if c.limiter != nil {
+		c.limiter.limitCollected(c.owner, families)
+	}
+
+	format := expfmt.FmtText
+	if openMetrics {
+		format = expfmt.FmtOpenMetrics_1_0_0
+	}
+	encoder := expfmt.NewEncoder(w, format)
+	for _, family := range families {
+		if !f.includesFamily(family.GetName()) {
+			continue
+		}
+		if f.Namespace != "" {
+			family.Metric = filterNamespace(family.Metric, f.Namespace)
+		}
+		if len(family.Metric) > 0 {
+			encoder.Encode(family)
+		}
+	}
+}
+
+// filterNamespace returns the metrics whose namespace label is the given
+// namespace.
+func filterNamespace(metrics []*dto.Metric, namespace string) []*dto.Metric {
+	var filtered []*dto.Metric
+	for _, m := range metrics {
+		for _, l := range m.GetLabel() {
+			if l.GetName() == "namespace" && l.GetValue() == namespace {
+				filtered = append(filtered, m)
+				break
+			}
+		}
+	}
+	return filtered
+}
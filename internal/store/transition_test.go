@@ -0,0 +1,222 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+)
+
+// transitionCloneSet returns a cloneset of the given replicas whose updated
+// and ready replicas are updatedReady.
+func transitionCloneSet(name string, replicas, updatedReady int32, updateRevision string) *v1alpha1.CloneSet {
+	cs := rolloutCloneSet(name, updatedReady)
+	cs.Spec.Replicas = ptr.To(replicas)
+	cs.Spec.UpdateStrategy.Type = v1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType
+	cs.Status.UpdatedReplicas = updatedReady
+	cs.Status.UpdateRevision = updateRevision
+	return cs
+}
+
+// transitionSamples returns the transition samples of the store for the
+// given cloneset.
+func transitionSamples(s *MetricsStore, name string) []string {
+	var samples []string
+	for _, line := range strings.Split(writeStore(s), "\n") {
+		if (strings.HasPrefix(line, "kruise_cloneset_scale_events_total{") || strings.HasPrefix(line, "kruise_cloneset_rollouts_completed_total{")) &&
+			strings.Contains(line, `cloneset="`+name+`"`) {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
+
+func TestTransitionStore(t *testing.T) {
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+	tracker := newTransitionTracker(clock)
+	families := cloneSetTransitionMetricFamilies(tracker)
+	store := NewMetricsStore(families, composeMetricGenFuncs(families))
+	transitions := &transitionStore{Store: store, tracker: tracker}
+
+	for _, cs := range []*v1alpha1.CloneSet{
+		transitionCloneSet("cs1", 4, 4, "r1"),
+		transitionCloneSet("cs2", 2, 2, "r1"),
+		// Scaled up, then down twice.
+		transitionCloneSet("cs1", 6, 4, "r1"),
+		transitionCloneSet("cs1", 5, 4, "r1"),
+		transitionCloneSet("cs1", 3, 3, "r1"),
+	} {
+		if err := transitions.Update(cs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A rollout of cs1 completing after 2 minutes, with an unrelated update
+	// of cs2 in between.
+	clock.SetTime(clock.Now().Add(time.Minute))
+	if err := transitions.Update(transitionCloneSet("cs1", 3, 0, "r2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := transitions.Update(transitionCloneSet("cs2", 3, 2, "r1")); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(time.Minute))
+	if err := transitions.Update(transitionCloneSet("cs1", 3, 2, "r2")); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(time.Minute))
+	if err := transitions.Update(transitionCloneSet("cs1", 3, 3, "r2")); err != nil {
+		t.Fatal(err)
+	}
+	// Complete rollouts are only counted once.
+	if err := transitions.Update(transitionCloneSet("cs1", 3, 3, "r2")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`kruise_cloneset_scale_events_total{namespace="ns1",cloneset="cs1",direction="up"} 1`,
+		`kruise_cloneset_scale_events_total{namespace="ns1",cloneset="cs1",direction="down"} 2`,
+		`kruise_cloneset_rollouts_completed_total{namespace="ns1",cloneset="cs1"} 1`,
+	}
+	if diff := cmp.Diff(want, transitionSamples(store, "cs1")); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+	want = []string{
+		`kruise_cloneset_scale_events_total{namespace="ns1",cloneset="cs2",direction="up"} 1`,
+		`kruise_cloneset_scale_events_total{namespace="ns1",cloneset="cs2",direction="down"} 0`,
+		`kruise_cloneset_rollouts_completed_total{namespace="ns1",cloneset="cs2"} 0`,
+	}
+	if diff := cmp.Diff(want, transitionSamples(store, "cs2")); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	histogram := &bytes.Buffer{}
+	tracker.durations["clonesets"].WriteAll(histogram)
+	for _, sample := range []string{
+		`kruise_cloneset_rollout_duration_seconds_bucket{namespace="ns1",le="60"} 0`,
+		`kruise_cloneset_rollout_duration_seconds_bucket{namespace="ns1",le="120"} 1`,
+		`kruise_cloneset_rollout_duration_seconds_sum{namespace="ns1"} 120`,
+		`kruise_cloneset_rollout_duration_seconds_count{namespace="ns1"} 1`,
+	} {
+		if !strings.Contains(histogram.String(), sample+"\n") {
+			t.Errorf("expected sample %s in:\n%s", sample, histogram)
+		}
+	}
+
+	// The counters of deleted objects and of objects left out of a relist
+	// are dropped, those of a recreated object start again.
+	if err := transitions.Delete(transitionCloneSet("cs2", 3, 2, "r1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := transitionSamples(store, "cs2"); len(got) != 0 {
+		t.Errorf("expected no samples of the deleted object, got %v", got)
+	}
+	if err := transitions.Replace([]interface{}{transitionCloneSet("cs2", 5, 5, "r1")}, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := tracker.counts("cs1"); got != (transitionCounts{}) {
+		t.Errorf("expected the counts of cs1 to be forgotten, got %+v", got)
+	}
+	want = []string{
+		`kruise_cloneset_scale_events_total{namespace="ns1",cloneset="cs2",direction="up"} 0`,
+		`kruise_cloneset_scale_events_total{namespace="ns1",cloneset="cs2",direction="down"} 0`,
+		`kruise_cloneset_rollouts_completed_total{namespace="ns1",cloneset="cs2"} 0`,
+	}
+	if diff := cmp.Diff(want, transitionSamples(store, "cs2")); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+}
+
+func TestTransitionCountersCreated(t *testing.T) {
+	seen := time.Unix(1700000000, 0)
+	clock := clocktesting.NewFakePassiveClock(seen)
+	tracker := newTransitionTracker(clock)
+	families := cloneSetTransitionMetricFamilies(tracker)
+	store := NewMetricsStore(families, composeMetricGenFuncs(families))
+	transitions := &transitionStore{Store: store, tracker: tracker}
+
+	// The counters start when the object is first observed, not when it was
+	// created, as they are reset when kruise-state-metrics restarts.
+	cs := transitionCloneSet("cs1", 4, 4, "r1")
+	cs.CreationTimestamp = goldenCreated
+	if err := transitions.Add(cs); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(seen.Add(time.Hour))
+	if err := transitions.Update(transitionCloneSet("cs1", 5, 4, "r1")); err != nil {
+		t.Fatal(err)
+	}
+
+	openMetrics := &bytes.Buffer{}
+	store.WriteAllOpenMetrics(openMetrics)
+	for _, sample := range []string{
+		`kruise_cloneset_scale_events_created{namespace="ns1",cloneset="cs1",direction="up"} 1.7e+09`,
+		`kruise_cloneset_rollouts_completed_created{namespace="ns1",cloneset="cs1"} 1.7e+09`,
+	} {
+		if !strings.Contains(openMetrics.String(), sample+"\n") {
+			t.Errorf("expected sample %s in:\n%s", sample, openMetrics)
+		}
+	}
+}
+
+func TestHistogramWriter(t *testing.T) {
+	tracker := newTransitionTracker(clocktesting.NewFakePassiveClock(time.Now()))
+	h := tracker.durations["statefulsets"]
+
+	buf := &bytes.Buffer{}
+	h.WriteAll(buf)
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written before any observation, got:\n%s", buf)
+	}
+
+	h.histogram.WithLabelValues("ns1").Observe(10)
+	h.histogram.WithLabelValues("ns2").Observe(20)
+	for _, test := range []struct {
+		name       string
+		filter     Filter
+		namespaces []string
+	}{
+		{name: "all", namespaces: []string{"ns1", "ns2"}},
+		{name: "namespace", filter: Filter{Namespace: "ns2"}, namespaces: []string{"ns2"}},
+		{name: "resource", filter: Filter{Resources: map[string]struct{}{"clonesets": {}}}},
+		{name: "family", filter: Filter{Family: regexp.MustCompile("^kruise_statefulset_labels$")}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			h.WriteFiltered(buf, true, test.filter)
+			var namespaces []string
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if strings.HasPrefix(line, "kruise_statefulset_rollout_duration_seconds_count{") {
+					namespaces = append(namespaces, strings.Split(line, `"`)[1])
+				}
+			}
+			if diff := cmp.Diff(test.namespaces, namespaces); diff != "" {
+				t.Errorf("unexpected namespaces (-want, +got):\n%s", diff)
+			}
+			if len(test.namespaces) > 0 && !strings.HasPrefix(buf.String(), "# HELP kruise_statefulset_rollout_duration_seconds ") {
+				t.Errorf("expected the HELP line first, got:\n%s", buf)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// uidHandler keeps what it needs of the objects written to a uidStore.
+type uidHandler interface {
+	// update is called for every new version of an object written to the
+	// store. It returns whether the object is kept, the objects which are
+	// not are neither tracked nor passed to delete.
+	update(obj interface{}) (bool, error)
+	// delete is called when a kept object is deleted.
+	delete(uid types.UID) error
+}
+
+// uidStore is the store written by a reflector which passes the objects to a
+// handler instead of keeping them. It tracks the UIDs of the kept objects, so
+// that those deleted while the reflector was not watching are found on
+// Replace.
+type uidStore struct {
+	discardingStore
+	handler uidHandler
+
+	// Protects uids and closed
+	mutex sync.Mutex
+	uids  sets.Set[types.UID]
+	// closed is set once the objects are forgotten for good, the store
+	// ignores the writes from then on.
+	closed bool
+}
+
+func newUIDStore(handler uidHandler) *uidStore {
+	return &uidStore{
+		handler: handler,
+		uids:    sets.New[types.UID](),
+	}
+}
+
+// Add passes the object to the handler.
+func (s *uidStore) Add(obj interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.add(obj)
+}
+
+// Update passes the object to the handler.
+func (s *uidStore) Update(obj interface{}) error {
+	return s.Add(obj)
+}
+
+// Delete notifies the handler of the deletion of the object.
+func (s *uidStore) Delete(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.delete(o.GetUID())
+}
+
+// Replace notifies the handler of the deletion of the objects which are not in
+// the list any more and passes it the listed objects.
+func (s *uidStore) Replace(list []interface{}, _ string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stale := s.uids.Clone()
+	for _, obj := range list {
+		if o, err := meta.Accessor(obj); err == nil {
+			stale.Delete(o.GetUID())
+		}
+	}
+	for uid := range stale {
+		if err := s.delete(uid); err != nil {
+			return err
+		}
+	}
+	for _, obj := range list {
+		if err := s.add(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close notifies the handler of the deletion of all the objects and ignores
+// the writes from then on.
+func (s *uidStore) close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var firstErr error
+	for uid := range s.uids {
+		if err := s.delete(uid); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.closed = true
+	return firstErr
+}
+
+func (s *uidStore) add(obj interface{}) error {
+	if s.closed {
+		return nil
+	}
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	keep, err := s.handler.update(obj)
+	if err != nil {
+		return err
+	}
+	if !keep {
+		// The object may have been kept in a previous version.
+		return s.delete(o.GetUID())
+	}
+	s.uids.Insert(o.GetUID())
+	return nil
+}
+
+func (s *uidStore) delete(uid types.UID) error {
+	if s.closed || !s.uids.Has(uid) {
+		return nil
+	}
+	s.uids.Delete(uid)
+	return s.handler.delete(uid)
+}
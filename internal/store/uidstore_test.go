@@ -0,0 +1,84 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// testUIDHandler keeps the objects which are not labeled as ignored.
+type testUIDHandler struct {
+	kept sets.Set[types.UID]
+}
+
+func (h *testUIDHandler) update(obj interface{}) (bool, error) {
+	o := obj.(metav1.Object)
+	if o.GetLabels()["ignored"] == "true" {
+		return false, nil
+	}
+	h.kept.Insert(o.GetUID())
+	return true, nil
+}
+
+func (h *testUIDHandler) delete(uid types.UID) error {
+	h.kept.Delete(uid)
+	return nil
+}
+
+func testUIDObject(uid string, ignored bool) *metav1.ObjectMeta {
+	o := &metav1.ObjectMeta{UID: types.UID(uid)}
+	if ignored {
+		o.Labels = map[string]string{"ignored": "true"}
+	}
+	return o
+}
+
+func TestUIDStore(t *testing.T) {
+	h := &testUIDHandler{kept: sets.New[types.UID]()}
+	s := newUIDStore(h)
+
+	if err := s.Replace([]interface{}{testUIDObject("a", false), testUIDObject("b", false), testUIDObject("c", true)}, "1"); err != nil {
+		t.Fatal(err)
+	}
+	// The objects missing from the list and those not kept any more are
+	// deleted.
+	if err := s.Replace([]interface{}{testUIDObject("b", true), testUIDObject("d", false)}, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]types.UID{"d"}, sets.List(h.kept)); diff != "" {
+		t.Errorf("unexpected kept objects (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]types.UID{"d"}, sets.List(s.uids)); diff != "" {
+		t.Errorf("unexpected tracked objects (-want, +got):\n%s", diff)
+	}
+
+	// Closing deletes all objects and ignores the later writes.
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(testUIDObject("e", false)); err != nil {
+		t.Fatal(err)
+	}
+	if h.kept.Len() != 0 || s.uids.Len() != 0 {
+		t.Errorf("expected no objects after close, got %v", sets.List(h.kept))
+	}
+}
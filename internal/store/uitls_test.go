@@ -17,10 +17,17 @@ limitations under the License.
 package store
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-state-metrics/v2/pkg/allowdenylist"
+	"k8s.io/kube-state-metrics/v2/pkg/options"
 )
 
 func TestGetIntSet(t *testing.T) {
@@ -145,3 +152,81 @@ func TestGetIntSet(t *testing.T) {
 		})
 	}
 }
+
+func TestConditionReasonMetrics(t *testing.T) {
+	conditions := []statusCondition{
+		{conditionType: "FailedScale", status: v1.ConditionTrue, reason: "CreateFailed"},
+		{conditionType: "FailedUpdate", status: v1.ConditionTrue, reason: "admission webhook denied the request"},
+		{conditionType: "Ready", status: v1.ConditionTrue},
+	}
+	var got [][]string
+	for _, m := range addConditionReasonMetrics(conditions, sets.New("CreateFailed")) {
+		got = append(got, m.LabelValues)
+	}
+	want := [][]string{
+		{"FailedScale", "CreateFailed"},
+		{"FailedUpdate", conditionReasonOther},
+		{"Ready", ""},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected label values (-want, +got):\n%s", diff)
+	}
+}
+
+// conditionReasonOutput returns the metrics of a cloneset whose scale failed,
+// with the given condition reasons.
+func conditionReasonOutput(t *testing.T, reasons []string) string {
+	t.Helper()
+
+	cs := testCloneSet("ns1", "cs1", 1, 0, 0)
+	cs.Status.Conditions = []v1alpha1.CloneSetCondition{
+		{Type: v1alpha1.CloneSetConditionFailedScale, Status: v1.ConditionTrue, Reason: "CreateFailed"},
+	}
+	allowDenyList, err := allowdenylist.New(options.MetricSet{}, options.MetricSet{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuilder()
+	if err := b.WithEnabledResources([]string{"clonesets"}); err != nil {
+		t.Fatal(err)
+	}
+	b.WithNamespaces(options.DefaultNamespaces)
+	b.WithAllowDenyList(allowDenyList)
+	b.WithConditionReasons(reasons)
+	b.WithKruiseStoresFunc(b.StaticKruiseStoresFunc([]interface{}{cs}, func(_ interface{}, err error) {
+		t.Fatal(err)
+	}), false)
+
+	buf := &bytes.Buffer{}
+	for _, w := range b.Build() {
+		w.WriteAll(buf)
+	}
+	return buf.String()
+}
+
+func TestConditionReasonFamilies(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		reasons []string
+		want    string
+	}{
+		{name: "allowed", reasons: []string{"CreateFailed"}, want: "CreateFailed"},
+		{name: "other", reasons: []string{"DeleteFailed"}, want: conditionReasonOther},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			want := `kruise_cloneset_status_condition_reason{namespace="ns1",cloneset="cs1",condition="FailedScale",reason="` + test.want + `"} 1`
+			if out := conditionReasonOutput(t, test.reasons); !strings.Contains(out, want) {
+				t.Errorf("expected %s in:\n%s", want, out)
+			}
+		})
+	}
+
+	// The family is not generated without reasons.
+	out := conditionReasonOutput(t, nil)
+	if !strings.Contains(out, `kruise_cloneset_status_condition{namespace="ns1",cloneset="cs1",condition="FailedScale",status="true"} 1`) {
+		t.Errorf("expected the condition of the cloneset in:\n%s", out)
+	}
+	if strings.Contains(out, "kruise_cloneset_status_condition_reason") {
+		t.Errorf("unexpected condition reason family:\n%s", out)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
@@ -39,7 +40,8 @@ var (
 	conditionStatuses  = []v1.ConditionStatus{v1.ConditionTrue, v1.ConditionFalse, v1.ConditionUnknown}
 )
 
-func boolFloat64(b bool) float64 {
+// BoolFloat64 returns 1 for true and 0 for false.
+func BoolFloat64(b bool) float64 {
 	if b {
 		return 1
 	}
@@ -55,13 +57,108 @@ func addConditionMetrics(cs v1.ConditionStatus) []*metric.Metric {
 	for i, status := range conditionStatuses {
 		ms[i] = &metric.Metric{
 			LabelValues: []string{strings.ToLower(string(status))},
-			Value:       boolFloat64(cs == status),
+			Value:       BoolFloat64(cs == status),
 		}
 	}
 
 	return ms
 }
 
+// conditionReasonOther is the reason of the conditions whose reason is not
+// allowed.
+const conditionReasonOther = "Other"
+
+// statusCondition holds the fields shared by the status conditions of the
+// Kruise objects.
+type statusCondition struct {
+	conditionType      string
+	status             v1.ConditionStatus
+	reason             string
+	lastTransitionTime metav1.Time
+}
+
+// addStatusConditionMetrics generates the metrics of every possible status of
+// the conditions, labelled by condition and status.
+func addStatusConditionMetrics(conditions []statusCondition) []*metric.Metric {
+	ms := make([]*metric.Metric, 0, len(conditions)*len(conditionStatuses))
+
+	for _, c := range conditions {
+		for _, m := range addConditionMetrics(c.status) {
+			m.LabelKeys = []string{"condition", "status"}
+			m.LabelValues = append([]string{c.conditionType}, m.LabelValues...)
+			ms = append(ms, m)
+		}
+	}
+
+	return ms
+}
+
+// addConditionLastTransitionTimeMetrics generates the Unix timestamp of the
+// last transition of every condition, labelled by condition. The conditions
+// which never transitioned are skipped.
+func addConditionLastTransitionTimeMetrics(conditions []statusCondition) []*metric.Metric {
+	ms := make([]*metric.Metric, 0, len(conditions))
+
+	for _, c := range conditions {
+		if c.lastTransitionTime.IsZero() {
+			continue
+		}
+		ms = append(ms, &metric.Metric{
+			LabelKeys:   []string{"condition"},
+			LabelValues: []string{c.conditionType},
+			Value:       float64(c.lastTransitionTime.Unix()),
+		})
+	}
+
+	return ms
+}
+
+// addConditionReasonMetrics generates one metric of every condition, labelled
+// by condition and reason. The reasons which are not allowed are reported as
+// Other, so that the cardinality doesn't depend on the messages of the
+// controllers.
+func addConditionReasonMetrics(conditions []statusCondition, reasons sets.Set[string]) []*metric.Metric {
+	ms := make([]*metric.Metric, len(conditions))
+
+	for i, c := range conditions {
+		reason := c.reason
+		if reason != "" && !reasons.Has(reason) {
+			reason = conditionReasonOther
+		}
+		ms[i] = &metric.Metric{
+			LabelKeys:   []string{"condition", "reason"},
+			LabelValues: []string{c.conditionType, reason},
+			Value:       1,
+		}
+	}
+
+	return ms
+}
+
+// conditionReasonMetricFamilies returns the family of the reasons of the
+// status conditions of a kind, reported as Other unless they are in reasons.
+func conditionReasonMetricFamilies[T metav1.Object](
+	kind string,
+	reasons []string,
+	conditions func(T) []statusCondition,
+	wrap func(func(T) *metric.Family) func(interface{}) *metric.Family,
+) []FamilyGenerator {
+	allowed := sets.New(reasons...)
+	return []FamilyGenerator{
+		newFamilyGenerator(
+			"kruise_"+kind+"_status_condition_reason",
+			"The reason of the current status conditions of a "+kind+". The reasons which are not allowed are reported as Other.",
+			metric.Gauge,
+			"",
+			wrap(func(obj T) *metric.Family {
+				return &metric.Family{
+					Metrics: addConditionReasonMetrics(conditions(obj), allowed),
+				}
+			}),
+		),
+	}
+}
+
 func kubeMapToPrometheusLabels(prefix string, input map[string]string) ([]string, []string) {
 	return mapToPrometheusLabels(input, prefix)
 }
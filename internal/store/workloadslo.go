@@ -0,0 +1,295 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	klog "k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// workloadSLOsResource is the resource of the availability SLOs of the
+	// workloads. It has no objects of its own, the SLOs are tracked from the
+	// objects of the workload resources.
+	workloadSLOsResource = "workloadslos"
+
+	// availabilityTargetAnnotation opts a workload in the availability SLOs
+	// with the target ratio of its ready to desired replicas, e.g. 0.999.
+	availabilityTargetAnnotation = "metrics.kruise.io/availability-target"
+	// availabilityWindowsAnnotation is the comma-separated list of the
+	// windows of the availability SLOs of a workload, e.g. 1h,1d,30d.
+	availabilityWindowsAnnotation = "metrics.kruise.io/availability-windows"
+	// defaultAvailabilityWindow is the window of the workloads without
+	// availabilityWindowsAnnotation.
+	defaultAvailabilityWindow = "30d"
+
+	// maxAvailabilitySamples bounds the memory used by the history of a
+	// workload whose availability keeps changing. The oldest samples are
+	// merged beyond it.
+	maxAvailabilitySamples = 1024
+)
+
+var descWorkloadSLOLabels = []string{"kind", "namespace", "name", "window"}
+
+// availabilitySample is the availability of a workload from since until the
+// next sample.
+type availabilitySample struct {
+	since time.Time
+	ratio float64
+}
+
+// sloWindow is a window of the availability SLO of a workload.
+type sloWindow struct {
+	label    string
+	duration time.Duration
+}
+
+// sloWorkload is the history of the availability of a workload opted in the
+// SLOs.
+type sloWorkload struct {
+	kind, namespace, name string
+	target                float64
+	windows               []sloWindow
+	// samples holds the changes of the availability, oldest first.
+	samples []availabilitySample
+}
+
+// parseSLO returns the target and the windows of the availability SLO of a
+// workload from its annotations, false if it is not opted in.
+func parseSLO(annotations map[string]string) (float64, []sloWindow, bool, error) {
+	v, ok := annotations[availabilityTargetAnnotation]
+	if !ok {
+		return 0, nil, false, nil
+	}
+	target, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return 0, nil, true, errors.Wrapf(err, "invalid %s", availabilityTargetAnnotation)
+	}
+	if target <= 0 || target >= 1 {
+		return 0, nil, true, errors.Errorf("invalid %s %q, must be between 0 and 1 excluded", availabilityTargetAnnotation, v)
+	}
+
+	v, ok = annotations[availabilityWindowsAnnotation]
+	if !ok {
+		v = defaultAvailabilityWindow
+	}
+	var windows []sloWindow
+	for _, s := range strings.Split(v, ",") {
+		d, err := model.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return 0, nil, true, errors.Wrapf(err, "invalid %s", availabilityWindowsAnnotation)
+		}
+		if d <= 0 {
+			return 0, nil, true, errors.Errorf("invalid %s %q, windows must be positive", availabilityWindowsAnnotation, v)
+		}
+		if !slices.ContainsFunc(windows, func(w sloWindow) bool { return w.label == d.String() }) {
+			windows = append(windows, sloWindow{label: d.String(), duration: time.Duration(d)})
+		}
+	}
+	return target, windows, true, nil
+}
+
+// availabilityRatio returns the ratio of the ready to desired replicas of a
+// workload, 1 if no replicas are desired.
+func availabilityRatio(desired, ready int32) float64 {
+	if desired <= 0 || ready >= desired {
+		return 1
+	}
+	return float64(max(ready, 0)) / float64(desired)
+}
+
+// observe records the availability of the workload at now, and forgets the
+// samples older than its longest window.
+func (w *sloWorkload) observe(now time.Time, ratio float64) {
+	if n := len(w.samples); n == 0 || w.samples[n-1].ratio != ratio {
+		w.samples = append(w.samples, availabilitySample{since: now, ratio: ratio})
+	}
+
+	var longest time.Duration
+	for _, window := range w.windows {
+		longest = max(longest, window.duration)
+	}
+	// The first sample kept covers the start of the longest window.
+	start := now.Add(-longest)
+	drop := 0
+	for drop+1 < len(w.samples) && !w.samples[drop+1].since.After(start) {
+		drop++
+	}
+	w.samples = w.samples[drop:]
+
+	for len(w.samples) > maxAvailabilitySamples {
+		first, second := w.samples[0], w.samples[1]
+		d1 := second.since.Sub(first.since).Seconds()
+		d2 := w.samples[2].since.Sub(second.since).Seconds()
+		merged := availabilitySample{since: first.since, ratio: second.ratio}
+		if d1+d2 > 0 {
+			merged.ratio = (first.ratio*d1 + second.ratio*d2) / (d1 + d2)
+		}
+		w.samples = append([]availabilitySample{merged}, w.samples[2:]...)
+	}
+}
+
+// availability returns the time-weighted availability of the workload over
+// the window ending at now. Until the history covers the window, it is the
+// availability since the workload was first seen.
+func (w *sloWorkload) availability(now time.Time, window time.Duration) float64 {
+	if len(w.samples) == 0 {
+		return 1
+	}
+	start := now.Add(-window)
+	if first := w.samples[0].since; start.Before(first) {
+		start = first
+	}
+	total := now.Sub(start).Seconds()
+	if total <= 0 {
+		return w.samples[len(w.samples)-1].ratio
+	}
+
+	var weighted float64
+	for i, s := range w.samples {
+		end := now
+		if i+1 < len(w.samples) {
+			end = w.samples[i+1].since
+		}
+		since := s.since
+		if since.Before(start) {
+			since = start
+		}
+		if end.After(since) {
+			weighted += s.ratio * end.Sub(since).Seconds()
+		}
+	}
+	return weighted / total
+}
+
+// workloadSLOs keeps the history of the availability of the workloads opted
+// in the SLOs by annotation, and computes their availability and remaining
+// error budget over their windows when it is collected. It is the
+// objectObserver of the workloads.
+type workloadSLOs struct {
+	collectorWriter
+	clock clock.PassiveClock
+
+	ratioDesc  *prometheus.Desc
+	budgetDesc *prometheus.Desc
+
+	// Protects workloads
+	mutex     sync.Mutex
+	workloads map[types.UID]*sloWorkload
+}
+
+func newWorkloadSLOs(c clock.PassiveClock) *workloadSLOs {
+	s := &workloadSLOs{
+		clock: c,
+		ratioDesc: prometheus.NewDesc(
+			"kruise_workload_availability_ratio",
+			"Time-weighted ratio of the ready to desired replicas of a workload over a window of its availability SLO.",
+			descWorkloadSLOLabels, nil,
+		),
+		budgetDesc: prometheus.NewDesc(
+			"kruise_workload_error_budget_remaining",
+			"Ratio of the error budget of the availability SLO of a workload left over a window, negative when the budget is overspent.",
+			descWorkloadSLOLabels, nil,
+		),
+		workloads: map[types.UID]*sloWorkload{},
+	}
+	s.collectorWriter = newCollectorWriter(workloadSLOsResource, s,
+		"kruise_workload_availability_ratio",
+		"kruise_workload_error_budget_remaining",
+	)
+	return s
+}
+
+// updateObject implements objectObserver. The history of the workloads
+// opted out, or whose SLO is invalid, is forgotten.
+func (s *workloadSLOs) updateObject(obj metav1.Object) {
+	kind, desired, ready, ok := workloadReplicas(obj)
+	if !ok {
+		return
+	}
+	target, windows, ok, err := parseSLO(obj.GetAnnotations())
+	if err != nil {
+		klog.V(2).Infof("Ignoring the availability SLO of %s %s/%s: %v", kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	now := s.clock.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !ok || err != nil {
+		delete(s.workloads, obj.GetUID())
+		return
+	}
+	w, ok := s.workloads[obj.GetUID()]
+	if !ok {
+		w = &sloWorkload{kind: kind, namespace: obj.GetNamespace(), name: obj.GetName()}
+		s.workloads[obj.GetUID()] = w
+	}
+	w.target = target
+	w.windows = windows
+	w.observe(now, availabilityRatio(desired, ready))
+}
+
+// deleteObject implements objectObserver.
+func (s *workloadSLOs) deleteObject(uid types.UID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.workloads, uid)
+}
+
+// Describe implements prometheus.Collector.
+func (s *workloadSLOs) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.ratioDesc
+	ch <- s.budgetDesc
+}
+
+// Collect implements prometheus.Collector. The remaining error budget is the
+// share of the allowed unavailability, 1 minus the target, which was not
+// consumed over the window.
+func (s *workloadSLOs) Collect(ch chan<- prometheus.Metric) {
+	now := s.clock.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, w := range s.workloads {
+		for _, window := range w.windows {
+			ratio := w.availability(now, window.duration)
+			remaining := 1 - (1-ratio)/(1-w.target)
+			ch <- prometheus.MustNewConstMetric(s.ratioDesc, prometheus.GaugeValue, ratio, w.kind, w.namespace, w.name, window.label)
+			ch <- prometheus.MustNewConstMetric(s.budgetDesc, prometheus.GaugeValue, remaining, w.kind, w.namespace, w.name, window.label)
+		}
+	}
+}
+
+// buildWorkloadSLOStores returns no stores, the SLOs are written by their
+// own writer.
+func (b *Builder) buildWorkloadSLOStores() []*MetricsStore {
+	return nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// sloTestCloneSet returns a cloneset of 4 replicas with the given ready
+// replicas, opted in the SLOs with the given annotations.
+func sloTestCloneSet(ready int32, annotations map[string]string) *v1alpha1.CloneSet {
+	cs := testCloneSet("ns1", "cs1", 4, ready, ready)
+	cs.Annotations = annotations
+	return cs
+}
+
+func TestParseSLO(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		annotations map[string]string
+		target      float64
+		windows     []string
+		optedIn     bool
+		wantErr     bool
+	}{
+		{name: "not opted in"},
+		{
+			name:        "default window",
+			annotations: map[string]string{availabilityTargetAnnotation: "0.99"},
+			target:      0.99, windows: []string{"30d"}, optedIn: true,
+		},
+		{
+			name:        "windows",
+			annotations: map[string]string{availabilityTargetAnnotation: "0.999", availabilityWindowsAnnotation: "1h, 1d,24h,4w"},
+			target:      0.999, windows: []string{"1h", "1d", "4w"}, optedIn: true,
+		},
+		{name: "invalid target", annotations: map[string]string{availabilityTargetAnnotation: "99.9%"}, optedIn: true, wantErr: true},
+		{name: "target out of range", annotations: map[string]string{availabilityTargetAnnotation: "1"}, optedIn: true, wantErr: true},
+		{
+			name:        "invalid window",
+			annotations: map[string]string{availabilityTargetAnnotation: "0.9", availabilityWindowsAnnotation: "1h,month"},
+			optedIn:     true, wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			target, windows, optedIn, err := parseSLO(test.annotations)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %t, got %v", test.wantErr, err)
+			}
+			if optedIn != test.optedIn || target != test.target {
+				t.Errorf("expected opted in %t with target %v, got %t with %v", test.optedIn, test.target, optedIn, target)
+			}
+			var labels []string
+			for _, w := range windows {
+				labels = append(labels, w.label)
+			}
+			if diff := cmp.Diff(test.windows, labels); diff != "" {
+				t.Errorf("unexpected windows (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWorkloadSLOs(t *testing.T) {
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+	slos := newWorkloadSLOs(clock)
+	store := newObservedStore[metav1.Object](cache.NewStore(cache.MetaNamespaceKeyFunc), slos)
+	annotations := map[string]string{availabilityTargetAnnotation: "0.5", availabilityWindowsAnnotation: "1h,4h"}
+
+	// Fully available for 1h, then half available for 30m.
+	if err := store.Add(sloTestCloneSet(4, annotations)); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(time.Hour))
+	if err := store.Update(sloTestCloneSet(2, annotations)); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(30 * time.Minute))
+
+	// Over 1h, 30m at 1 and 30m at 0.5. Over 4h, the history only covers
+	// 1h30m.
+	want := []string{
+		`kruise_workload_availability_ratio{kind="CloneSet",name="cs1",namespace="ns1",window="1h"} 0.75`,
+		`kruise_workload_availability_ratio{kind="CloneSet",name="cs1",namespace="ns1",window="4h"} 0.8333333333333334`,
+		`kruise_workload_error_budget_remaining{kind="CloneSet",name="cs1",namespace="ns1",window="1h"} 0.5`,
+		`kruise_workload_error_budget_remaining{kind="CloneSet",name="cs1",namespace="ns1",window="4h"} 0.6666666666666667`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(slos)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// Updates which don't change the availability don't start a sample,
+	// and the samples older than the longest window are forgotten.
+	if err := store.Update(sloTestCloneSet(4, annotations)); err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(4 * time.Hour))
+	if err := store.Update(sloTestCloneSet(4, annotations)); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(slos.workloads["ns1/cs1"].samples); got != 1 {
+		t.Errorf("expected 1 sample, got %d", got)
+	}
+	want = []string{
+		`kruise_workload_availability_ratio{kind="CloneSet",name="cs1",namespace="ns1",window="1h"} 1`,
+		`kruise_workload_availability_ratio{kind="CloneSet",name="cs1",namespace="ns1",window="4h"} 1`,
+		`kruise_workload_error_budget_remaining{kind="CloneSet",name="cs1",namespace="ns1",window="1h"} 1`,
+		`kruise_workload_error_budget_remaining{kind="CloneSet",name="cs1",namespace="ns1",window="4h"} 1`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(slos)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// The workloads opted out are forgotten.
+	if err := store.Update(sloTestCloneSet(4, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got := collectorSamples(slos); len(got) != 0 {
+		t.Errorf("expected no samples of the workload opted out, got %v", got)
+	}
+}
+
+func TestAvailabilitySamplesBounded(t *testing.T) {
+	start := time.Now()
+	w := &sloWorkload{windows: []sloWindow{{label: "30d", duration: 30 * 24 * time.Hour}}}
+	for i := 0; i < 2*maxAvailabilitySamples; i++ {
+		w.observe(start.Add(time.Duration(i)*time.Minute), float64(i%2))
+	}
+	if len(w.samples) != maxAvailabilitySamples {
+		t.Errorf("expected %d samples, got %d", maxAvailabilitySamples, len(w.samples))
+	}
+	// Alternating minutes at 0 and 1 average to 0.5 whatever the merges.
+	if got := w.availability(start.Add(time.Duration(2*maxAvailabilitySamples)*time.Minute), 30*24*time.Hour); got != 0.5 {
+		t.Errorf("expected an availability of 0.5, got %v", got)
+	}
+}
+
+func TestWorkloadSLOMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kruiseClient := kruisefake.NewSimpleClientset(sloTestCloneSet(2, map[string]string{availabilityTargetAnnotation: "0.5", availabilityWindowsAnnotation: "1h"}))
+	b := newTestBuilder(t, ctx, fake.NewSimpleClientset(), kruiseClient, "clonesets", workloadSLOsResource)
+	b.clock = clocktesting.NewFakePassiveClock(time.Now())
+	writers := b.Build()
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_workload_availability_ratio{kind="CloneSet",name="cs1",namespace="ns1",window="1h"} 0.5`) &&
+			strings.Contains(out, `kruise_workload_error_budget_remaining{kind="CloneSet",name="cs1",namespace="ns1",window="1h"} 0`)
+	})
+}
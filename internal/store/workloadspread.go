@@ -28,7 +28,6 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/kube-state-metrics/v2/pkg/metric"
-	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
 )
 
 var (
@@ -39,9 +38,9 @@ var (
 	descWorkloadSpreadLabelsDefaultLabels = []string{"namespace", "workloadspread"}
 )
 
-func workloadSpreadMetricFamilies(allowAnnotationsList, allowLabelsList []string) []generator.FamilyGenerator {
-	return []generator.FamilyGenerator{
-		*generator.NewFamilyGenerator(
+func workloadSpreadMetricFamilies(allowAnnotationsList, allowLabelsList []string) []FamilyGenerator {
+	return []FamilyGenerator{
+		newFamilyGenerator(
 			"kruise_workloadspread_created",
 			"Unix creation timestamp",
 			metric.Gauge,
@@ -60,7 +59,7 @@ func workloadSpreadMetricFamilies(allowAnnotationsList, allowLabelsList []string
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_workloadspread_status_subset_replicas",
 			"The most recently observed number of replicas for subset.",
 			metric.Gauge,
@@ -78,7 +77,7 @@ func workloadSpreadMetricFamilies(allowAnnotationsList, allowLabelsList []string
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_workloadspread_status_subset_replicas_missing",
 			"The number of replicas belong to this subset not be found.",
 			metric.Gauge,
@@ -96,7 +95,7 @@ func workloadSpreadMetricFamilies(allowAnnotationsList, allowLabelsList []string
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_workloadspread_metadata_generation",
 			"Sequence number representing a specific generation of the desired state for the workloadspread.",
 			metric.Gauge,
@@ -111,7 +110,7 @@ func workloadSpreadMetricFamilies(allowAnnotationsList, allowLabelsList []string
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descWorkloadSpreadAnnotationsName,
 			descWorkloadSpreadAnnotationsHelp,
 			metric.Gauge,
@@ -129,7 +128,7 @@ func workloadSpreadMetricFamilies(allowAnnotationsList, allowLabelsList []string
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			descWorkloadSpreadLabelsName,
 			descWorkloadSpreadLabelsHelp,
 			metric.Gauge,
@@ -147,7 +146,7 @@ func workloadSpreadMetricFamilies(allowAnnotationsList, allowLabelsList []string
 				}
 			}),
 		),
-		*generator.NewFamilyGenerator(
+		newFamilyGenerator(
 			"kruise_workloadspread_spec_strategy_type",
 			"The type of updateStrategy.",
 			metric.Gauge,
@@ -181,12 +180,16 @@ func wrapWorkloadSpreadFunc(f func(*v1alpha1.WorkloadSpread) *metric.Family) fun
 	}
 }
 
-func createWorkloadSpreadListWatch(kruiseClient kruiseclientset.Interface, ns string) cache.ListerWatcher {
+func createWorkloadSpreadListWatch(kruiseClient kruiseclientset.Interface, ns string, labelSelector, fieldSelector string) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().WorkloadSpreads(ns).List(context.TODO(), opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = labelSelector
+			opts.FieldSelector = fieldSelector
 			return kruiseClient.AppsV1alpha1().WorkloadSpreads(ns).Watch(context.TODO(), opts)
 		},
 	}
@@ -0,0 +1,511 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	klog "k8s.io/klog/v2"
+)
+
+// matchedWorkloadSpreadAnnotation is the annotation of the WorkloadSpread and
+// subset a pod was assigned to by the Kruise webhook.
+const matchedWorkloadSpreadAnnotation = "apps.kruise.io/matched-workloadspread"
+
+var descWorkloadSpreadSubsetLabels = []string{"namespace", "workloadspread", "subset"}
+
+// complianceSubset is what the placement compliance needs of a subset of a
+// WorkloadSpread.
+type complianceSubset struct {
+	name string
+	// term is nil if the subset selects all nodes.
+	term        *v1.NodeSelectorTerm
+	maxReplicas *intstr.IntOrString
+}
+
+// matches returns whether the node of the given name and labels belongs to
+// the subset.
+func (s *complianceSubset) matches(nodeName string, nodeLabels map[string]string) bool {
+	return s.term == nil || nodeSelectorTermMatches(s.term, nodeName, nodeLabels)
+}
+
+// complianceWorkloadSpread is what the placement compliance needs of a
+// WorkloadSpread.
+type complianceWorkloadSpread struct {
+	namespace string
+	name      string
+	subsets   []complianceSubset
+	// target is the workload the percentages of the subsets are scaled on,
+	// nil if they cannot be scaled.
+	target *v1alpha1.TargetReference
+}
+
+func newComplianceWorkloadSpread(ws *v1alpha1.WorkloadSpread) *complianceWorkloadSpread {
+	w := &complianceWorkloadSpread{
+		namespace: ws.Namespace,
+		name:      ws.Name,
+		subsets:   make([]complianceSubset, 0, len(ws.Spec.Subsets)),
+	}
+	// The pods filtered out of the target are not part of the spread, their
+	// number is not known from the replicas of the target.
+	if ws.Spec.TargetFilter == nil {
+		w.target = ws.Spec.TargetReference
+	}
+	for _, s := range ws.Spec.Subsets {
+		w.subsets = append(w.subsets, complianceSubset{
+			name:        s.Name,
+			term:        s.RequiredNodeSelectorTerm,
+			maxReplicas: s.MaxReplicas,
+		})
+	}
+	return w
+}
+
+// subsetOf returns the index of the subset of the nodes a pod runs on, -1 if
+// none. The subset recorded by the webhook is kept while the node matches it,
+// otherwise the first subset matching the node is returned.
+func (w *complianceWorkloadSpread) subsetOf(pod *compliancePod, nodeLabels map[string]string) int {
+	first := -1
+	for i := range w.subsets {
+		if !w.subsets[i].matches(pod.nodeName, nodeLabels) {
+			continue
+		}
+		if w.subsets[i].name == pod.subset {
+			return i
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return first
+}
+
+// nodeSelectorTermMatches returns whether the node of the given name and
+// labels matches the term, like the scheduler does. An empty term matches no
+// nodes.
+func nodeSelectorTermMatches(term *v1.NodeSelectorTerm, nodeName string, nodeLabels map[string]string) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, r := range term.MatchExpressions {
+		value, ok := nodeLabels[r.Key]
+		if !nodeSelectorRequirementMatches(r, value, ok) {
+			return false
+		}
+	}
+	for _, r := range term.MatchFields {
+		// metadata.name is the only field supported by the scheduler.
+		if r.Key != "metadata.name" || !nodeSelectorRequirementMatches(r, nodeName, true) {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeSelectorRequirementMatches(r v1.NodeSelectorRequirement, value string, exists bool) bool {
+	switch r.Operator {
+	case v1.NodeSelectorOpIn:
+		return exists && slices.Contains(r.Values, value)
+	case v1.NodeSelectorOpNotIn:
+		return !exists || !slices.Contains(r.Values, value)
+	case v1.NodeSelectorOpExists:
+		return exists
+	case v1.NodeSelectorOpDoesNotExist:
+		return !exists
+	case v1.NodeSelectorOpGt, v1.NodeSelectorOpLt:
+		if !exists || len(r.Values) != 1 {
+			return false
+		}
+		actual, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		bound, err := strconv.ParseInt(r.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if r.Operator == v1.NodeSelectorOpGt {
+			return actual > bound
+		}
+		return actual < bound
+	}
+	return false
+}
+
+// compliancePod is what the placement compliance needs of a pod.
+type compliancePod struct {
+	namespace      string
+	workloadSpread string
+	// subset is the subset recorded by the webhook.
+	subset   string
+	nodeName string
+}
+
+// workloadSpreadCompliance computes the placement of the pods of the
+// WorkloadSpreads from the nodes they run on, when it is collected. It is the
+// objectObserver of the WorkloadSpreads and the podHandler of the pods.
+type workloadSpreadCompliance struct {
+	collectorWriter
+	nodeLabels func(string) (map[string]string, bool)
+	// replicasOf returns the desired replicas of the target of a
+	// WorkloadSpread.
+	replicasOf func(namespace string, target *v1alpha1.TargetReference) (int32, bool)
+
+	podsDesc        *prometheus.Desc
+	maxReplicasDesc *prometheus.Desc
+	unmatchedDesc   *prometheus.Desc
+
+	// Protects workloadSpreads and pods
+	mutex           sync.RWMutex
+	workloadSpreads map[types.UID]*complianceWorkloadSpread
+	pods            map[types.UID]*compliancePod
+}
+
+func newWorkloadSpreadCompliance(
+	nodeLabels func(string) (map[string]string, bool),
+	replicasOf func(namespace string, target *v1alpha1.TargetReference) (int32, bool),
+) *workloadSpreadCompliance {
+	c := &workloadSpreadCompliance{
+		nodeLabels: nodeLabels,
+		replicasOf: replicasOf,
+		podsDesc: prometheus.NewDesc(
+			"kruise_workloadspread_subset_pods",
+			"Number of pods of a workloadspread running on the nodes of a subset, resolved from the required node selector terms of the subsets and the labels of the nodes.",
+			descWorkloadSpreadSubsetLabels, nil,
+		),
+		maxReplicasDesc: prometheus.NewDesc(
+			"kruise_workloadspread_subset_max_replicas",
+			"Maximum number of pods of a subset of a workloadspread, percentages are scaled on the desired replicas of its target workload.",
+			descWorkloadSpreadSubsetLabels, nil,
+		),
+		unmatchedDesc: prometheus.NewDesc(
+			"kruise_workloadspread_pods_unmatched",
+			"Number of pods of a workloadspread running on nodes which match none of its subsets.",
+			descWorkloadSpreadLabelsDefaultLabels, nil,
+		),
+		workloadSpreads: map[types.UID]*complianceWorkloadSpread{},
+		pods:            map[types.UID]*compliancePod{},
+	}
+	c.collectorWriter = newCollectorWriter("workloadspreads", c,
+		"kruise_workloadspread_subset_pods",
+		"kruise_workloadspread_subset_max_replicas",
+		"kruise_workloadspread_pods_unmatched",
+	)
+	return c
+}
+
+// updateObject implements objectObserver.
+func (c *workloadSpreadCompliance) updateObject(ws *v1alpha1.WorkloadSpread) {
+	w := newComplianceWorkloadSpread(ws)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.workloadSpreads[ws.UID] = w
+}
+
+// deleteObject implements objectObserver.
+func (c *workloadSpreadCompliance) deleteObject(uid types.UID) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.workloadSpreads, uid)
+}
+
+// updatePod implements podHandler. Only the pods assigned to a WorkloadSpread
+// by the webhook which are not done are kept.
+func (c *workloadSpreadCompliance) updatePod(pod *v1.Pod) {
+	var matched struct {
+		Name   string `json:"name"`
+		Subset string `json:"subset"`
+	}
+	v, ok := pod.Annotations[matchedWorkloadSpreadAnnotation]
+	if !ok || json.Unmarshal([]byte(v), &matched) != nil || matched.Name == "" ||
+		pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		c.deletePod(pod.UID)
+		return
+	}
+	p := &compliancePod{
+		namespace:      pod.Namespace,
+		workloadSpread: matched.Name,
+		subset:         matched.Subset,
+		nodeName:       pod.Spec.NodeName,
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pods[pod.UID] = p
+}
+
+// deletePod implements podHandler.
+func (c *workloadSpreadCompliance) deletePod(uid types.UID) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.pods, uid)
+}
+
+// Describe implements prometheus.Collector.
+func (c *workloadSpreadCompliance) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.podsDesc
+	ch <- c.maxReplicasDesc
+	ch <- c.unmatchedDesc
+}
+
+// Collect implements prometheus.Collector. The pods which are not scheduled
+// yet or whose node is unknown are left out. The maximum of the subsets given
+// in percent is left out until the replicas of the target are known.
+func (c *workloadSpreadCompliance) Collect(ch chan<- prometheus.Metric) {
+	type key struct {
+		namespace, name string
+	}
+	type counts struct {
+		unmatched float64
+		subsets   []float64
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	workloadSpreads := make(map[key]*complianceWorkloadSpread, len(c.workloadSpreads))
+	placements := make(map[key]*counts, len(c.workloadSpreads))
+	for _, w := range c.workloadSpreads {
+		k := key{namespace: w.namespace, name: w.name}
+		workloadSpreads[k] = w
+		placements[k] = &counts{subsets: make([]float64, len(w.subsets))}
+	}
+	for _, p := range c.pods {
+		k := key{namespace: p.namespace, name: p.workloadSpread}
+		w, ok := workloadSpreads[k]
+		if !ok || p.nodeName == "" {
+			continue
+		}
+		nodeLabels, ok := c.nodeLabels(p.nodeName)
+		if !ok {
+			continue
+		}
+		if i := w.subsetOf(p, nodeLabels); i >= 0 {
+			placements[k].subsets[i]++
+		} else {
+			placements[k].unmatched++
+		}
+	}
+
+	for k, placement := range placements {
+		w := workloadSpreads[k]
+		for i, s := range w.subsets {
+			ch <- prometheus.MustNewConstMetric(c.podsDesc, prometheus.GaugeValue, placement.subsets[i], k.namespace, k.name, s.name)
+			if s.maxReplicas == nil {
+				continue
+			}
+			var replicas int32
+			if s.maxReplicas.Type == intstr.String {
+				if w.target == nil {
+					continue
+				}
+				var ok bool
+				if replicas, ok = c.replicasOf(w.namespace, w.target); !ok {
+					continue
+				}
+			}
+			maxReplicas, err := intstr.GetScaledValueFromIntOrPercent(s.maxReplicas, int(replicas), true)
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.maxReplicasDesc, prometheus.GaugeValue, float64(maxReplicas), k.namespace, k.name, s.name)
+		}
+		ch <- prometheus.MustNewConstMetric(c.unmatchedDesc, prometheus.GaugeValue, placement.unmatched, k.namespace, k.name)
+	}
+}
+
+// targetReplicasRefreshPeriod is the period at which the replicas of the
+// targets of the WorkloadSpreads are read again.
+const targetReplicasRefreshPeriod = 30 * time.Second
+
+// targetKey identifies the target of a WorkloadSpread.
+type targetKey struct {
+	schema.GroupKind
+	namespace, name string
+}
+
+// targetReplicas resolves the desired replicas of the targets of the
+// WorkloadSpreads from their scale subresource, so that the workloads they
+// may target are not watched. Only the targets requested since the previous
+// refresh are read again, the replicas of a target are unknown until read.
+type targetReplicas struct {
+	// getScale returns the desired replicas of a target.
+	getScale func(ctx context.Context, key targetKey) (int32, error)
+	// refresh is signaled when the replicas of a requested target are
+	// unknown.
+	refresh chan struct{}
+
+	// Protects replicas, requested and read
+	mutex     sync.Mutex
+	replicas  map[targetKey]int32
+	requested sets.Set[targetKey]
+	// read holds the targets read since the previous refresh, so that the
+	// targets which cannot be read are not read on every request.
+	read sets.Set[targetKey]
+}
+
+func newTargetReplicas() *targetReplicas {
+	return &targetReplicas{
+		refresh:   make(chan struct{}, 1),
+		replicas:  map[targetKey]int32{},
+		requested: sets.New[targetKey](),
+		read:      sets.New[targetKey](),
+	}
+}
+
+// get returns the desired replicas of the target in the given namespace.
+func (t *targetReplicas) get(namespace string, target *v1alpha1.TargetReference) (int32, bool) {
+	gv, err := schema.ParseGroupVersion(target.APIVersion)
+	if err != nil {
+		return 0, false
+	}
+	key := targetKey{GroupKind: gv.WithKind(target.Kind).GroupKind(), namespace: namespace, name: target.Name}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.requested.Insert(key)
+	r, ok := t.replicas[key]
+	if !ok && !t.read.Has(key) {
+		select {
+		case t.refresh <- struct{}{}:
+		default:
+		}
+	}
+	return r, ok
+}
+
+// run reads the replicas of the requested targets every refresh period, and
+// those of the newly requested targets as soon as they are requested, until
+// the context is done.
+func (t *targetReplicas) run(ctx context.Context) {
+	ticker := time.NewTicker(targetReplicasRefreshPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.update(ctx, true)
+		case <-t.refresh:
+			t.update(ctx, false)
+		}
+	}
+}
+
+// update reads the replicas of the targets requested since the previous
+// refresh if all is set, and forgets the other targets. Otherwise it only
+// reads the requested targets which were not read yet.
+func (t *targetReplicas) update(ctx context.Context, all bool) {
+	t.mutex.Lock()
+	keys := t.requested.Clone()
+	if all {
+		t.requested = sets.New[targetKey]()
+		t.read = sets.New[targetKey]()
+	} else {
+		keys = keys.Difference(t.read)
+	}
+	t.read = t.read.Union(keys)
+	t.mutex.Unlock()
+
+	replicas := make(map[targetKey]int32, keys.Len())
+	failed := sets.New[targetKey]()
+	for key := range keys {
+		r, err := t.getScale(ctx, key)
+		if err != nil {
+			klog.V(4).Infof("Failed to read the replicas of %s %s/%s: %v", key.Kind, key.namespace, key.name, err)
+			// The replicas of the targets which still exist are kept
+			// until they are read again.
+			if !apierrors.IsNotFound(err) {
+				failed.Insert(key)
+			}
+			continue
+		}
+		replicas[key] = r
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if all {
+		for key := range failed {
+			if r, ok := t.replicas[key]; ok {
+				replicas[key] = r
+			}
+		}
+		t.replicas = replicas
+		return
+	}
+	for key, r := range replicas {
+		t.replicas[key] = r
+	}
+}
+
+// targetScale returns the desired replicas of a target of a WorkloadSpread
+// from its scale subresource.
+func (b *Builder) targetScale(ctx context.Context, key targetKey) (int32, error) {
+	var scale *autoscalingv1.Scale
+	var err error
+	switch key.GroupKind {
+	case schema.GroupKind{Group: v1alpha1.GroupVersion.Group, Kind: "CloneSet"}:
+		scale, err = b.kruiseClient.AppsV1alpha1().CloneSets(key.namespace).GetScale(ctx, key.name, metav1.GetOptions{})
+	case schema.GroupKind{Group: v1beta1.GroupVersion.Group, Kind: "StatefulSet"}:
+		scale, err = b.kruiseClient.AppsV1beta1().StatefulSets(key.namespace).GetScale(ctx, key.name, metav1.GetOptions{})
+	case schema.GroupKind{Group: appsv1.GroupName, Kind: "Deployment"}:
+		scale, err = b.kubeClient.AppsV1().Deployments(key.namespace).GetScale(ctx, key.name, metav1.GetOptions{})
+	case schema.GroupKind{Group: appsv1.GroupName, Kind: "ReplicaSet"}:
+		scale, err = b.kubeClient.AppsV1().ReplicaSets(key.namespace).GetScale(ctx, key.name, metav1.GetOptions{})
+	case schema.GroupKind{Group: appsv1.GroupName, Kind: "StatefulSet"}:
+		scale, err = b.kubeClient.AppsV1().StatefulSets(key.namespace).GetScale(ctx, key.name, metav1.GetOptions{})
+	default:
+		return 0, errors.Errorf("unsupported target kind %s", key.GroupKind)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return scale.Spec.Replicas, nil
+}
+
+// buildTargetReplicas starts reading the replicas of the targets of the
+// WorkloadSpreads.
+func (b *Builder) buildTargetReplicas(t *targetReplicas) {
+	t.getScale = b.targetScale
+	go t.run(b.ctx)
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	"github.com/pkg/errors"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+// zoneTerm returns a node selector term selecting the nodes of a zone.
+func zoneTerm(zone string) *v1.NodeSelectorTerm {
+	return &v1.NodeSelectorTerm{
+		MatchExpressions: []v1.NodeSelectorRequirement{{
+			Key:      v1.LabelTopologyZone,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{zone},
+		}},
+	}
+}
+
+// complianceTestWorkloadSpread returns a workloadspread of CloneSet c1 with a
+// subset of at most 1 pod in zone a, and an unlimited subset in zone b.
+func complianceTestWorkloadSpread() *v1alpha1.WorkloadSpread {
+	return &v1alpha1.WorkloadSpread{
+		ObjectMeta: testObjectMeta("ns1", "ws1"),
+		Spec: v1alpha1.WorkloadSpreadSpec{
+			TargetReference: &v1alpha1.TargetReference{APIVersion: "apps.kruise.io/v1alpha1", Kind: "CloneSet", Name: "c1"},
+			Subsets: []v1alpha1.WorkloadSpreadSubset{
+				{Name: "a", RequiredNodeSelectorTerm: zoneTerm("a"), MaxReplicas: ptr.To(intstr.FromInt32(1))},
+				{Name: "b", RequiredNodeSelectorTerm: zoneTerm("b")},
+			},
+		},
+	}
+}
+
+// complianceTestPod returns a pod assigned to the given subset of ws1 by the
+// webhook, running on the given node.
+func complianceTestPod(name, subset, nodeName string) *v1.Pod {
+	pod := testPod("ns1", name)
+	pod.Annotations = map[string]string{matchedWorkloadSpreadAnnotation: `{"name":"ws1","subset":"` + subset + `"}`}
+	pod.Spec.NodeName = nodeName
+	return pod
+}
+
+func TestNodeSelectorTermMatches(t *testing.T) {
+	nodeLabels := map[string]string{"zone": "a", "cpus": "8"}
+	for _, test := range []struct {
+		name string
+		term v1.NodeSelectorTerm
+		want bool
+	}{
+		{name: "empty", want: false},
+		{name: "in", term: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a", "b"}}}}, want: true},
+		{name: "not in", term: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpNotIn, Values: []string{"a"}}}}, want: false},
+		{name: "not in missing", term: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "rack", Operator: v1.NodeSelectorOpNotIn, Values: []string{"r1"}}}}, want: true},
+		{name: "exists", term: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpExists}}}, want: true},
+		{name: "does not exist", term: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpDoesNotExist}}}, want: false},
+		{name: "gt", term: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "cpus", Operator: v1.NodeSelectorOpGt, Values: []string{"4"}}}}, want: true},
+		{name: "lt", term: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "cpus", Operator: v1.NodeSelectorOpLt, Values: []string{"4"}}}}, want: false},
+		{name: "all expressions", term: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+			{Key: "zone", Operator: v1.NodeSelectorOpExists},
+			{Key: "cpus", Operator: v1.NodeSelectorOpLt, Values: []string{"4"}},
+		}}, want: false},
+		{name: "name", term: v1.NodeSelectorTerm{MatchFields: []v1.NodeSelectorRequirement{{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: []string{"n1"}}}}, want: true},
+		{name: "unsupported field", term: v1.NodeSelectorTerm{MatchFields: []v1.NodeSelectorRequirement{{Key: "spec.unschedulable", Operator: v1.NodeSelectorOpIn, Values: []string{"false"}}}}, want: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := nodeSelectorTermMatches(&test.term, "n1", nodeLabels); got != test.want {
+				t.Errorf("expected %t, got %t", test.want, got)
+			}
+		})
+	}
+}
+
+func TestWorkloadSpreadCompliance(t *testing.T) {
+	nodes := map[string]map[string]string{
+		"a1": {v1.LabelTopologyZone: "a"},
+		"b1": {v1.LabelTopologyZone: "b"},
+		"c1": {v1.LabelTopologyZone: "c"},
+	}
+	compliance := newWorkloadSpreadCompliance(func(name string) (map[string]string, bool) {
+		l, ok := nodes[name]
+		return l, ok
+	}, func(namespace string, target *v1alpha1.TargetReference) (int32, bool) {
+		if namespace == "ns1" && target.Kind == "CloneSet" && target.Name == "c1" {
+			return 6, true
+		}
+		return 0, false
+	})
+	store := newObservedStore[*v1alpha1.WorkloadSpread](cache.NewStore(cache.MetaNamespaceKeyFunc), compliance)
+	if err := store.Add(complianceTestWorkloadSpread()); err != nil {
+		t.Fatal(err)
+	}
+
+	other := complianceTestPod("other", "a", "a1")
+	other.Annotations[matchedWorkloadSpreadAnnotation] = `{"name":"ws2","subset":"a"}`
+	for _, pod := range []*v1.Pod{
+		complianceTestPod("p1", "a", "a1"),
+		// Recorded in subset b, but its node was relabeled to zone a.
+		complianceTestPod("p2", "b", "a1"),
+		complianceTestPod("p3", "b", "b1"),
+		complianceTestPod("p4", "b", "c1"),
+		complianceTestPod("pending", "b", ""),
+		complianceTestPod("unknown", "b", "d1"),
+		other,
+	} {
+		compliance.updatePod(pod)
+	}
+
+	want := []string{
+		`kruise_workloadspread_pods_unmatched{namespace="ns1",workloadspread="ws1"} 1`,
+		`kruise_workloadspread_subset_max_replicas{namespace="ns1",subset="a",workloadspread="ws1"} 1`,
+		`kruise_workloadspread_subset_pods{namespace="ns1",subset="a",workloadspread="ws1"} 2`,
+		`kruise_workloadspread_subset_pods{namespace="ns1",subset="b",workloadspread="ws1"} 1`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(compliance)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// Percentages are scaled on the replicas of the target.
+	ws := complianceTestWorkloadSpread()
+	ws.Spec.Subsets[0].MaxReplicas = ptr.To(intstr.FromString("50%"))
+	if err := store.Update(ws); err != nil {
+		t.Fatal(err)
+	}
+	compliance.deletePod("ns1/p4")
+	want = []string{
+		`kruise_workloadspread_pods_unmatched{namespace="ns1",workloadspread="ws1"} 0`,
+		`kruise_workloadspread_subset_max_replicas{namespace="ns1",subset="a",workloadspread="ws1"} 3`,
+		`kruise_workloadspread_subset_pods{namespace="ns1",subset="a",workloadspread="ws1"} 2`,
+		`kruise_workloadspread_subset_pods{namespace="ns1",subset="b",workloadspread="ws1"} 1`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(compliance)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// Percentages are left out when the replicas of the target are unknown,
+	// or when the target is filtered.
+	ws = ws.DeepCopy()
+	ws.Spec.TargetReference.Name = "c2"
+	filtered := complianceTestWorkloadSpread()
+	filtered.Spec.Subsets[0].MaxReplicas = ptr.To(intstr.FromString("50%"))
+	filtered.Spec.TargetFilter = &v1alpha1.TargetFilter{ReplicasPathList: []string{"spec.replicas"}}
+	for _, ws := range []*v1alpha1.WorkloadSpread{ws, filtered} {
+		if err := store.Update(ws); err != nil {
+			t.Fatal(err)
+		}
+		for _, sample := range collectorSamples(compliance) {
+			if strings.HasPrefix(sample, "kruise_workloadspread_subset_max_replicas") {
+				t.Errorf("unexpected sample %s", sample)
+			}
+		}
+	}
+
+	if err := store.Replace(nil, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := collectorSamples(compliance); len(got) != 0 {
+		t.Errorf("expected no samples of the deleted workloadspread, got %v", got)
+	}
+}
+
+func TestTargetReplicas(t *testing.T) {
+	replicas := map[string]int32{"c1": 3, "c2": 5}
+	reads := 0
+	targets := newTargetReplicas()
+	targets.getScale = func(_ context.Context, key targetKey) (int32, error) {
+		reads++
+		if key.name == "broken" {
+			return 0, errors.New("unavailable")
+		}
+		r, ok := replicas[key.name]
+		if !ok {
+			return 0, apierrors.NewNotFound(v1alpha1.Resource("clonesets"), key.name)
+		}
+		return r, nil
+	}
+	target := func(name string) *v1alpha1.TargetReference {
+		return &v1alpha1.TargetReference{APIVersion: v1alpha1.GroupVersion.String(), Kind: "CloneSet", Name: name}
+	}
+
+	// The replicas are unknown until read.
+	for _, name := range []string{"c1", "c2", "missing"} {
+		if _, ok := targets.get("ns1", target(name)); ok {
+			t.Errorf("expected the replicas of %s to be unknown", name)
+		}
+	}
+	targets.update(context.Background(), false)
+	if r, ok := targets.get("ns1", target("c1")); !ok || r != 3 {
+		t.Errorf("expected 3 replicas, got %d, %t", r, ok)
+	}
+	// The targets already read are not read again before the next refresh.
+	targets.update(context.Background(), false)
+	if reads != 3 {
+		t.Errorf("expected 3 reads, got %d", reads)
+	}
+
+	// The targets which are not requested any more are forgotten, those
+	// which cannot be read keep their replicas.
+	replicas["c1"] = 4
+	targets.replicas[targetKey{GroupKind: v1alpha1.GroupVersion.WithKind("CloneSet").GroupKind(), namespace: "ns1", name: "broken"}] = 2
+	targets.get("ns1", target("broken"))
+	targets.update(context.Background(), true)
+	if r, ok := targets.get("ns1", target("c1")); !ok || r != 4 {
+		t.Errorf("expected 4 replicas, got %d, %t", r, ok)
+	}
+	if r, ok := targets.get("ns1", target("broken")); !ok || r != 2 {
+		t.Errorf("expected the replicas of broken to be kept, got %d, %t", r, ok)
+	}
+	targets.update(context.Background(), true)
+	if _, ok := targets.get("ns1", target("c2")); ok {
+		t.Errorf("expected the replicas of c2 to be forgotten")
+	}
+}
+
+func TestWorkloadSpreadComplianceMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "n1", Labels: map[string]string{v1.LabelTopologyZone: "b"}},
+	}
+	scheme := metadatafake.NewTestScheme()
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(scheme, node)
+	ws := complianceTestWorkloadSpread()
+	ws.Spec.TargetReference = &v1alpha1.TargetReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "d1"}
+	ws.Spec.Subsets[0].MaxReplicas = ptr.To(intstr.FromString("50%"))
+	kubeClient := fake.NewSimpleClientset(complianceTestPod("p1", "b", "n1"))
+	// The fake clientset does not serve the scale subresource.
+	kubeClient.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" || action.(k8stesting.GetAction).GetName() != "d1" {
+			return false, nil, nil
+		}
+		return true, &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 4}}, nil
+	})
+	kruiseClient := kruisefake.NewSimpleClientset(ws)
+	b := newTestBuilder(t, ctx, kubeClient, kruiseClient, "workloadspreads")
+	b.WithMetadataClient(metadataClient)
+	b.WithPodMetrics(true)
+	writers := b.Build()
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_workloadspread_subset_pods{namespace="ns1",subset="b",workloadspread="ws1"} 1`) &&
+			strings.Contains(out, `kruise_workloadspread_subset_max_replicas{namespace="ns1",subset="a",workloadspread="ws1"} 2`)
+	})
+
+	// The pod follows the labels of its node.
+	node = node.DeepCopy()
+	node.Labels[v1.LabelTopologyZone] = "a"
+	if _, err := metadataClient.Resource(nodesResource).(metadatafake.MetadataClient).UpdateFake(node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_workloadspread_subset_pods{namespace="ns1",subset="a",workloadspread="ws1"} 1`)
+	})
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"strings"
+	"sync"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+// workloadsSummaryResource is the resource of the summary of the workloads.
+// It has no objects of its own, the summary is maintained from the objects
+// of the workload resources.
+const workloadsSummaryResource = "workloadssummary"
+
+var descWorkloadsSummaryLabels = []string{"kind", "namespace"}
+
+// summaryKey identifies the workloads of a kind in a namespace.
+type summaryKey struct {
+	kind, namespace string
+}
+
+// summaryCounts are the counts of the workloads of a kind in a namespace, or
+// the contribution of a single workload to them.
+type summaryCounts struct {
+	workloads, desired, ready, updating, paused, failing float64
+}
+
+func (c *summaryCounts) add(o *summaryCounts, sign float64) {
+	c.workloads += sign * o.workloads
+	c.desired += sign * o.desired
+	c.ready += sign * o.ready
+	c.updating += sign * o.updating
+	c.paused += sign * o.paused
+	c.failing += sign * o.failing
+}
+
+// summaryWorkload is the contribution of a workload to the summary.
+type summaryWorkload struct {
+	key    summaryKey
+	counts summaryCounts
+}
+
+// isFailingCondition returns whether a condition reports a failure of the
+// controller, e.g. the FailedScale condition of the clonesets or the
+// FailedCreatePod condition of the advanced statefulsets.
+func isFailingCondition(conditionType string, status v1.ConditionStatus) bool {
+	return strings.HasPrefix(conditionType, "Failed") && status == v1.ConditionTrue
+}
+
+// workloadReplicas returns the kind and the desired and ready replicas of a
+// workload, false if the object is not a workload. The desired replicas of
+// the advanced daemonsets are their desired number of scheduled pods.
+func workloadReplicas(obj metav1.Object) (kind string, desired, ready int32, ok bool) {
+	switch o := obj.(type) {
+	case *appsv1alpha1.CloneSet:
+		return "CloneSet", ptr.Deref(o.Spec.Replicas, 1), o.Status.ReadyReplicas, true
+	case *appsv1beta1.StatefulSet:
+		return "StatefulSet", ptr.Deref(o.Spec.Replicas, 1), o.Status.ReadyReplicas, true
+	case *appsv1alpha1.DaemonSet:
+		return "DaemonSet", o.Status.DesiredNumberScheduled, o.Status.NumberReady, true
+	}
+	return "", 0, 0, false
+}
+
+// newSummaryWorkload returns the contribution of a workload to the summary,
+// nil if the object is not a workload. The replicas updating are the target
+// replicas of the rollout which are not updated and ready yet.
+func newSummaryWorkload(obj metav1.Object) *summaryWorkload {
+	kind, desired, ready, ok := workloadReplicas(obj)
+	if !ok {
+		return nil
+	}
+	w := &summaryWorkload{
+		key: summaryKey{kind: kind, namespace: obj.GetNamespace()},
+		counts: summaryCounts{
+			workloads: 1,
+			desired:   float64(desired),
+			ready:     float64(ready),
+		},
+	}
+	var progress rolloutProgress
+	switch o := obj.(type) {
+	case *appsv1alpha1.CloneSet:
+		progress = cloneSetRolloutProgress(o)
+		w.counts.paused = BoolFloat64(o.Spec.UpdateStrategy.Paused)
+		for _, c := range o.Status.Conditions {
+			if isFailingCondition(string(c.Type), c.Status) {
+				w.counts.failing = 1
+			}
+		}
+	case *appsv1beta1.StatefulSet:
+		progress = statefulSetRolloutProgress(o)
+		w.counts.paused = BoolFloat64(o.Spec.UpdateStrategy.RollingUpdate != nil && o.Spec.UpdateStrategy.RollingUpdate.Paused)
+		for _, c := range o.Status.Conditions {
+			if isFailingCondition(string(c.Type), c.Status) {
+				w.counts.failing = 1
+			}
+		}
+	case *appsv1alpha1.DaemonSet:
+		progress = daemonSetRolloutProgress(o)
+		w.counts.paused = BoolFloat64(o.Spec.UpdateStrategy.RollingUpdate != nil &&
+			o.Spec.UpdateStrategy.RollingUpdate.Paused != nil && *o.Spec.UpdateStrategy.RollingUpdate.Paused)
+		for _, c := range o.Status.Conditions {
+			if isFailingCondition(string(c.Type), c.Status) {
+				w.counts.failing = 1
+			}
+		}
+	}
+	w.counts.updating = float64(max(progress.target-progress.updatedReady, 0))
+	return w
+}
+
+// workloadsSummary sums the workloads by kind and namespace. The sums are
+// maintained as the workloads are written to the stores, so that collecting
+// them doesn't depend on the number of workloads. It is the objectObserver
+// of the workloads.
+type workloadsSummary struct {
+	collectorWriter
+
+	workloadsDesc *prometheus.Desc
+	desiredDesc   *prometheus.Desc
+	readyDesc     *prometheus.Desc
+	updatingDesc  *prometheus.Desc
+	pausedDesc    *prometheus.Desc
+	failingDesc   *prometheus.Desc
+
+	// Protects workloads and totals
+	mutex     sync.RWMutex
+	workloads map[types.UID]*summaryWorkload
+	totals    map[summaryKey]*summaryCounts
+}
+
+func newWorkloadsSummary() *workloadsSummary {
+	s := &workloadsSummary{
+		workloadsDesc: prometheus.NewDesc(
+			"kruise_workloads_summary_workloads",
+			"Number of workloads of a kind in a namespace.",
+			descWorkloadsSummaryLabels, nil,
+		),
+		desiredDesc: prometheus.NewDesc(
+			"kruise_workloads_summary_replicas_desired",
+			"Number of replicas desired by the workloads of a kind in a namespace.",
+			descWorkloadsSummaryLabels, nil,
+		),
+		readyDesc: prometheus.NewDesc(
+			"kruise_workloads_summary_replicas_ready",
+			"Number of ready replicas of the workloads of a kind in a namespace.",
+			descWorkloadsSummaryLabels, nil,
+		),
+		updatingDesc: prometheus.NewDesc(
+			"kruise_workloads_summary_replicas_updating",
+			"Number of replicas of the workloads of a kind in a namespace which are still to be updated and ready, as allowed by their partition.",
+			descWorkloadsSummaryLabels, nil,
+		),
+		pausedDesc: prometheus.NewDesc(
+			"kruise_workloads_summary_workloads_paused",
+			"Number of workloads of a kind in a namespace whose update is paused.",
+			descWorkloadsSummaryLabels, nil,
+		),
+		failingDesc: prometheus.NewDesc(
+			"kruise_workloads_summary_workloads_failing",
+			"Number of workloads of a kind in a namespace with a true Failed condition, e.g. FailedScale.",
+			descWorkloadsSummaryLabels, nil,
+		),
+		workloads: map[types.UID]*summaryWorkload{},
+		totals:    map[summaryKey]*summaryCounts{},
+	}
+	s.collectorWriter = newCollectorWriter(workloadsSummaryResource, s,
+		"kruise_workloads_summary_workloads",
+		"kruise_workloads_summary_replicas_desired",
+		"kruise_workloads_summary_replicas_ready",
+		"kruise_workloads_summary_replicas_updating",
+		"kruise_workloads_summary_workloads_paused",
+		"kruise_workloads_summary_workloads_failing",
+	)
+	return s
+}
+
+// updateObject implements objectObserver. The previous contribution of the
+// workload is replaced by the new one.
+func (s *workloadsSummary) updateObject(obj metav1.Object) {
+	w := newSummaryWorkload(obj)
+	if w == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.remove(obj.GetUID())
+	s.workloads[obj.GetUID()] = w
+	totals, ok := s.totals[w.key]
+	if !ok {
+		totals = &summaryCounts{}
+		s.totals[w.key] = totals
+	}
+	totals.add(&w.counts, 1)
+}
+
+// deleteObject implements objectObserver.
+func (s *workloadsSummary) deleteObject(uid types.UID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.remove(uid)
+}
+
+// remove subtracts the contribution of a workload from the totals, and drops
+// the totals once no workload is left. The mutex must be held.
+func (s *workloadsSummary) remove(uid types.UID) {
+	w, ok := s.workloads[uid]
+	if !ok {
+		return
+	}
+	delete(s.workloads, uid)
+
+	totals := s.totals[w.key]
+	totals.add(&w.counts, -1)
+	if totals.workloads <= 0 {
+		delete(s.totals, w.key)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (s *workloadsSummary) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.workloadsDesc
+	ch <- s.desiredDesc
+	ch <- s.readyDesc
+	ch <- s.updatingDesc
+	ch <- s.pausedDesc
+	ch <- s.failingDesc
+}
+
+// Collect implements prometheus.Collector.
+func (s *workloadsSummary) Collect(ch chan<- prometheus.Metric) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for k, c := range s.totals {
+		ch <- prometheus.MustNewConstMetric(s.workloadsDesc, prometheus.GaugeValue, c.workloads, k.kind, k.namespace)
+		ch <- prometheus.MustNewConstMetric(s.desiredDesc, prometheus.GaugeValue, c.desired, k.kind, k.namespace)
+		ch <- prometheus.MustNewConstMetric(s.readyDesc, prometheus.GaugeValue, c.ready, k.kind, k.namespace)
+		ch <- prometheus.MustNewConstMetric(s.updatingDesc, prometheus.GaugeValue, c.updating, k.kind, k.namespace)
+		ch <- prometheus.MustNewConstMetric(s.pausedDesc, prometheus.GaugeValue, c.paused, k.kind, k.namespace)
+		ch <- prometheus.MustNewConstMetric(s.failingDesc, prometheus.GaugeValue, c.failing, k.kind, k.namespace)
+	}
+}
+
+// buildWorkloadsSummaryStores returns no stores, the summary is written by
+// its own writer.
+func (b *Builder) buildWorkloadsSummaryStores() []*MetricsStore {
+	return nil
+}
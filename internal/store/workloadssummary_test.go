@@ -0,0 +1,156 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-api/apps/v1beta1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+func TestWorkloadsSummary(t *testing.T) {
+	summary := newWorkloadsSummary()
+	clonesets := newObservedStore[metav1.Object](cache.NewStore(cache.MetaNamespaceKeyFunc), summary)
+	others := newObservedStore[metav1.Object](cache.NewStore(cache.MetaNamespaceKeyFunc), summary)
+
+	paused := testCloneSet("ns1", "paused", 3, 3, 1)
+	paused.Spec.UpdateStrategy.Paused = true
+	failing := testCloneSet("ns1", "failing", 2, 0, 0)
+	failing.Status.Conditions = []v1alpha1.CloneSetCondition{
+		{Type: v1alpha1.CloneSetConditionFailedScale, Status: v1.ConditionTrue},
+		{Type: v1alpha1.CloneSetConditionFailedUpdate, Status: v1.ConditionTrue},
+	}
+	if err := clonesets.Replace([]interface{}{paused, failing, testCloneSet("ns2", "cs1", 1, 1, 1)}, "1"); err != nil {
+		t.Fatal(err)
+	}
+	sts := &v1beta1.StatefulSet{
+		ObjectMeta: testObjectMeta("ns1", "sts1"),
+		Spec: v1beta1.StatefulSetSpec{
+			Replicas: ptr.To[int32](4),
+			UpdateStrategy: v1beta1.StatefulSetUpdateStrategy{
+				RollingUpdate: &v1beta1.RollingUpdateStatefulSetStrategy{Partition: ptr.To[int32](2)},
+			},
+		},
+		Status: v1beta1.StatefulSetStatus{ReadyReplicas: 4},
+	}
+	ds := &v1alpha1.DaemonSet{
+		ObjectMeta: testObjectMeta("ns1", "ds1"),
+		Spec: v1alpha1.DaemonSetSpec{
+			UpdateStrategy: v1alpha1.DaemonSetUpdateStrategy{
+				RollingUpdate: &v1alpha1.RollingUpdateDaemonSet{Paused: ptr.To(true)},
+			},
+		},
+		Status: v1alpha1.DaemonSetStatus{
+			DesiredNumberScheduled: 5,
+			NumberReady:            5,
+			UpdatedNumberScheduled: 5,
+			Conditions:             []appsv1.DaemonSetCondition{{Type: "FailedPlacement", Status: v1.ConditionFalse}},
+		},
+	}
+	for _, obj := range []interface{}{sts, ds} {
+		if err := others.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		`kruise_workloads_summary_replicas_desired{kind="CloneSet",namespace="ns1"} 5`,
+		`kruise_workloads_summary_replicas_desired{kind="CloneSet",namespace="ns2"} 1`,
+		`kruise_workloads_summary_replicas_desired{kind="DaemonSet",namespace="ns1"} 5`,
+		`kruise_workloads_summary_replicas_desired{kind="StatefulSet",namespace="ns1"} 4`,
+		`kruise_workloads_summary_replicas_ready{kind="CloneSet",namespace="ns1"} 3`,
+		`kruise_workloads_summary_replicas_ready{kind="CloneSet",namespace="ns2"} 1`,
+		`kruise_workloads_summary_replicas_ready{kind="DaemonSet",namespace="ns1"} 5`,
+		`kruise_workloads_summary_replicas_ready{kind="StatefulSet",namespace="ns1"} 4`,
+		`kruise_workloads_summary_replicas_updating{kind="CloneSet",namespace="ns1"} 4`,
+		`kruise_workloads_summary_replicas_updating{kind="CloneSet",namespace="ns2"} 0`,
+		`kruise_workloads_summary_replicas_updating{kind="DaemonSet",namespace="ns1"} 0`,
+		`kruise_workloads_summary_replicas_updating{kind="StatefulSet",namespace="ns1"} 2`,
+		`kruise_workloads_summary_workloads{kind="CloneSet",namespace="ns1"} 2`,
+		`kruise_workloads_summary_workloads{kind="CloneSet",namespace="ns2"} 1`,
+		`kruise_workloads_summary_workloads{kind="DaemonSet",namespace="ns1"} 1`,
+		`kruise_workloads_summary_workloads{kind="StatefulSet",namespace="ns1"} 1`,
+		`kruise_workloads_summary_workloads_failing{kind="CloneSet",namespace="ns1"} 1`,
+		`kruise_workloads_summary_workloads_failing{kind="CloneSet",namespace="ns2"} 0`,
+		`kruise_workloads_summary_workloads_failing{kind="DaemonSet",namespace="ns1"} 0`,
+		`kruise_workloads_summary_workloads_failing{kind="StatefulSet",namespace="ns1"} 0`,
+		`kruise_workloads_summary_workloads_paused{kind="CloneSet",namespace="ns1"} 1`,
+		`kruise_workloads_summary_workloads_paused{kind="CloneSet",namespace="ns2"} 0`,
+		`kruise_workloads_summary_workloads_paused{kind="DaemonSet",namespace="ns1"} 1`,
+		`kruise_workloads_summary_workloads_paused{kind="StatefulSet",namespace="ns1"} 0`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(summary)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// Updates replace the contribution of the workloads, and the totals of
+	// the namespaces without workloads are dropped.
+	if err := clonesets.Update(testCloneSet("ns1", "failing", 2, 2, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := clonesets.Delete(paused); err != nil {
+		t.Fatal(err)
+	}
+	if err := others.Replace(nil, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := clonesets.Replace([]interface{}{testCloneSet("ns1", "failing", 2, 2, 2)}, "3"); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		`kruise_workloads_summary_replicas_desired{kind="CloneSet",namespace="ns1"} 2`,
+		`kruise_workloads_summary_replicas_ready{kind="CloneSet",namespace="ns1"} 2`,
+		`kruise_workloads_summary_replicas_updating{kind="CloneSet",namespace="ns1"} 0`,
+		`kruise_workloads_summary_workloads{kind="CloneSet",namespace="ns1"} 1`,
+		`kruise_workloads_summary_workloads_failing{kind="CloneSet",namespace="ns1"} 0`,
+		`kruise_workloads_summary_workloads_paused{kind="CloneSet",namespace="ns1"} 0`,
+	}
+	if diff := cmp.Diff(want, collectorSamples(summary)); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWorkloadsSummaryMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kruiseClient := kruisefake.NewSimpleClientset(testCloneSet("ns1", "cs1", 3, 2, 2))
+	b := newTestBuilder(t, ctx, fake.NewSimpleClientset(), kruiseClient, "clonesets", workloadsSummaryResource)
+	writers := b.Build()
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_workloads_summary_replicas_ready{kind="CloneSet",namespace="ns1"} 2`)
+	})
+
+	if _, err := kruiseClient.AppsV1alpha1().CloneSets("ns1").Create(ctx, testCloneSet("ns1", "cs2", 1, 1, 1), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, writers, func(out string) bool {
+		return strings.Contains(out, `kruise_workloads_summary_workloads{kind="CloneSet",namespace="ns1"} 2`) &&
+			strings.Contains(out, `kruise_workloads_summary_replicas_ready{kind="CloneSet",namespace="ns1"} 3`)
+	})
+}
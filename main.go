@@ -31,8 +31,12 @@ import (
 
 func main() {
 	options.DefaultResources = localoptions.DefaultResources
-	opts := options.NewOptions()
+	opts := localoptions.NewOptions()
 	opts.AddFlags()
+	render := len(os.Args) > 1 && os.Args[1] == app.RenderCommand
+	if render {
+		opts.AddRenderFlags()
+	}
 
 	err := opts.Parse()
 	if err != nil {
@@ -49,6 +53,13 @@ func main() {
 		os.Exit(0)
 	}
 
+	if render {
+		if err := app.Render(opts, os.Stdout); err != nil {
+			klog.Fatalf("Failed to render metrics: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 	if err := app.RunKruiseStateMetrics(ctx, opts); err != nil {
 		klog.Fatalf("Failed to run kruise-state-metrics: %v", err)
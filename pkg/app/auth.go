@@ -0,0 +1,262 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/cache"
+	clientset "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	authCacheSize = 1024
+	authTimeout   = 10 * time.Second
+)
+
+// authConfig configures which SubjectAccessReview a request must pass.
+type authConfig struct {
+	// NonResourceURL is checked when no Resource is set. The path of the
+	// request is checked when it is empty.
+	NonResourceURL string
+	// Resource is a virtual resource in the form resource.group, a request
+	// must be allowed to get it.
+	Resource  string
+	Namespace string
+	CacheTTL  time.Duration
+}
+
+// authFilter authenticates requests by their bearer token with a TokenReview
+// and authorizes them with a SubjectAccessReview, like kube-rbac-proxy.
+// Successful and denied reviews are cached for the configured TTL.
+type authFilter struct {
+	kubeClient clientset.Interface
+	config     authConfig
+	resource   *schema.GroupResource
+
+	authnCache *cache.LRUExpireCache
+	authzCache *cache.LRUExpireCache
+
+	requests *prometheus.CounterVec
+}
+
+func newAuthFilter(kubeClient clientset.Interface, config authConfig, clock clock.Clock, r prometheus.Registerer) (*authFilter, error) {
+	a := &authFilter{
+		kubeClient: kubeClient,
+		config:     config,
+		authnCache: cache.NewLRUExpireCacheWithClock(authCacheSize, clock),
+		authzCache: cache.NewLRUExpireCacheWithClock(authCacheSize, clock),
+		requests: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "kruise_state_metrics_auth_requests_total",
+			Help: "Number of requests to the metrics server by authentication and authorization result.",
+		}, []string{"result"}),
+	}
+	if config.Resource != "" {
+		gr := schema.ParseGroupResource(config.Resource)
+		if gr.Resource == "" {
+			return nil, errors.Errorf("invalid resource %q, must be resource.group", config.Resource)
+		}
+		a.resource = &gr
+	} else if config.NonResourceURL != "" && !strings.HasPrefix(config.NonResourceURL, "/") {
+		return nil, errors.Errorf("invalid non-resource URL %q, must start with /", config.NonResourceURL)
+	}
+
+	return a, nil
+}
+
+type authnResult struct {
+	authenticated bool
+	user          authenticationv1.UserInfo
+}
+
+// Handler only passes authenticated and authorized requests to next.
+func (a *authFilter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			a.requests.WithLabelValues("unauthenticated").Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kruise-state-metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		user, authenticated, err := a.authenticate(r.Context(), token)
+		if err != nil {
+			klog.Errorf("Failed to authenticate request to %s: %v", r.URL.Path, err)
+			a.requests.WithLabelValues("error").Inc()
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !authenticated {
+			a.requests.WithLabelValues("unauthenticated").Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kruise-state-metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		allowed, err := a.authorize(r.Context(), user, r)
+		if err != nil {
+			klog.Errorf("Failed to authorize %s for %s: %v", user.Username, r.URL.Path, err)
+			a.requests.WithLabelValues("error").Inc()
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			klog.V(2).Infof("Forbidden request of %s to %s", user.Username, r.URL.Path)
+			a.requests.WithLabelValues("forbidden").Inc()
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		a.requests.WithLabelValues("allowed").Inc()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *authFilter) authenticate(ctx context.Context, token string) (authenticationv1.UserInfo, bool, error) {
+	// Only keep a hash of the token in memory.
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if cached, ok := a.authnCache.Get(key); ok {
+		result := cached.(authnResult)
+		return result.user, result.authenticated, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+	review, err := a.kubeClient.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return authenticationv1.UserInfo{}, false, errors.Wrap(err, "create TokenReview")
+	}
+	if review.Status.Error != "" {
+		klog.V(2).Infof("TokenReview failed: %s", review.Status.Error)
+	}
+
+	result := authnResult{authenticated: review.Status.Authenticated, user: review.Status.User}
+	a.authnCache.Add(key, result, a.config.CacheTTL)
+	return result.user, result.authenticated, nil
+}
+
+func (a *authFilter) authorize(ctx context.Context, user authenticationv1.UserInfo, r *http.Request) (bool, error) {
+	spec := authorizationv1.SubjectAccessReviewSpec{
+		User:   user.Username,
+		UID:    user.UID,
+		Groups: user.Groups,
+	}
+	if len(user.Extra) > 0 {
+		spec.Extra = make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+		for k, v := range user.Extra {
+			spec.Extra[k] = authorizationv1.ExtraValue(v)
+		}
+	}
+	verb := requestVerb(r.Method)
+	if a.resource != nil {
+		spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace: a.config.Namespace,
+			Verb:      "get",
+			Group:     a.resource.Group,
+			Resource:  a.resource.Resource,
+		}
+	} else {
+		path := a.config.NonResourceURL
+		if path == "" {
+			path = r.URL.Path
+		}
+		spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{Path: path, Verb: verb}
+	}
+
+	key := authzCacheKey(spec)
+	if cached, ok := a.authzCache.Get(key); ok {
+		return cached.(bool), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+	review, err := a.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{Spec: spec}, metav1.CreateOptions{})
+	if err != nil {
+		return false, errors.Wrap(err, "create SubjectAccessReview")
+	}
+	if review.Status.EvaluationError != "" {
+		klog.V(2).Infof("SubjectAccessReview for %s failed: %s", user.Username, review.Status.EvaluationError)
+	}
+
+	a.authzCache.Add(key, review.Status.Allowed, a.config.CacheTTL)
+	return review.Status.Allowed, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := strings.TrimSpace(r.Header.Get("Authorization"))
+	scheme, token, found := strings.Cut(auth, " ")
+	if !found || !strings.EqualFold(scheme, "bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// requestVerb maps the HTTP method to the verb of a non-resource request.
+func requestVerb(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return "get"
+	case http.MethodPost:
+		return "post"
+	case http.MethodPut:
+		return "put"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return strings.ToLower(method)
+	}
+}
+
+func authzCacheKey(spec authorizationv1.SubjectAccessReviewSpec) string {
+	groups := append([]string(nil), spec.Groups...)
+	sort.Strings(groups)
+	extra := make([]string, 0, len(spec.Extra))
+	for k, v := range spec.Extra {
+		extra = append(extra, k+"="+strings.Join(v, ","))
+	}
+	sort.Strings(extra)
+
+	parts := []string{spec.User, spec.UID, strings.Join(groups, ","), strings.Join(extra, ";")}
+	if ra := spec.ResourceAttributes; ra != nil {
+		parts = append(parts, ra.Verb, ra.Namespace, ra.Group, ra.Resource)
+	} else if nra := spec.NonResourceAttributes; nra != nil {
+		parts = append(parts, nra.Verb, nra.Path)
+	}
+	return strings.Join(parts, "\x00")
+}
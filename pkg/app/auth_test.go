@@ -0,0 +1,186 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// fakeReviews answers TokenReviews for the tokens of users and allows
+// SubjectAccessReviews for the allowed attributes of a user.
+type fakeReviews struct {
+	users   map[string]string
+	allowed map[string]func(spec authorizationv1.SubjectAccessReviewSpec) bool
+
+	tokenReviews int
+	accessReview []authorizationv1.SubjectAccessReviewSpec
+	fail         bool
+}
+
+func (f *fakeReviews) client() *fake.Clientset {
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		f.tokenReviews++
+		if f.fail {
+			return true, nil, errors.New("apiserver unavailable")
+		}
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if user, ok := f.users[review.Spec.Token]; ok {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: user, Groups: []string{"system:authenticated"}}
+		}
+		return true, review, nil
+	})
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		f.accessReview = append(f.accessReview, review.Spec)
+		if allowed, ok := f.allowed[review.Spec.User]; ok {
+			review.Status.Allowed = allowed(review.Spec)
+		}
+		return true, review, nil
+	})
+	return kubeClient
+}
+
+func authRequest(h http.Handler, path, token string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAuthNonResourceURL(t *testing.T) {
+	reviews := &fakeReviews{
+		users: map[string]string{"prometheus-token": "prometheus", "other-token": "other"},
+		allowed: map[string]func(authorizationv1.SubjectAccessReviewSpec) bool{
+			"prometheus": func(spec authorizationv1.SubjectAccessReviewSpec) bool {
+				return spec.NonResourceAttributes.Path == metricsPath && spec.NonResourceAttributes.Verb == "get"
+			},
+		},
+	}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	auth, err := newAuthFilter(reviews.client(), authConfig{CacheTTL: time.Minute}, fakeClock, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("kruise_cloneset_created 1\n"))
+	})
+	mux := buildMetricsServer(metrics, prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test"}, []string{"method"}), auth.Handler, nil)
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{name: "healthz is not protected", path: healthzPath, want: http.StatusOK},
+		{name: "missing token", path: metricsPath, want: http.StatusUnauthorized},
+		{name: "unknown token", path: metricsPath, token: "unknown", want: http.StatusUnauthorized},
+		{name: "allowed", path: metricsPath, token: "prometheus-token", want: http.StatusOK},
+		{name: "index is protected", path: "/", token: "prometheus-token", want: http.StatusForbidden},
+		{name: "forbidden", path: metricsPath, token: "other-token", want: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := authRequest(mux, test.path, test.token); got != test.want {
+				t.Errorf("expected status %d, got %d", test.want, got)
+			}
+		})
+	}
+
+	// Cached results don't hit the apiserver.
+	tokenReviews, accessReviews := reviews.tokenReviews, len(reviews.accessReview)
+	if got := authRequest(mux, metricsPath, "prometheus-token"); got != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, got)
+	}
+	if got := authRequest(mux, metricsPath, "unknown"); got != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, got)
+	}
+	if reviews.tokenReviews != tokenReviews || len(reviews.accessReview) != accessReviews {
+		t.Errorf("expected cached reviews, got %d TokenReviews and %d SubjectAccessReviews", reviews.tokenReviews-tokenReviews, len(reviews.accessReview)-accessReviews)
+	}
+
+	// Expired results are reviewed again.
+	fakeClock.Step(2 * time.Minute)
+	if got := authRequest(mux, metricsPath, "prometheus-token"); got != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, got)
+	}
+	if reviews.tokenReviews != tokenReviews+1 || len(reviews.accessReview) != accessReviews+1 {
+		t.Errorf("expected expired reviews to be repeated")
+	}
+
+	// Errors are not cached.
+	reviews.fail = true
+	fakeClock.Step(2 * time.Minute)
+	if got := authRequest(mux, metricsPath, "prometheus-token"); got != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, got)
+	}
+	reviews.fail = false
+	if got := authRequest(mux, metricsPath, "prometheus-token"); got != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, got)
+	}
+}
+
+func TestAuthResource(t *testing.T) {
+	reviews := &fakeReviews{
+		users: map[string]string{"prometheus-token": "prometheus"},
+		allowed: map[string]func(authorizationv1.SubjectAccessReviewSpec) bool{
+			"prometheus": func(spec authorizationv1.SubjectAccessReviewSpec) bool { return true },
+		},
+	}
+	auth, err := newAuthFilter(reviews.client(), authConfig{
+		Resource:  "metrics.kruise-state-metrics.kruise.io",
+		Namespace: "kruise-system",
+		CacheTTL:  time.Minute,
+	}, clocktesting.NewFakeClock(time.Now()), prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := authRequest(auth.Handler(http.NotFoundHandler()), metricsPath, "prometheus-token"); got != http.StatusNotFound {
+		t.Errorf("expected the request to pass, got status %d", got)
+	}
+	want := authorizationv1.ResourceAttributes{
+		Namespace: "kruise-system",
+		Verb:      "get",
+		Group:     "kruise-state-metrics.kruise.io",
+		Resource:  "metrics",
+	}
+	if len(reviews.accessReview) != 1 || reviews.accessReview[0].ResourceAttributes == nil || *reviews.accessReview[0].ResourceAttributes != want {
+		t.Errorf("expected a SubjectAccessReview for %+v, got %+v", want, reviews.accessReview)
+	}
+
+	if _, err := newAuthFilter(reviews.client(), authConfig{NonResourceURL: "metrics"}, clocktesting.NewFakeClock(time.Now()), prometheus.NewRegistry()); err == nil {
+		t.Error("expected an error for a non-resource URL not starting with /")
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	debugResourcesPath     = "/debug/resources"
+	debugAllowDenyListPath = "/debug/allowdenylist"
+	debugShardingPath      = "/debug/sharding"
+	debugStoresPath        = "/debug/stores"
+)
+
+// debugInfo provides the state dumped by the debug server.
+type debugInfo struct {
+	resources         []string
+	allowDenyStatus   func() string
+	allowlist         map[string]struct{}
+	denylist          map[string]struct{}
+	sharding          func() (int32, int)
+	shardingAlgorithm string
+	objectCounts      func() map[string]int
+}
+
+type allowDenyListInfo struct {
+	Status    string   `json:"status"`
+	Allowlist []string `json:"allowlist"`
+	Denylist  []string `json:"denylist"`
+}
+
+type shardingInfo struct {
+	Shard       int32  `json:"shard"`
+	TotalShards int    `json:"totalShards"`
+	Algorithm   string `json:"algorithm"`
+}
+
+// buildDebugServer returns the mux of the debug server, serving pprof and the
+// internal state of kruise-state-metrics as JSON.
+func buildDebugServer(info debugInfo) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
+	mux.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+	mux.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
+	mux.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+
+	mux.HandleFunc(debugResourcesPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, info.resources)
+	})
+	mux.HandleFunc(debugAllowDenyListPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, allowDenyListInfo{
+			Status:    info.allowDenyStatus(),
+			Allowlist: sortedKeys(info.allowlist),
+			Denylist:  sortedKeys(info.denylist),
+		})
+	})
+	mux.HandleFunc(debugShardingPath, func(w http.ResponseWriter, r *http.Request) {
+		shard, totalShards := info.sharding()
+		writeJSON(w, shardingInfo{Shard: shard, TotalShards: totalShards, Algorithm: info.shardingAlgorithm})
+	})
+	mux.HandleFunc(debugStoresPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, info.objectCounts())
+	})
+
+	// Add index
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>
+             <head><title>Kruise-State-Metrics Debug Server</title></head>
+             <body>
+             <h1>Kruise-State-Metrics Debug</h1>
+			 <ul>
+             <li><a href='/debug/pprof/'>pprof</a></li>
+             <li><a href='` + debugResourcesPath + `'>resources</a></li>
+             <li><a href='` + debugAllowDenyListPath + `'>allowdenylist</a></li>
+             <li><a href='` + debugShardingPath + `'>sharding</a></li>
+             <li><a href='` + debugStoresPath + `'>stores</a></li>
+			 </ul>
+             </body>
+             </html>`))
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		klog.Errorf("Failed to write debug response: %v", err)
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestDebugServer(t *testing.T) {
+	mux := buildDebugServer(debugInfo{
+		resources:         []string{"clonesets", "statefulsets"},
+		allowDenyStatus:   func() string { return "Excluding the following lists that were on denylist: kruise_cloneset_labels" },
+		denylist:          map[string]struct{}{"kruise_cloneset_labels": {}, "kruise_.*_annotations": {}},
+		sharding:          func() (int32, int) { return 1, 3 },
+		shardingAlgorithm: "rendezvous",
+		objectCounts:      func() map[string]int { return map[string]int{"clonesets": 4, "statefulsets": 0} },
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: debugResourcesPath, want: `["clonesets","statefulsets"]`},
+		{path: debugAllowDenyListPath, want: `{"status":"Excluding the following lists that were on denylist: kruise_cloneset_labels","allowlist":[],"denylist":["kruise_.*_annotations","kruise_cloneset_labels"]}`},
+		{path: debugShardingPath, want: `{"shard":1,"totalShards":3,"algorithm":"rendezvous"}`},
+		{path: debugStoresPath, want: `{"clonesets":4,"statefulsets":0}`},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("expected a JSON response, got status %d and content type %q", rec.Code, rec.Header().Get("Content-Type"))
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(test.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected response (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("expected the debug server to serve pprof")
+	}
+}
+
+func TestMetricsServerWithoutPprof(t *testing.T) {
+	mux := buildMetricsServer(http.NotFoundHandler(), prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test"}, []string{"method"}), nil, nil)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("expected the metrics server not to serve pprof")
+	}
+}
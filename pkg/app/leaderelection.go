@@ -0,0 +1,156 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	klog "k8s.io/klog/v2"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+)
+
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// leaderElector campaigns for a Lease so that only one of several replicas
+// serves metrics. Followers keep their informers warm and take over as soon
+// as they acquire the Lease.
+type leaderElector struct {
+	config   leaderelection.LeaderElectionConfig
+	leader   atomic.Bool
+	isLeader prometheus.Gauge
+}
+
+func newLeaderElector(kubeClient clientset.Interface, namespace, name, identity string, r prometheus.Registerer) (*leaderElector, error) {
+	l := &leaderElector{
+		isLeader: promauto.With(r).NewGauge(prometheus.GaugeOpts{
+			Name: "kruise_state_metrics_leader",
+			Help: "Whether this instance is the leader serving metrics (1) or a follower (0).",
+		}),
+	}
+
+	config := leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Client:     kubeClient.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				klog.Infof("Acquired lease %s/%s, serving metrics", namespace, name)
+				l.setLeader(true)
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("Lost lease %s/%s, no longer serving metrics", namespace, name)
+				l.setLeader(false)
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					klog.Infof("Instance %s is the leader", current)
+				}
+			},
+		},
+	}
+	// Validate the configuration upfront, Run would panic otherwise.
+	if _, err := leaderelection.NewLeaderElector(config); err != nil {
+		return nil, err
+	}
+	l.config = config
+
+	return l, nil
+}
+
+// Run campaigns for the Lease until the context is done. After losing the
+// Lease it campaigns again rather than exiting, so that the instance stays
+// a warm follower.
+func (l *leaderElector) Run(ctx context.Context) error {
+	for {
+		le, err := leaderelection.NewLeaderElector(l.config)
+		if err != nil {
+			return err
+		}
+		le.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.config.RetryPeriod):
+		}
+	}
+}
+
+func (l *leaderElector) setLeader(leader bool) {
+	l.leader.Store(leader)
+	l.isLeader.Set(store.BoolFloat64(leader))
+}
+
+// IsLeader returns whether this instance currently holds the Lease.
+func (l *leaderElector) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// Handler only passes requests to next while this instance is the leader.
+// Followers answer without metrics, so that they are not reported as down
+// by Prometheus while the leader's series are not duplicated.
+func (l *leaderElector) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.IsLeader() {
+			contentType := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+			if contentType == expfmt.FmtOpenMetrics_1_0_0 || contentType == expfmt.FmtOpenMetrics_0_0_1 {
+				// An OpenMetrics exposition must be terminated even when empty.
+				w.Header().Set("Content-Type", string(contentType))
+				w.Write([]byte("# EOF\n"))
+				return
+			}
+			w.Header().Set("Content-Type", string(expfmt.FmtText))
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Gatherer only gathers from next while this instance is the leader, so
+// that followers do not push duplicate metrics.
+func (l *leaderElector) Gatherer(next prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		if !l.IsLeader() {
+			return nil, nil
+		}
+		return next.Gather()
+	})
+}
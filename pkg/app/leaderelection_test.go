@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func scrape(h http.Handler) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestLeaderElection(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("kruise_cloneset_created 1\n"))
+	})
+
+	newElector := func(identity string) (*leaderElector, *prometheus.Registry) {
+		registry := prometheus.NewRegistry()
+		l, err := newLeaderElector(kubeClient, "kruise-system", "kruise-state-metrics", identity, registry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l, registry
+	}
+	a, _ := newElector("a")
+	b, _ := newElector("b")
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	defer cancelA()
+	doneA := make(chan struct{})
+	go func() {
+		a.Run(ctxA)
+		close(doneA)
+	}()
+	waitForLeader(t, a)
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	go b.Run(ctxB)
+	waitForCampaign(t, kubeClient, 2)
+
+	if code, body := scrape(a.Handler(metrics)); code != http.StatusOK || body == "" {
+		t.Errorf("expected the leader to serve metrics, got %d %q", code, body)
+	}
+	if code, body := scrape(b.Handler(metrics)); code != http.StatusOK || body != "" {
+		t.Errorf("expected the follower to serve an empty body, got %d %q", code, body)
+	}
+	req := httptest.NewRequest(http.MethodGet, metricsPath, nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0")
+	rec := httptest.NewRecorder()
+	b.Handler(metrics).ServeHTTP(rec, req)
+	if body := rec.Body.String(); body != "# EOF\n" {
+		t.Errorf("expected the follower to serve an empty OpenMetrics exposition, got %q", body)
+	}
+	if v := testutil.ToFloat64(a.isLeader); v != 1 {
+		t.Errorf("expected leader gauge of 1 on the leader, got %v", v)
+	}
+	if v := testutil.ToFloat64(b.isLeader); v != 0 {
+		t.Errorf("expected leader gauge of 0 on the follower, got %v", v)
+	}
+
+	// The follower takes over once the leader releases the Lease.
+	cancelA()
+	<-doneA
+	if a.IsLeader() {
+		t.Errorf("expected the stopped instance to no longer be the leader")
+	}
+	waitForLeader(t, b)
+	if code, body := scrape(b.Handler(metrics)); code != http.StatusOK || body == "" {
+		t.Errorf("expected the new leader to serve metrics, got %d %q", code, body)
+	}
+}
+
+// waitForCampaign waits until the Lease has been read n times, that is until
+// n campaigns have started.
+func waitForCampaign(t *testing.T, kubeClient *fake.Clientset, n int) {
+	t.Helper()
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 3*leaseDuration, true, func(context.Context) (bool, error) {
+		gets := 0
+		for _, action := range kubeClient.Actions() {
+			if action.GetVerb() == "get" && action.GetResource().Resource == "leases" {
+				gets++
+			}
+		}
+		return gets >= n, nil
+	})
+	if err != nil {
+		t.Fatalf("expected %d campaigns for the lease", n)
+	}
+}
+
+func waitForLeader(t *testing.T, l *leaderElector) {
+	t.Helper()
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 50*time.Millisecond, 3*leaseDuration, true, func(context.Context) (bool, error) {
+		return l.IsLeader(), nil
+	})
+	if err != nil {
+		t.Fatalf("expected instance to become the leader")
+	}
+}
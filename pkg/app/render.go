@@ -0,0 +1,258 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	kruisescheme "github.com/openkruise/kruise-api/client/clientset/versioned/scheme"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/v2/pkg/allowdenylist"
+	"k8s.io/kube-state-metrics/v2/pkg/options"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+	localoptions "github.com/openkruise/kruise-state-metrics/pkg/options"
+)
+
+const (
+	// RenderCommand is the subcommand computing the metrics of manifests
+	// offline.
+	RenderCommand = "render"
+
+	renderFormatText        = "text"
+	renderFormatOpenMetrics = "openmetrics"
+)
+
+// Render writes the metrics of the Kruise objects in the manifests of
+// opts.Filenames to w, like they would be exposed on /metrics by an instance
+// with the same resources, namespaces and allow and deny lists. The samples
+// of every family are sorted so that the output is stable.
+func Render(opts *localoptions.Options, w io.Writer) error {
+	if len(opts.Filenames) == 0 {
+		return errors.New("no manifests, set them with -f")
+	}
+	openMetrics := false
+	switch opts.RenderFormat {
+	case renderFormatText:
+	case renderFormatOpenMetrics:
+		openMetrics = true
+	default:
+		return errors.Errorf("unknown format %q, must be %s or %s", opts.RenderFormat, renderFormatText, renderFormatOpenMetrics)
+	}
+
+	var objects []interface{}
+	for _, filename := range opts.Filenames {
+		o, err := readManifests(filename)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, o...)
+	}
+
+	storeBuilder := store.NewBuilder()
+	resources := localoptions.DefaultResources.AsSlice()
+	if len(opts.Resources) > 0 {
+		resources = opts.Resources.AsSlice()
+	}
+	// The events are counted from those watched in the cluster, they are not
+	// read from the manifests.
+	if slices.Contains(resources, "events") {
+		return errors.New("the events resource cannot be rendered, its metrics are counted from the events of the cluster")
+	}
+	if err := storeBuilder.WithEnabledResources(resources); err != nil {
+		return errors.Wrap(err, "set up resources")
+	}
+	if len(opts.Namespaces) == 0 {
+		storeBuilder.WithNamespaces(options.DefaultNamespaces)
+	} else {
+		storeBuilder.WithNamespaces(opts.Namespaces)
+	}
+	allowDenyList, err := allowdenylist.New(opts.MetricAllowlist, opts.MetricDenylist)
+	if err != nil {
+		return err
+	}
+	if err := allowDenyList.Parse(); err != nil {
+		return errors.Wrap(err, "initialize the allowdeny list")
+	}
+	storeBuilder.WithAllowDenyList(allowDenyList)
+	storeBuilder.WithAllowAnnotations(opts.AnnotationsAllowList)
+	storeBuilder.WithAllowLabels(opts.LabelsAllowList)
+	var errs []error
+	storeBuilder.WithKruiseStoresFunc(storeBuilder.StaticKruiseStoresFunc(objects, func(obj interface{}, err error) {
+		o, _ := meta.Accessor(obj)
+		errs = append(errs, errors.Wrapf(err, "%T %s, the manifest may lack fields defaulted by the apiserver", obj, strings.TrimPrefix(o.GetNamespace()+"/"+o.GetName(), "/")))
+	}), false)
+	writers := storeBuilder.Build()
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	buf := &bytes.Buffer{}
+	for _, writer := range writers {
+		if ow, ok := writer.(store.OpenMetricsWriter); ok && openMetrics {
+			ow.WriteAllOpenMetrics(buf)
+			continue
+		}
+		writer.WriteAll(buf)
+	}
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	_, err = w.Write(sortSamples(buf.Bytes()))
+	return err
+}
+
+// readManifests returns the Kruise objects in the YAML or JSON manifests of
+// a file, of the files of a directory and its subdirectories, or of stdin for
+// "-". Lists like the output of kubectl get -o yaml are flattened and objects
+// of other kinds are skipped.
+func readManifests(filename string) ([]interface{}, error) {
+	if filename == "-" {
+		return decodeManifests(os.Stdin, "stdin")
+	}
+
+	var objects []interface{}
+	err := filepath.WalkDir(filename, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		// Files given explicitly are read whatever their extension.
+		if path != filename {
+			switch filepath.Ext(path) {
+			case ".yaml", ".yml", ".json":
+			default:
+				return nil
+			}
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		o, err := decodeManifests(f, path)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, o...)
+		return nil
+	})
+	return objects, err
+}
+
+func decodeManifests(r io.Reader, source string) ([]interface{}, error) {
+	var objects []interface{}
+	decoder := yaml.NewYAMLOrJSONDecoder(bufio.NewReader(r), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); err != nil {
+			if err == io.EOF {
+				return objects, nil
+			}
+			return nil, errors.Wrapf(err, "decode %s", source)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+
+		items := []unstructured.Unstructured{*u}
+		if u.IsList() {
+			list, err := u.ToList()
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode list in %s", source)
+			}
+			items = list.Items
+		}
+		for i := range items {
+			o, err := convertManifest(&items[i])
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode %s", source)
+			}
+			if o != nil {
+				objects = append(objects, o)
+			}
+		}
+	}
+}
+
+// convertManifest returns the typed Kruise object of a manifest, nil if it
+// is not a Kruise object. Objects without UID, as usually written by hand,
+// get one derived from their kind, namespace and name.
+func convertManifest(u *unstructured.Unstructured) (runtime.Object, error) {
+	gvk := u.GroupVersionKind()
+	if !kruisescheme.Scheme.Recognizes(gvk) {
+		klog.V(2).Infof("Skipping %s %s/%s", gvk, u.GetNamespace(), u.GetName())
+		return nil, nil
+	}
+	o, err := kruisescheme.Scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, o); err != nil {
+		return nil, errors.Wrapf(err, "convert %s %s/%s", gvk.Kind, u.GetNamespace(), u.GetName())
+	}
+	if m, ok := o.(metav1.Object); ok && m.GetUID() == "" {
+		m.SetUID(types.UID(strings.Join([]string{gvk.Kind, m.GetNamespace(), m.GetName()}, "/")))
+	}
+	return o, nil
+}
+
+// sortSamples sorts the consecutive sample lines of the exposition, i.e. the
+// samples of every family, by labels. Samples with the same labels, like the
+// _total and _created samples of an OpenMetrics counter, stay together.
+func sortSamples(exposition []byte) []byte {
+	lines := strings.SplitAfter(string(exposition), "\n")
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && lines[i] != "" && !strings.HasPrefix(lines[i], "#") {
+			continue
+		}
+		samples := lines[start:i]
+		sort.SliceStable(samples, func(a, b int) bool {
+			return sampleLabels(samples[a]) < sampleLabels(samples[b])
+		})
+		start = i + 1
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// sampleLabels returns the labels of a sample line, {...} included.
+func sampleLabels(line string) string {
+	start := strings.IndexByte(line, '{')
+	end := strings.LastIndexByte(line, '}')
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start : end+1]
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/kube-state-metrics/v2/pkg/options"
+
+	localoptions "github.com/openkruise/kruise-state-metrics/pkg/options"
+)
+
+const (
+	renderCloneSets = `apiVersion: apps.kruise.io/v1alpha1
+kind: CloneSet
+metadata:
+  name: web
+  namespace: default
+spec:
+  replicas: 3
+status:
+  replicas: 3
+  updatedReadyReplicas: 2
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+  namespace: default
+---
+apiVersion: apps.kruise.io/v1alpha1
+kind: CloneSet
+metadata:
+  name: api
+  namespace: other
+spec:
+  replicas: 1
+status:
+  replicas: 1
+  updatedReadyReplicas: 1
+`
+	renderList = `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {
+      "apiVersion": "apps.kruise.io/v1alpha1",
+      "kind": "CloneSet",
+      "metadata": {"name": "worker", "namespace": "default", "uid": "3e9d7a8e"},
+      "spec": {"replicas": 5},
+      "status": {"replicas": 5, "updatedReadyReplicas": 5}
+    }
+  ]
+}`
+)
+
+func writeManifest(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// renderedFamily returns the sample lines of a family in the exposition.
+func renderedFamily(exposition, family string) []string {
+	var samples []string
+	for _, line := range strings.Split(exposition, "\n") {
+		if strings.HasPrefix(line, family+"{") {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, filepath.Join(dir, "clonesets.yaml"), renderCloneSets)
+	writeManifest(t, filepath.Join(dir, "dump", "list.json"), renderList)
+	writeManifest(t, filepath.Join(dir, "README.md"), "not a manifest")
+
+	opts := localoptions.NewOptions()
+	opts.Filenames = []string{dir}
+	opts.RenderFormat = "text"
+	opts.Resources = options.ResourceSet{"clonesets": struct{}{}}
+	opts.MetricDenylist = options.MetricSet{"kruise_cloneset_labels": struct{}{}}
+
+	out := &bytes.Buffer{}
+	if err := Render(opts, out); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`kruise_cloneset_status_replicas_updated_ready{namespace="default",cloneset="web"} 2`,
+		`kruise_cloneset_status_replicas_updated_ready{namespace="default",cloneset="worker"} 5`,
+		`kruise_cloneset_status_replicas_updated_ready{namespace="other",cloneset="api"} 1`,
+	}
+	if diff := cmp.Diff(want, renderedFamily(out.String(), "kruise_cloneset_status_replicas_updated_ready")); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+	if strings.Contains(out.String(), "kruise_cloneset_labels") {
+		t.Error("expected the denylisted family not to be rendered")
+	}
+
+	// Namespaces and the OpenMetrics format are honored.
+	opts.Namespaces = options.NamespaceList{"other"}
+	opts.RenderFormat = "openmetrics"
+	out.Reset()
+	if err := Render(opts, out); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{`kruise_cloneset_status_replicas_updated_ready{namespace="other",cloneset="api"} 1`}
+	if diff := cmp.Diff(want, renderedFamily(out.String(), "kruise_cloneset_status_replicas_updated_ready")); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+	if !strings.HasSuffix(out.String(), "# EOF\n") {
+		t.Error("expected the OpenMetrics exposition to be terminated by # EOF")
+	}
+
+	opts.RenderFormat = "json"
+	if err := Render(opts, out); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	// The summary is computed from the workloads of the manifests.
+	opts.Namespaces = nil
+	opts.RenderFormat = "text"
+	opts.Resources = options.ResourceSet{"clonesets": struct{}{}, "workloadssummary": struct{}{}}
+	out.Reset()
+	if err := Render(opts, out); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		`kruise_workloads_summary_replicas_desired{kind="CloneSet",namespace="default"} 8`,
+		`kruise_workloads_summary_replicas_desired{kind="CloneSet",namespace="other"} 1`,
+	}
+	if diff := cmp.Diff(want, renderedFamily(out.String(), "kruise_workloads_summary_replicas_desired")); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+
+	// The SLOs are computed from the workloads of the manifests, and need
+	// their resources.
+	writeManifest(t, filepath.Join(dir, "slo.yaml"), `apiVersion: apps.kruise.io/v1alpha1
+kind: CloneSet
+metadata:
+  name: slo
+  namespace: default
+  annotations:
+    metrics.kruise.io/availability-target: "0.9"
+    metrics.kruise.io/availability-windows: "1h"
+spec:
+  replicas: 4
+status:
+  replicas: 4
+  readyReplicas: 2
+`)
+	opts.Resources = options.ResourceSet{"clonesets": struct{}{}, "workloadslos": struct{}{}}
+	out.Reset()
+	if err := Render(opts, out); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{`kruise_workload_availability_ratio{kind="CloneSet",name="slo",namespace="default",window="1h"} 0.5`}
+	if diff := cmp.Diff(want, renderedFamily(out.String(), "kruise_workload_availability_ratio")); diff != "" {
+		t.Errorf("unexpected samples (-want, +got):\n%s", diff)
+	}
+	opts.Resources = options.ResourceSet{"workloadslos": struct{}{}}
+	if err := Render(opts, out); err == nil {
+		t.Error("expected an error for the SLOs without the resources of the workloads")
+	}
+
+	// The events are only counted in the cluster.
+	opts.Resources = options.ResourceSet{"clonesets": struct{}{}, "events": struct{}{}}
+	if err := Render(opts, out); err == nil || !strings.Contains(err.Error(), "events") {
+		t.Errorf("expected an error for the events resource, got %v", err)
+	}
+	// The metric generators expect the fields defaulted by the apiserver.
+	opts.Resources = options.ResourceSet{"sidecarsets": struct{}{}}
+	writeManifest(t, filepath.Join(dir, "sidecarset.yaml"), "apiVersion: apps.kruise.io/v1alpha1\nkind: SidecarSet\nmetadata:\n  name: log\n")
+	if err := Render(opts, out); err == nil || !strings.Contains(err.Error(), "SidecarSet log") {
+		t.Errorf("expected an error for the SidecarSet without defaults, got %v", err)
+	}
+	writeManifest(t, filepath.Join(dir, "invalid.yaml"), "apiVersion: apps.kruise.io/v1alpha1\nkind: CloneSet\nspec: [")
+	if err := Render(opts, out); err == nil {
+		t.Error("expected an error for an invalid manifest")
+	}
+}
+
+func TestSortSamples(t *testing.T) {
+	exposition := `# HELP kruise_cloneset_restarts Test.
+# TYPE kruise_cloneset_restarts counter
+kruise_cloneset_restarts_total{cloneset="b"} 1
+kruise_cloneset_restarts_created{cloneset="b"} 10
+kruise_cloneset_restarts_total{cloneset="a"} 2
+kruise_cloneset_restarts_created{cloneset="a"} 20
+# HELP kruise_cloneset_replicas Test.
+# TYPE kruise_cloneset_replicas gauge
+kruise_cloneset_replicas{cloneset="b"} 1
+kruise_cloneset_replicas{cloneset="a"} 2
+# EOF
+`
+	want := `# HELP kruise_cloneset_restarts Test.
+# TYPE kruise_cloneset_restarts counter
+kruise_cloneset_restarts_total{cloneset="a"} 2
+kruise_cloneset_restarts_created{cloneset="a"} 20
+kruise_cloneset_restarts_total{cloneset="b"} 1
+kruise_cloneset_restarts_created{cloneset="b"} 10
+# HELP kruise_cloneset_replicas Test.
+# TYPE kruise_cloneset_replicas gauge
+kruise_cloneset_replicas{cloneset="a"} 2
+kruise_cloneset_replicas{cloneset="b"} 1
+# EOF
+`
+	if diff := cmp.Diff(want, string(sortSamples([]byte(exposition)))); diff != "" {
+		t.Errorf("unexpected exposition (-want, +got):\n%s", diff)
+	}
+}
@@ -20,7 +20,7 @@ import (
 	"context"
 	"net"
 	"net/http"
-	"net/http/pprof"
+	"os"
 	"strconv"
 	"time"
 
@@ -35,16 +35,20 @@ import (
 	"github.com/prometheus/exporter-toolkit/web"
 	vpaclientset "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/clientset/versioned"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	klog "k8s.io/klog/v2"
 	"k8s.io/kube-state-metrics/v2/pkg/allowdenylist"
-	"k8s.io/kube-state-metrics/v2/pkg/metricshandler"
 	"k8s.io/kube-state-metrics/v2/pkg/options"
 	"k8s.io/kube-state-metrics/v2/pkg/util/proc"
+	"k8s.io/utils/clock"
 
 	"github.com/openkruise/kruise-state-metrics/internal/store"
+	"github.com/openkruise/kruise-state-metrics/pkg/metricshandler"
 	localoptions "github.com/openkruise/kruise-state-metrics/pkg/options"
+	"github.com/openkruise/kruise-state-metrics/pkg/otlp"
+	"github.com/openkruise/kruise-state-metrics/pkg/remotewrite"
 )
 
 const (
@@ -66,7 +70,7 @@ func (pl promLogger) Log(v ...interface{}) error {
 }
 
 // RunKruiseStateMetrics will build and run the kruise-state-metrics.
-func RunKruiseStateMetrics(ctx context.Context, opts *options.Options) error {
+func RunKruiseStateMetrics(ctx context.Context, opts *localoptions.Options) error {
 	promLogger := promLogger{}
 
 	storeBuilder := store.NewBuilder()
@@ -96,6 +100,24 @@ func RunKruiseStateMetrics(ctx context.Context, opts *options.Options) error {
 		klog.Fatalf("Failed to set up resources: %v", err)
 	}
 
+	for _, r := range resources {
+		if selector, ok := opts.ResourceLabelSelectors[r]; ok {
+			klog.Infof("Using label selector %q for %s", selector, r)
+		}
+		if selector, ok := opts.ResourceFieldSelectors[r]; ok {
+			klog.Infof("Using field selector %q for %s", selector, r)
+		}
+	}
+	if selector, ok := opts.ResourceLabelSelectors[store.ResourceWildcard]; ok {
+		klog.Infof("Using label selector %q for all other resources", selector)
+	}
+	if selector, ok := opts.ResourceFieldSelectors[store.ResourceWildcard]; ok {
+		klog.Infof("Using field selector %q for all other resources", selector)
+	}
+	if err := storeBuilder.WithResourceSelectors(opts.ResourceLabelSelectors, opts.ResourceFieldSelectors); err != nil {
+		klog.Fatalf("Failed to set up resource selectors: %v", err)
+	}
+
 	if len(opts.Namespaces) == 0 {
 		klog.Info("Using all namespace")
 		storeBuilder.WithNamespaces(options.DefaultNamespaces)
@@ -108,6 +130,13 @@ func RunKruiseStateMetrics(ctx context.Context, opts *options.Options) error {
 		storeBuilder.WithNamespaces(opts.Namespaces)
 	}
 
+	if opts.NamespaceSelector != "" {
+		klog.Infof("Using namespaces matching selector %q", opts.NamespaceSelector)
+	}
+	if err := storeBuilder.WithNamespaceSelector(opts.NamespaceSelector); err != nil {
+		klog.Fatalf("Failed to set up namespace selector: %v", err)
+	}
+
 	allowDenyList, err := allowdenylist.New(opts.MetricAllowlist, opts.MetricDenylist)
 	if err != nil {
 		klog.Fatal(err)
@@ -138,12 +167,36 @@ func RunKruiseStateMetrics(ctx context.Context, opts *options.Options) error {
 	if err != nil {
 		klog.Fatalf("Failed to create kruise client: %v", err)
 	}
+	metadataClient, err := createMetadataClient(cfg)
+	if err != nil {
+		klog.Fatalf("Failed to create metadata client: %v", err)
+	}
 	storeBuilder.WithKubeClient(kubeClient)
 	storeBuilder.WithKruiseClient(kruiseClient)
+	storeBuilder.WithMetadataClient(metadataClient)
 	storeBuilder.WithVPAClient(vpaClient)
 	storeBuilder.WithSharding(opts.Shard, opts.TotalShards)
+	if err := storeBuilder.WithShardingAlgorithm(opts.ShardingAlgorithm); err != nil {
+		klog.Fatalf("Failed to set up sharding: %v", err)
+	}
 	storeBuilder.WithAllowAnnotations(opts.AnnotationsAllowList)
 	storeBuilder.WithAllowLabels(opts.LabelsAllowList)
+	if opts.FamilySeriesLimit > 0 || opts.TotalSeriesLimit > 0 || len(opts.FamilySeriesLimits) > 0 {
+		klog.Infof("Limiting series to %d per family (overrides: %s) and %d in total", opts.FamilySeriesLimit, opts.FamilySeriesLimits.String(), opts.TotalSeriesLimit)
+	}
+	storeBuilder.WithSeriesLimits(opts.FamilySeriesLimit, opts.FamilySeriesLimits, opts.TotalSeriesLimit)
+	storeBuilder.WithState(opts.EnableStateAPI || opts.EnableWatchAPI)
+	storeBuilder.WithPodMetrics(opts.EnablePodMetrics)
+	storeBuilder.WithEventReasons(opts.EventReasons)
+	storeBuilder.WithConditionReasons(opts.ConditionReasons)
+	var watcher *metricshandler.Watcher
+	if opts.EnableWatchAPI {
+		if opts.WatchBufferSize <= 0 {
+			klog.Fatalf("Invalid --watch-buffer-size %d, must be positive", opts.WatchBufferSize)
+		}
+		watcher = metricshandler.NewWatcher(opts.WatchBufferSize, ksmMetricsRegistry)
+		storeBuilder.WithStateListener(watcher.OnStateChange)
+	}
 
 	ksmMetricsRegistry.MustRegister(
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
@@ -155,6 +208,7 @@ func RunKruiseStateMetrics(ctx context.Context, opts *options.Options) error {
 	m := metricshandler.New(
 		opts,
 		kubeClient,
+		kruiseClient,
 		storeBuilder,
 		opts.EnableGZIPEncoding,
 	)
@@ -174,10 +228,135 @@ func RunKruiseStateMetrics(ctx context.Context, opts *options.Options) error {
 	telemetryListenAddress := net.JoinHostPort(opts.TelemetryHost, strconv.Itoa(opts.TelemetryPort))
 	telemetryServer := http.Server{Handler: telemetryMux, Addr: telemetryListenAddress}
 
-	metricsMux := buildMetricsServer(m, durationVec)
+	var metricsHandler http.Handler = m
+	var gatherer prometheus.Gatherer = m
+	if opts.LeaderElect {
+		identity := opts.Pod
+		if identity == "" {
+			identity, err = os.Hostname()
+			if err != nil {
+				klog.Fatalf("Failed to get hostname for leader election: %v", err)
+			}
+		}
+		elector, err := newLeaderElector(kubeClient, opts.LeaderElectNamespace, opts.LeaderElectName, identity, ksmMetricsRegistry)
+		if err != nil {
+			klog.Fatalf("Failed to set up leader election: %v", err)
+		}
+		klog.Infof("Leader election enabled with lease %s/%s and identity %s", opts.LeaderElectNamespace, opts.LeaderElectName, identity)
+		metricsHandler = elector.Handler(m)
+		gatherer = elector.Gatherer(m)
+
+		// Run leader election
+		ctxLeaderElection, cancel := context.WithCancel(ctx)
+		g.Add(func() error {
+			return elector.Run(ctxLeaderElection)
+		}, func(error) {
+			cancel()
+		})
+	}
+
+	if opts.OTLPEndpoint != "" {
+		exporter, err := otlp.New(otlp.Config{
+			Endpoint:    opts.OTLPEndpoint,
+			Protocol:    opts.OTLPProtocol,
+			Insecure:    opts.OTLPInsecure,
+			Headers:     opts.OTLPHeaders,
+			Interval:    opts.OTLPInterval,
+			Timeout:     opts.OTLPTimeout,
+			ClusterName: opts.ClusterName,
+		}, gatherer, m.Sharding, ksmMetricsRegistry)
+		if err != nil {
+			klog.Fatalf("Failed to set up OTLP exporter: %v", err)
+		}
+
+		// Run OTLP exporter
+		ctxExporter, cancel := context.WithCancel(ctx)
+		g.Add(func() error {
+			return exporter.Run(ctxExporter)
+		}, func(error) {
+			cancel()
+		})
+	}
+
+	if opts.RemoteWriteURL != "" {
+		sender, err := remotewrite.New(remotewrite.Config{
+			URL:             opts.RemoteWriteURL,
+			Username:        opts.RemoteWriteUsername,
+			PasswordFile:    opts.RemoteWritePasswordFile,
+			BearerTokenFile: opts.RemoteWriteBearerTokenFile,
+			ExternalLabels:  opts.RemoteWriteExternalLabels,
+			Interval:        opts.RemoteWriteInterval,
+			Timeout:         opts.RemoteWriteTimeout,
+			QueueMaxSamples: opts.RemoteWriteQueueMaxSamples,
+		}, gatherer, ksmMetricsRegistry)
+		if err != nil {
+			klog.Fatalf("Failed to set up remote write: %v", err)
+		}
+
+		// Run remote write sender
+		ctxSender, cancel := context.WithCancel(ctx)
+		g.Add(func() error {
+			return sender.Run(ctxSender)
+		}, func(error) {
+			cancel()
+		})
+	}
+
+	var protect func(http.Handler) http.Handler
+	if opts.EnableAuth {
+		auth, err := newAuthFilter(kubeClient, authConfig{
+			NonResourceURL: opts.AuthNonResourceURL,
+			Resource:       opts.AuthResource,
+			Namespace:      opts.AuthResourceNamespace,
+			CacheTTL:       opts.AuthCacheTTL,
+		}, clock.RealClock{}, ksmMetricsRegistry)
+		if err != nil {
+			klog.Fatalf("Failed to set up authentication: %v", err)
+		}
+		klog.Info("Authenticating and authorizing requests to the metrics server")
+		protect = auth.Handler
+	}
+
+	apiHandlers := map[string]http.Handler{}
+	if opts.EnableStateAPI {
+		klog.Infof("Serving the state of the objects as JSON on %s", metricshandler.StatePath)
+		apiHandlers[metricshandler.StatePath] = m.StateHandler()
+	}
+	if watcher != nil {
+		klog.Infof("Streaming the state changes of the objects on %s", metricshandler.WatchPath)
+		apiHandlers[metricshandler.WatchPath] = m.WatchHandler(watcher)
+	}
+
+	metricsMux := buildMetricsServer(metricsHandler, durationVec, protect, apiHandlers)
 	metricsServerListenAddress := net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port))
 	metricsServer := http.Server{Handler: metricsMux, Addr: metricsServerListenAddress}
 
+	// Run Debug server
+	if opts.DebugPort > 0 {
+		debugMux := buildDebugServer(debugInfo{
+			resources:         resources,
+			allowDenyStatus:   allowDenyList.Status,
+			allowlist:         opts.MetricAllowlist,
+			denylist:          opts.MetricDenylist,
+			sharding:          m.Sharding,
+			shardingAlgorithm: opts.ShardingAlgorithm,
+			objectCounts:      storeBuilder.ObjectCounts,
+		})
+		debugListenAddress := net.JoinHostPort(opts.DebugHost, strconv.Itoa(opts.DebugPort))
+		var debugHandler http.Handler = debugMux
+		if protect != nil {
+			debugHandler = protect(debugMux)
+		}
+		debugServer := http.Server{Handler: debugHandler, Addr: debugListenAddress}
+		g.Add(func() error {
+			klog.Infof("Starting debug server: %s", debugListenAddress)
+			return web.ListenAndServe(&debugServer, tlsConfig, promLogger)
+		}, func(error) {
+			ctxShutDown, cancel := context.WithTimeout(ctx, 3*time.Second)
+			defer cancel()
+			debugServer.Shutdown(ctxShutDown)
+		})
+	}
 	// Run Telemetry server
 	{
 		g.Add(func() error {
@@ -246,6 +425,13 @@ func createKruiseClient(cfg *rest.Config) (kruiseclientset.Interface, error) {
 	return kruiseclientset.NewForConfig(config)
 }
 
+func createMetadataClient(cfg *rest.Config) (metadata.Interface, error) {
+	config := rest.CopyConfig(cfg)
+	config.UserAgent = version.Version
+
+	return metadata.NewForConfig(config)
+}
+
 func buildTelemetryServer(registry prometheus.Gatherer) *http.ServeMux {
 	mux := http.NewServeMux()
 
@@ -266,17 +452,19 @@ func buildTelemetryServer(registry prometheus.Gatherer) *http.ServeMux {
 	return mux
 }
 
-func buildMetricsServer(m *metricshandler.MetricsHandler, durationObserver prometheus.ObserverVec) *http.ServeMux {
+// buildMetricsServer returns the mux of the metrics server, serving the
+// apiHandlers by path next to the metrics. All handlers except healthz are
+// wrapped with protect unless it is nil.
+func buildMetricsServer(m http.Handler, durationObserver prometheus.ObserverVec, protect func(http.Handler) http.Handler, apiHandlers map[string]http.Handler) *http.ServeMux {
 	mux := http.NewServeMux()
+	if protect == nil {
+		protect = func(h http.Handler) http.Handler { return h }
+	}
 
-	// TODO: This doesn't belong into serveMetrics
-	mux.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
-	mux.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
-	mux.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
-	mux.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
-	mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
-
-	mux.Handle(metricsPath, promhttp.InstrumentHandlerDuration(durationObserver, m))
+	mux.Handle(metricsPath, protect(promhttp.InstrumentHandlerDuration(durationObserver, m)))
+	for path, h := range apiHandlers {
+		mux.Handle(path, protect(h))
+	}
 
 	// Add healthzPath
 	mux.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
@@ -284,7 +472,7 @@ func buildMetricsServer(m *metricshandler.MetricsHandler, durationObserver prome
 		w.Write([]byte(http.StatusText(http.StatusOK)))
 	})
 	// Add index
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/", protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
              <head><title>Kube Metrics Server</title></head>
              <body>
@@ -295,6 +483,6 @@ func buildMetricsServer(m *metricshandler.MetricsHandler, durationObserver prome
 			 </ul>
              </body>
              </html>`))
-	})
+	})))
 	return mux
 }
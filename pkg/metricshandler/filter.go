@@ -0,0 +1,102 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	metricsstore "k8s.io/kube-state-metrics/v2/pkg/metrics_store"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+)
+
+const (
+	resourceParam  = "resource"
+	familyParam    = "family"
+	namespaceParam = "namespace"
+)
+
+// parseFilter returns the filter selected by the query parameters:
+//
+//   - resource: comma-separated or repeated list of resources.
+//   - family: regular expression matching the whole family name, repeated
+//     expressions are alternatives.
+//   - namespace: namespace of the objects.
+//
+// They are validated against the resources and families of the writers.
+func parseFilter(query url.Values, writers []metricsstore.MetricsWriter) (store.Filter, error) {
+	filter := store.Filter{}
+
+	available := map[string][]string{}
+	for _, w := range writers {
+		if fw, ok := w.(store.FilterableWriter); ok {
+			available[fw.Resource()] = append(available[fw.Resource()], fw.FamilyNames()...)
+		}
+	}
+
+	for _, value := range query[resourceParam] {
+		for _, resource := range strings.Split(value, ",") {
+			resource = strings.TrimSpace(resource)
+			if resource == "" {
+				continue
+			}
+			if _, ok := available[resource]; !ok {
+				return filter, errors.Errorf("unknown resource %q", resource)
+			}
+			if filter.Resources == nil {
+				filter.Resources = map[string]struct{}{}
+			}
+			filter.Resources[resource] = struct{}{}
+		}
+	}
+
+	if expressions := query[familyParam]; len(expressions) > 0 {
+		family, err := regexp.Compile("^(?:" + strings.Join(expressions, "|") + ")$")
+		if err != nil {
+			return filter, errors.Wrapf(err, "invalid %s", familyParam)
+		}
+		filter.Family = family
+
+		matched := false
+		for resource, families := range available {
+			if len(filter.Resources) > 0 {
+				if _, ok := filter.Resources[resource]; !ok {
+					continue
+				}
+			}
+			for _, name := range families {
+				matched = matched || family.MatchString(name)
+			}
+		}
+		if !matched {
+			return filter, errors.Errorf("%s %q matches no metric family", familyParam, strings.Join(expressions, "|"))
+		}
+	}
+
+	if namespace := query.Get(namespaceParam); namespace != "" {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return filter, errors.Errorf("invalid %s %q: %s", namespaceParam, namespace, strings.Join(errs, ", "))
+		}
+		filter.Namespace = namespace
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	klog "k8s.io/klog/v2"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+)
+
+const (
+	counterSuffix = "_total"
+	createdSuffix = "_created"
+)
+
+var _ prometheus.Gatherer = &MetricsHandler{}
+
+// Gather implements the prometheus.Gatherer interface, it returns the metric
+// families currently exposed on /metrics, e.g. for pushing them elsewhere.
+// Families which are not valid Prometheus text format are left out, so that
+// a single broken family does not prevent the others from being gathered.
+// The counters carry the start time of their _created sample in the
+// OpenMetrics format, if any.
+func (m *MetricsHandler) Gather() ([]*dto.MetricFamily, error) {
+	buf := &bytes.Buffer{}
+	openMetrics := &bytes.Buffer{}
+	m.mtx.RLock()
+	for _, w := range m.metricsWriters {
+		w.WriteAll(buf)
+		if ow, ok := w.(store.OpenMetricsWriter); ok {
+			ow.WriteAllOpenMetrics(openMetrics)
+		}
+	}
+	m.mtx.RUnlock()
+
+	var result []*dto.MetricFamily
+	for _, block := range splitFamilies(buf.String()) {
+		families, err := new(expfmt.TextParser).TextToMetricFamilies(strings.NewReader(block))
+		if err != nil {
+			if _, warned := m.invalidFamilies.LoadOrStore(familyName(block), true); !warned {
+				klog.Warningf("Leaving out invalid metric family %s from gathered metrics: %v", familyName(block), err)
+			}
+			continue
+		}
+		for _, f := range families {
+			if len(f.Metric) > 0 {
+				result = append(result, f)
+			}
+		}
+	}
+	setCreatedTimestamps(result, createdTimestamps(openMetrics.String()))
+
+	return result, nil
+}
+
+// createdTimestamps returns the values of the _created samples of the
+// OpenMetrics format, indexed by seriesKey of the series they are created
+// for without the suffix. The samples which are not valid are left out.
+func createdTimestamps(openMetrics string) map[string]float64 {
+	var names []string
+	lines := map[string][]string{}
+	for _, line := range strings.Split(openMetrics, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.SplitN(line, " ", 2)[0], "{")
+		if !strings.HasSuffix(name, createdSuffix) {
+			continue
+		}
+		if _, ok := lines[name]; !ok {
+			names = append(names, name)
+		}
+		lines[name] = append(lines[name], line)
+	}
+
+	created := map[string]float64{}
+	for _, name := range names {
+		families, err := new(expfmt.TextParser).TextToMetricFamilies(strings.NewReader(strings.Join(lines[name], "\n") + "\n"))
+		if err != nil {
+			klog.V(2).Infof("Leaving out the created timestamps of %s from gathered metrics: %v", name, err)
+			continue
+		}
+		for _, f := range families {
+			for _, metric := range f.Metric {
+				created[seriesKey(strings.TrimSuffix(name, createdSuffix), metric.Label)] = metric.GetUntyped().GetValue()
+			}
+		}
+	}
+	return created
+}
+
+// setCreatedTimestamps sets the created timestamp of the counters which have
+// a _created sample.
+func setCreatedTimestamps(families []*dto.MetricFamily, created map[string]float64) {
+	if len(created) == 0 {
+		return
+	}
+	for _, f := range families {
+		if f.GetType() != dto.MetricType_COUNTER {
+			continue
+		}
+		name := strings.TrimSuffix(f.GetName(), counterSuffix)
+		for _, metric := range f.Metric {
+			if ts, ok := created[seriesKey(name, metric.Label)]; ok && metric.Counter != nil {
+				sec, frac := math.Modf(ts)
+				metric.Counter.CreatedTimestamp = timestamppb.New(time.Unix(int64(sec), int64(frac*1e9)))
+			}
+		}
+	}
+}
+
+// seriesKey identifies a series by its name and its labels in any order.
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"="+strconv.Quote(l.GetValue()))
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Sharding returns the shard of this instance and the total number of shards.
+func (m *MetricsHandler) Sharding() (int32, int) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.curShard, m.curTotalShards
+}
+
+// splitFamilies splits the Prometheus text format at the HELP line of every
+// metric family.
+func splitFamilies(s string) []string {
+	var blocks []string
+	for len(s) > 0 {
+		next := strings.Index(s[1:], "\n# HELP ")
+		if next < 0 {
+			blocks = append(blocks, s)
+			break
+		}
+		blocks = append(blocks, s[:next+2])
+		s = s[next+2:]
+	}
+	return blocks
+}
+
+func familyName(block string) string {
+	fields := strings.Fields(strings.TrimPrefix(block, "# HELP "))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
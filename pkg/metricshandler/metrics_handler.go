@@ -0,0 +1,349 @@
+/*
+Copyright 2026 The Kruise Authors.
+Copyright 2018 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/expfmt"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+	ksmtypes "k8s.io/kube-state-metrics/v2/pkg/builder/types"
+	metricsstore "k8s.io/kube-state-metrics/v2/pkg/metrics_store"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+	"github.com/openkruise/kruise-state-metrics/pkg/options"
+)
+
+// MetricsHandler is a http.Handler that exposes the main kruise-state-metrics
+// /metrics endpoint. It allows concurrent reconfiguration at runtime.
+type MetricsHandler struct {
+	opts               *options.Options
+	kubeClient         kubernetes.Interface
+	kruiseClient       kruiseclientset.Interface
+	storeBuilder       ksmtypes.BuilderInterface
+	enableGZIPEncoding bool
+
+	cancel func()
+
+	// mtx protects metricsWriters, curShard, and curTotalShards
+	mtx            *sync.RWMutex
+	metricsWriters []metricsstore.MetricsWriter
+	curShard       int32
+	curTotalShards int
+
+	// invalidFamilies holds the families left out by Gather, which are only
+	// logged once.
+	invalidFamilies sync.Map
+}
+
+// New creates and returns a new MetricsHandler with the given options.
+func New(opts *options.Options, kubeClient kubernetes.Interface, kruiseClient kruiseclientset.Interface, storeBuilder ksmtypes.BuilderInterface, enableGZIPEncoding bool) *MetricsHandler {
+	return &MetricsHandler{
+		opts:               opts,
+		kubeClient:         kubeClient,
+		kruiseClient:       kruiseClient,
+		storeBuilder:       storeBuilder,
+		enableGZIPEncoding: enableGZIPEncoding,
+		mtx:                &sync.RWMutex{},
+	}
+}
+
+// ConfigureSharding (re-)configures sharding. Re-configuration can be done
+// concurrently.
+func (m *MetricsHandler) ConfigureSharding(ctx context.Context, shard int32, totalShards int) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if totalShards != 1 {
+		klog.Infof("configuring sharding of this instance to be shard index %d (zero-indexed) out of %d total shards", shard, totalShards)
+	}
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.storeBuilder.WithSharding(shard, totalShards)
+	m.storeBuilder.WithContext(ctx)
+	m.metricsWriters = m.storeBuilder.Build()
+	m.curShard = shard
+	m.curTotalShards = totalShards
+}
+
+// Run configures the MetricsHandler's sharding and if autosharding is enabled
+// re-configures sharding on re-sharding events. Run should only be called
+// once.
+//
+// Autosharding derives the shard from the ordinal of the pod and the total
+// number of shards from the replicas of the StatefulSet owning the pod, which
+// can either be a Kubernetes StatefulSet or a Kruise Advanced StatefulSet.
+func (m *MetricsHandler) Run(ctx context.Context) error {
+	autoSharding := len(m.opts.Pod) > 0 && len(m.opts.Namespace) > 0
+
+	if !autoSharding {
+		klog.Info("Autosharding disabled")
+		m.ConfigureSharding(ctx, m.opts.Shard, m.opts.TotalShards)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	klog.Infof("Autosharding enabled with pod=%v pod_namespace=%v", m.opts.Pod, m.opts.Namespace)
+	klog.Infof("Auto detecting sharding settings.")
+	owner, err := detectStatefulSet(ctx, m.kubeClient, m.opts.Pod, m.opts.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "detect StatefulSet")
+	}
+
+	var i cache.SharedIndexInformer
+	var shardingSettings func(obj interface{}) (int32, int, error)
+	switch owner.APIVersion {
+	case appsv1.SchemeGroupVersion.String():
+		i = cache.NewSharedIndexInformer(newStatefulSetListWatch(ctx, m.kubeClient, m.opts.Namespace, owner.Name), &appsv1.StatefulSet{}, 0, cache.Indexers{})
+		shardingSettings = func(obj interface{}) (int32, int, error) {
+			return shardingSettingsFromStatefulSet(obj.(*appsv1.StatefulSet), m.opts.Pod)
+		}
+	default:
+		i = cache.NewSharedIndexInformer(newAdvancedStatefulSetListWatch(ctx, m.kruiseClient, m.opts.Namespace, owner.Name), &appsv1beta1.StatefulSet{}, 0, cache.Indexers{})
+		shardingSettings = func(obj interface{}) (int32, int, error) {
+			return shardingSettingsFromAdvancedStatefulSet(obj.(*appsv1beta1.StatefulSet), m.opts.Pod)
+		}
+	}
+
+	reshard := func(obj interface{}) {
+		shard, totalShards, err := shardingSettings(obj)
+		if err != nil {
+			klog.Errorf("detect sharding settings from StatefulSet: %v", err)
+			return
+		}
+
+		m.mtx.RLock()
+		shardingUnchanged := m.curShard == shard && m.curTotalShards == totalShards
+		m.mtx.RUnlock()
+
+		if shardingUnchanged {
+			return
+		}
+
+		m.ConfigureSharding(ctx, shard, totalShards)
+	}
+	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: reshard,
+		UpdateFunc: func(oldo, curo interface{}) {
+			old, _ := oldo.(metav1.Object)
+			cur, _ := curo.(metav1.Object)
+			if old != nil && cur != nil && old.GetResourceVersion() == cur.GetResourceVersion() {
+				return
+			}
+			reshard(curo)
+		},
+	})
+	go i.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), i.HasSynced) {
+		return errors.New("waiting for informer cache to sync failed")
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// ServeHTTP implements the http.Handler interface. It writes all generated
+// metrics to the response body, or only those selected by the resource,
+// family and namespace query parameters.
+func (m *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	filter, err := parseFilter(r.URL.Query(), m.metricsWriters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resHeader := w.Header()
+	var writer io.Writer = w
+
+	contentType := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+	// Only the text based formats are supported, fall back to the Prometheus
+	// text format for the others.
+	openMetrics := contentType == expfmt.FmtOpenMetrics_1_0_0 || contentType == expfmt.FmtOpenMetrics_0_0_1
+	if !openMetrics {
+		contentType = expfmt.FmtText
+	}
+	resHeader.Set("Content-Type", string(contentType))
+
+	if m.enableGZIPEncoding {
+		// Gzip response if requested. Taken from
+		// github.com/prometheus/client_golang/prometheus/promhttp.decorateWriter.
+		reqHeader := r.Header.Get("Accept-Encoding")
+		parts := strings.Split(reqHeader, ",")
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "gzip" || strings.HasPrefix(part, "gzip;") {
+				writer = gzip.NewWriter(writer)
+				resHeader.Set("Content-Encoding", "gzip")
+			}
+		}
+	}
+
+	for _, w := range m.metricsWriters {
+		if !filter.IsZero() {
+			// Writers which cannot be filtered are left out.
+			if fw, ok := w.(store.FilterableWriter); ok {
+				fw.WriteFiltered(writer, openMetrics, filter)
+			}
+			continue
+		}
+		if ow, ok := w.(store.OpenMetricsWriter); ok && openMetrics {
+			ow.WriteAllOpenMetrics(writer)
+			continue
+		}
+		w.WriteAll(writer)
+	}
+	if openMetrics {
+		writer.Write([]byte("# EOF\n"))
+	}
+
+	// In case we gzipped the response, we have to close the writer.
+	if closer, ok := writer.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+func newStatefulSetListWatch(ctx context.Context, kubeClient kubernetes.Interface, ns, name string) cache.ListerWatcher {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.FieldSelector = fieldSelector
+			return kubeClient.AppsV1().StatefulSets(ns).List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.FieldSelector = fieldSelector
+			return kubeClient.AppsV1().StatefulSets(ns).Watch(ctx, opts)
+		},
+	}
+}
+
+func newAdvancedStatefulSetListWatch(ctx context.Context, kruiseClient kruiseclientset.Interface, ns, name string) cache.ListerWatcher {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.FieldSelector = fieldSelector
+			return kruiseClient.AppsV1beta1().StatefulSets(ns).List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.FieldSelector = fieldSelector
+			return kruiseClient.AppsV1beta1().StatefulSets(ns).Watch(ctx, opts)
+		},
+	}
+}
+
+func shardingSettingsFromStatefulSet(ss *appsv1.StatefulSet, podName string) (nominal int32, totalReplicas int, err error) {
+	nominal, err = detectNominalFromPod(ss.Name, podName)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "detecting Pod nominal")
+	}
+	if ss.Spec.Ordinals != nil {
+		nominal -= ss.Spec.Ordinals.Start
+	}
+
+	totalReplicas = 1
+	replicas := ss.Spec.Replicas
+	if replicas != nil {
+		totalReplicas = int(*replicas)
+	}
+
+	return nominal, totalReplicas, nil
+}
+
+// shardingSettingsFromAdvancedStatefulSet returns the shard of the pod and the
+// total number of shards. Advanced StatefulSets skip reserved ordinals, so the
+// shard is the rank of the pod ordinal among the ordinals in use.
+func shardingSettingsFromAdvancedStatefulSet(ss *appsv1beta1.StatefulSet, podName string) (nominal int32, totalReplicas int, err error) {
+	ordinal, err := detectNominalFromPod(ss.Name, podName)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "detecting Pod nominal")
+	}
+
+	start := int32(0)
+	if ss.Spec.Ordinals != nil {
+		start = ss.Spec.Ordinals.Start
+	}
+	reserved := store.GetReserveOrdinalIntSet(ss.Spec.ReserveOrdinals)
+	if reserved.Has(int(ordinal)) {
+		return 0, 0, errors.Errorf("ordinal %d of Pod %s is reserved by StatefulSet %s", ordinal, podName, ss.Name)
+	}
+	nominal = ordinal - start
+	for r := range reserved {
+		if int32(r) >= start && int32(r) < ordinal {
+			nominal--
+		}
+	}
+
+	totalReplicas = 1
+	replicas := ss.Spec.Replicas
+	if replicas != nil {
+		totalReplicas = int(*replicas)
+	}
+
+	return nominal, totalReplicas, nil
+}
+
+func detectNominalFromPod(statefulSetName, podName string) (int32, error) {
+	nominalString := strings.TrimPrefix(podName, statefulSetName+"-")
+	nominal, err := strconv.Atoi(nominalString)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to detect shard index for Pod %s of StatefulSet %s, parsed %s", podName, statefulSetName, nominalString)
+	}
+
+	return int32(nominal), nil
+}
+
+// detectStatefulSet returns the owner reference of the StatefulSet or Advanced
+// StatefulSet controlling the given pod.
+func detectStatefulSet(ctx context.Context, kubeClient kubernetes.Interface, podName, namespaceName string) (*metav1.OwnerReference, error) {
+	p, err := kubeClient.CoreV1().Pods(namespaceName).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieve pod %s for sharding", podName)
+	}
+
+	for _, o := range p.GetOwnerReferences() {
+		if o.Kind != "StatefulSet" || o.Controller == nil || !*o.Controller {
+			continue
+		}
+		if o.APIVersion != appsv1.SchemeGroupVersion.String() && o.APIVersion != appsv1beta1.SchemeGroupVersion.String() {
+			continue
+		}
+
+		return &o, nil
+	}
+
+	return nil, errors.Errorf("no suitable statefulset found for auto detecting sharding for Pod %s/%s", namespaceName, podName)
+}
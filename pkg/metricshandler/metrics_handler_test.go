@@ -0,0 +1,516 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	vpaclientset "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/clientset/versioned"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	ksmtypes "k8s.io/kube-state-metrics/v2/pkg/builder/types"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
+	metricsstore "k8s.io/kube-state-metrics/v2/pkg/metrics_store"
+	ksmoptions "k8s.io/kube-state-metrics/v2/pkg/options"
+	"k8s.io/utils/ptr"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+	"github.com/openkruise/kruise-state-metrics/pkg/options"
+)
+
+// fakeBuilder records the sharding settings it was built with.
+type fakeBuilder struct {
+	mtx         sync.Mutex
+	shard       int32
+	totalShards int
+	writers     []metricsstore.MetricsWriter
+}
+
+var _ ksmtypes.BuilderInterface = &fakeBuilder{}
+
+func (b *fakeBuilder) WithMetrics(prometheus.Registerer)                     {}
+func (b *fakeBuilder) WithEnabledResources([]string) error                   { return nil }
+func (b *fakeBuilder) WithNamespaces(ksmoptions.NamespaceList)               {}
+func (b *fakeBuilder) WithContext(context.Context)                           {}
+func (b *fakeBuilder) WithKubeClient(clientset.Interface)                    {}
+func (b *fakeBuilder) WithVPAClient(vpaclientset.Interface)                  {}
+func (b *fakeBuilder) WithAllowDenyList(ksmtypes.AllowDenyLister)            {}
+func (b *fakeBuilder) WithAllowLabels(map[string][]string)                   {}
+func (b *fakeBuilder) WithGenerateStoresFunc(ksmtypes.BuildStoresFunc, bool) {}
+func (b *fakeBuilder) DefaultGenerateStoresFunc() ksmtypes.BuildStoresFunc   { return nil }
+
+func (b *fakeBuilder) WithSharding(shard int32, totalShards int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.shard, b.totalShards = shard, totalShards
+}
+
+func (b *fakeBuilder) Build() []metricsstore.MetricsWriter {
+	return b.writers
+}
+
+func (b *fakeBuilder) sharding() (int32, int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.shard, b.totalShards
+}
+
+func TestShardingSettingsFromAdvancedStatefulSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		pod       string
+		spec      appsv1beta1.StatefulSetSpec
+		wantShard int32
+		wantTotal int
+		wantErr   bool
+	}{
+		{
+			name:      "plain ordinals",
+			pod:       "ksm-2",
+			spec:      appsv1beta1.StatefulSetSpec{Replicas: ptr.To[int32](3)},
+			wantShard: 2,
+			wantTotal: 3,
+		},
+		{
+			name: "reserved ordinals are skipped",
+			pod:  "ksm-3",
+			spec: appsv1beta1.StatefulSetSpec{
+				Replicas:        ptr.To[int32](3),
+				ReserveOrdinals: []intstr.IntOrString{intstr.FromInt32(1)},
+			},
+			wantShard: 2,
+			wantTotal: 3,
+		},
+		{
+			name: "ordinals start offset",
+			pod:  "ksm-12",
+			spec: appsv1beta1.StatefulSetSpec{
+				Replicas:        ptr.To[int32](3),
+				Ordinals:        &appsv1beta1.StatefulSetOrdinals{Start: 10},
+				ReserveOrdinals: []intstr.IntOrString{intstr.FromString("0-5"), intstr.FromInt32(11)},
+			},
+			wantShard: 1,
+			wantTotal: 3,
+		},
+		{
+			name: "reserved pod ordinal",
+			pod:  "ksm-1",
+			spec: appsv1beta1.StatefulSetSpec{
+				Replicas:        ptr.To[int32](3),
+				ReserveOrdinals: []intstr.IntOrString{intstr.FromInt32(1)},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "foreign pod",
+			pod:     "other-1",
+			spec:    appsv1beta1.StatefulSetSpec{Replicas: ptr.To[int32](3)},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ss := &appsv1beta1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "ksm"}, Spec: test.spec}
+			shard, total, err := shardingSettingsFromAdvancedStatefulSet(ss, test.pod)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+			if shard != test.wantShard || total != test.wantTotal {
+				t.Errorf("expected shard %d of %d, got %d of %d", test.wantShard, test.wantTotal, shard, total)
+			}
+		})
+	}
+}
+
+func TestAutoShardingFromAdvancedStatefulSet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ss := &appsv1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kruise-system", Name: "ksm", ResourceVersion: "1"},
+		Spec:       appsv1beta1.StatefulSetSpec{Replicas: ptr.To[int32](2)},
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "kruise-system",
+			Name:      "ksm-1",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: appsv1beta1.SchemeGroupVersion.String(),
+				Kind:       "StatefulSet",
+				Name:       "ksm",
+				Controller: ptr.To(true),
+			}},
+		},
+	}
+	kubeClient := fake.NewSimpleClientset(pod)
+	kruiseClient := kruisefake.NewSimpleClientset(ss)
+
+	opts := options.NewOptions()
+	opts.Pod = "ksm-1"
+	opts.Namespace = "kruise-system"
+	builder := &fakeBuilder{}
+	m := New(opts, kubeClient, kruiseClient, builder, false)
+	go m.Run(ctx)
+
+	waitForSharding := func(wantShard int32, wantTotal int) {
+		t.Helper()
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			shard, total := builder.sharding()
+			return shard == wantShard && total == wantTotal, nil
+		})
+		if err != nil {
+			shard, total := builder.sharding()
+			t.Fatalf("expected shard %d of %d, got %d of %d", wantShard, wantTotal, shard, total)
+		}
+	}
+	waitForSharding(1, 2)
+
+	// Scaling the StatefulSet rebuilds the shards.
+	ss = ss.DeepCopy()
+	ss.ResourceVersion = "2"
+	ss.Spec.Replicas = ptr.To[int32](4)
+	if _, err := kruiseClient.AppsV1beta1().StatefulSets("kruise-system").Update(ctx, ss, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForSharding(1, 4)
+}
+
+func TestServeHTTPContentNegotiation(t *testing.T) {
+	families := []store.FamilyGenerator{{
+		FamilyGenerator: *generator.NewFamilyGenerator("kruise_test_restarts_total", "Test counter.", metric.Counter, "", func(obj interface{}) *metric.Family {
+			return &metric.Family{Metrics: []*metric.Metric{{Value: 3}}}
+		}),
+	}}
+	s := store.NewMetricsStore(families, func(obj interface{}) []metric.FamilyInterface {
+		return []metric.FamilyInterface{families[0].Generate(obj)}
+	})
+	if err := s.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "a", CreationTimestamp: metav1.Unix(1500000000, 0)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(options.NewOptions(), fake.NewSimpleClientset(), kruisefake.NewSimpleClientset(), &fakeBuilder{writers: []metricsstore.MetricsWriter{s}}, true)
+	m.ConfigureSharding(context.Background(), 0, 1)
+
+	tests := []struct {
+		name            string
+		accept          string
+		acceptEncoding  string
+		wantContentType string
+		want            string
+	}{
+		{
+			name:            "text format by default",
+			wantContentType: string(expfmt.FmtText),
+			want:            "# HELP kruise_test_restarts_total Test counter.\n# TYPE kruise_test_restarts_total counter\nkruise_test_restarts_total 3\n",
+		},
+		{
+			name:            "protobuf falls back to text format",
+			accept:          "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited",
+			wantContentType: string(expfmt.FmtText),
+			want:            "# HELP kruise_test_restarts_total Test counter.\n# TYPE kruise_test_restarts_total counter\nkruise_test_restarts_total 3\n",
+		},
+		{
+			name:            "openmetrics",
+			accept:          "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5",
+			wantContentType: string(expfmt.FmtOpenMetrics_1_0_0),
+			want:            "# HELP kruise_test_restarts Test counter.\n# TYPE kruise_test_restarts counter\nkruise_test_restarts_total 3\nkruise_test_restarts_created 1.5e+09\n# EOF\n",
+		},
+		{
+			name:            "gzipped openmetrics",
+			accept:          "application/openmetrics-text;version=1.0.0",
+			acceptEncoding:  "gzip",
+			wantContentType: string(expfmt.FmtOpenMetrics_1_0_0),
+			want:            "# HELP kruise_test_restarts Test counter.\n# TYPE kruise_test_restarts counter\nkruise_test_restarts_total 3\nkruise_test_restarts_created 1.5e+09\n# EOF\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Accept", test.accept)
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Type"); got != test.wantContentType {
+				t.Errorf("expected content type %q, got %q", test.wantContentType, got)
+			}
+			body := io.Reader(rec.Body)
+			if test.acceptEncoding == "gzip" {
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = gz
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("expected body:\n%s\ngot:\n%s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestGather(t *testing.T) {
+	families := []store.FamilyGenerator{
+		{FamilyGenerator: *generator.NewFamilyGenerator("kruise_test_replicas", "Test gauge.", metric.Gauge, "", func(obj interface{}) *metric.Family {
+			return &metric.Family{Metrics: []*metric.Metric{{LabelKeys: []string{"name"}, LabelValues: []string{"a"}, Value: 2}}}
+		})},
+		{FamilyGenerator: *generator.NewFamilyGenerator("kruise_test_invalid", "Repeats a label.", metric.Gauge, "", func(obj interface{}) *metric.Family {
+			return &metric.Family{Metrics: []*metric.Metric{{LabelKeys: []string{"name", "name"}, LabelValues: []string{"a", "b"}, Value: 1}}}
+		})},
+		{FamilyGenerator: *generator.NewFamilyGenerator("kruise_test_empty", "Test gauge without metrics.", metric.Gauge, "", func(obj interface{}) *metric.Family {
+			return &metric.Family{}
+		})},
+	}
+	s := store.NewMetricsStore(families, func(obj interface{}) []metric.FamilyInterface {
+		result := make([]metric.FamilyInterface, 0, len(families))
+		for _, f := range families {
+			result = append(result, f.Generate(obj))
+		}
+		return result
+	})
+	if err := s.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(options.NewOptions(), fake.NewSimpleClientset(), kruisefake.NewSimpleClientset(), &fakeBuilder{writers: []metricsstore.MetricsWriter{s}}, false)
+	m.ConfigureSharding(context.Background(), 1, 3)
+
+	got, err := m.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected only the valid non-empty family, got %v", got)
+	}
+	if got[0].GetName() != "kruise_test_replicas" || len(got[0].Metric) != 1 || got[0].Metric[0].GetGauge().GetValue() != 2 {
+		t.Errorf("unexpected family %v", got[0])
+	}
+
+	if shard, totalShards := m.Sharding(); shard != 1 || totalShards != 3 {
+		t.Errorf("expected shard 1 of 3, got %d of %d", shard, totalShards)
+	}
+}
+
+func TestGatherCreatedTimestamps(t *testing.T) {
+	families := []store.FamilyGenerator{
+		{FamilyGenerator: *generator.NewFamilyGenerator("kruise_test_restarts_total", "Test counter.", metric.Counter, "", func(obj interface{}) *metric.Family {
+			return &metric.Family{Metrics: []*metric.Metric{{LabelKeys: []string{"name", "container"}, LabelValues: []string{"a", "app"}, Value: 3}}}
+		})},
+		{FamilyGenerator: *generator.NewFamilyGenerator("kruise_test_created", "Test creation timestamp.", metric.Gauge, "", func(obj interface{}) *metric.Family {
+			return &metric.Family{Metrics: []*metric.Metric{{LabelKeys: []string{"name"}, LabelValues: []string{"a"}, Value: 1}}}
+		})},
+	}
+	s := store.NewMetricsStore(families, func(obj interface{}) []metric.FamilyInterface {
+		result := make([]metric.FamilyInterface, 0, len(families))
+		for _, f := range families {
+			result = append(result, f.Generate(obj))
+		}
+		return result
+	})
+	if err := s.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "a", CreationTimestamp: metav1.Unix(1500000000, 0)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(options.NewOptions(), fake.NewSimpleClientset(), kruisefake.NewSimpleClientset(), &fakeBuilder{writers: []metricsstore.MetricsWriter{s}}, false)
+	m.ConfigureSharding(context.Background(), 0, 1)
+
+	got, err := m.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range got {
+		switch f.GetName() {
+		case "kruise_test_restarts_total":
+			found = true
+			if created := f.Metric[0].GetCounter().GetCreatedTimestamp(); created == nil || created.AsTime().Unix() != 1500000000 {
+				t.Errorf("expected the counter to be created at 1500000000, got %v", created)
+			}
+		case "kruise_test_created":
+			if v := f.Metric[0].GetGauge().GetValue(); v != 1 {
+				t.Errorf("expected the gauge to be left as is, got %v", v)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected kruise_test_restarts_total to be gathered")
+	}
+}
+
+func TestServeHTTPFilter(t *testing.T) {
+	newWriter := func(resource string, familyNames ...string) *store.MultiStoreMetricsWriter {
+		families := make([]store.FamilyGenerator, 0, len(familyNames))
+		for _, name := range familyNames {
+			families = append(families, store.FamilyGenerator{
+				FamilyGenerator: *generator.NewFamilyGenerator(name, "Test gauge.", metric.Gauge, "", func(obj interface{}) *metric.Family {
+					return &metric.Family{Metrics: []*metric.Metric{{
+						LabelKeys:   []string{"namespace"},
+						LabelValues: []string{obj.(*v1.Pod).Namespace},
+						Value:       1,
+					}}}
+				}),
+			})
+		}
+		s := store.NewMetricsStore(families, func(obj interface{}) []metric.FamilyInterface {
+			result := make([]metric.FamilyInterface, 0, len(families))
+			for _, f := range families {
+				result = append(result, f.Generate(obj))
+			}
+			return result
+		})
+		for _, ns := range []string{"ns1", "ns2"} {
+			if err := s.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, UID: types.UID(resource + ns)}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return store.NewResourceMetricsWriter(resource, []*store.MetricsStore{s})
+	}
+	writers := []metricsstore.MetricsWriter{
+		newWriter("clonesets", "kruise_cloneset_status_replicas", "kruise_cloneset_labels"),
+		newWriter("statefulsets", "kruise_statefulset_status_replicas"),
+		// Resources may have several writers.
+		newWriter("clonesets", "kruise_cloneset_rollout_duration_seconds"),
+	}
+	m := New(options.NewOptions(), fake.NewSimpleClientset(), kruisefake.NewSimpleClientset(), &fakeBuilder{writers: writers}, true)
+	m.ConfigureSharding(context.Background(), 0, 1)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{
+			name:       "no filter",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`kruise_cloneset_status_replicas{namespace="ns1"} 1`,
+				`kruise_cloneset_status_replicas{namespace="ns2"} 1`,
+				`kruise_cloneset_labels{namespace="ns1"} 1`,
+				`kruise_cloneset_labels{namespace="ns2"} 1`,
+				`kruise_statefulset_status_replicas{namespace="ns1"} 1`,
+				`kruise_statefulset_status_replicas{namespace="ns2"} 1`,
+				`kruise_cloneset_rollout_duration_seconds{namespace="ns1"} 1`,
+				`kruise_cloneset_rollout_duration_seconds{namespace="ns2"} 1`,
+			},
+		},
+		{
+			name:       "resource",
+			query:      "resource=statefulsets",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`kruise_statefulset_status_replicas{namespace="ns1"} 1`,
+				`kruise_statefulset_status_replicas{namespace="ns2"} 1`,
+			},
+		},
+		{
+			name:       "family",
+			query:      "family=kruise_.*_status_replicas",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`kruise_cloneset_status_replicas{namespace="ns1"} 1`,
+				`kruise_cloneset_status_replicas{namespace="ns2"} 1`,
+				`kruise_statefulset_status_replicas{namespace="ns1"} 1`,
+				`kruise_statefulset_status_replicas{namespace="ns2"} 1`,
+			},
+		},
+		{
+			name:       "resource, family and namespace",
+			query:      "resource=clonesets,statefulsets&family=kruise_cloneset_labels&family=kruise_statefulset_.*&namespace=ns2",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`kruise_cloneset_labels{namespace="ns2"} 1`,
+				`kruise_statefulset_status_replicas{namespace="ns2"} 1`,
+			},
+		},
+		{
+			name:       "family of the first writer of a resource",
+			query:      "resource=clonesets&family=kruise_cloneset_status_replicas&namespace=ns1",
+			wantStatus: http.StatusOK,
+			want:       []string{`kruise_cloneset_status_replicas{namespace="ns1"} 1`},
+		},
+		{name: "unknown resource", query: "resource=deployments", wantStatus: http.StatusBadRequest},
+		{name: "invalid family", query: "family=kruise_(", wantStatus: http.StatusBadRequest},
+		{name: "family of another resource", query: "resource=statefulsets&family=kruise_cloneset_.*", wantStatus: http.StatusBadRequest},
+		{name: "invalid namespace", query: "namespace=NS_1", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics?"+test.query, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+
+			if rec.Code != test.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", test.wantStatus, rec.Code, rec.Body.String())
+			}
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+			if rec.Header().Get("Content-Encoding") != "gzip" {
+				t.Fatalf("expected a gzipped response")
+			}
+			gz, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(gz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range strings.Split(string(body), "\n") {
+				if line != "" && !strings.HasPrefix(line, "#") {
+					got = append(got, line)
+				}
+			}
+			sort.Strings(got)
+			want := append([]string(nil), test.want...)
+			sort.Strings(want)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected metrics (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	klog "k8s.io/klog/v2"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+)
+
+const (
+	// StatePath is the prefix of the JSON state API,
+	// StatePath{resource}[/{namespace}[/{name}]].
+	StatePath = "/api/v1/state/"
+
+	limitParam    = "limit"
+	continueParam = "continue"
+	fieldsParam   = "fields"
+)
+
+// StateList is the JSON representation of the state of several objects.
+type StateList struct {
+	Resource string        `json:"resource"`
+	Items    []ObjectState `json:"items"`
+	// Continue is set when there are more items, it must be passed as the
+	// continue parameter to get them.
+	Continue string `json:"continue,omitempty"`
+}
+
+// ObjectState is the JSON representation of the metrics of an object by
+// family name.
+type ObjectState struct {
+	Namespace string              `json:"namespace,omitempty"`
+	Name      string              `json:"name"`
+	Metrics   map[string][]Sample `json:"metrics"`
+}
+
+// Sample is a single metric of a family.
+type Sample struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  Value             `json:"value"`
+}
+
+// Value is a metric value, NaN and infinities are encoded as strings like in
+// the Prometheus HTTP API.
+type Value float64
+
+// MarshalJSON implements json.Marshaler.
+func (v Value) MarshalJSON() ([]byte, error) {
+	f := float64(v)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return json.Marshal(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return json.Marshal(f)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value %q", s)
+		}
+		*v = Value(f)
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	*v = Value(f)
+	return nil
+}
+
+// StateHandler returns the handler of the JSON state API. It serves the
+// values the metric families compute for the objects of a resource:
+//
+//   - StatePath{resource} lists all objects.
+//   - StatePath{resource}/{namespace} lists the objects of a namespace.
+//   - StatePath{resource}/{namespace}/{name} returns a single object, cluster
+//     scoped objects are addressed by StatePath{resource}/{name}.
+//
+// Lists are sorted by namespace/name and can be paginated with the limit
+// and continue parameters. The fields parameter selects metric families.
+func (m *MetricsHandler) StateHandler() http.Handler {
+	return http.HandlerFunc(m.serveState)
+}
+
+func (m *MetricsHandler) serveState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, StatePath), "/"), "/")
+	reader := m.stateReader(segments[0])
+	if reader == nil {
+		http.Error(w, fmt.Sprintf("resource %q not found", segments[0]), http.StatusNotFound)
+		return
+	}
+
+	var namespace, name string
+	switch {
+	case len(segments) == 2 && reader.Namespaced():
+		namespace = segments[1]
+	case len(segments) == 2:
+		name = segments[1]
+	case len(segments) == 3 && reader.Namespaced():
+		namespace, name = segments[1], segments[2]
+	case len(segments) > 1:
+		http.Error(w, fmt.Sprintf("invalid path %q", r.URL.Path), http.StatusNotFound)
+		return
+	}
+
+	fields, err := parseFields(r.URL.Query()[fieldsParam], reader.FamilyNames())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	states := reader.States()
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Key() < states[j].Key()
+	})
+
+	if name != "" {
+		for _, s := range states {
+			if s.Namespace == namespace && s.Name == name {
+				writeJSON(w, newObjectState(s, fields))
+				return
+			}
+		}
+		http.Error(w, fmt.Sprintf("%s %q not found", reader.Resource(), strings.TrimPrefix(namespace+"/"+name, "/")), http.StatusNotFound)
+		return
+	}
+
+	limit, start, err := parsePage(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list := StateList{Resource: reader.Resource(), Items: []ObjectState{}}
+	for _, s := range states {
+		if namespace != "" && s.Namespace != namespace {
+			continue
+		}
+		if start != "" && s.Key() <= start {
+			continue
+		}
+		if limit > 0 && len(list.Items) == limit {
+			list.Continue = base64.RawURLEncoding.EncodeToString([]byte(list.Items[limit-1].key()))
+			break
+		}
+		list.Items = append(list.Items, newObjectState(s, fields))
+	}
+	writeJSON(w, list)
+}
+
+// stateReader returns the StateReader of the resource, nil if the resource
+// is not enabled.
+func (m *MetricsHandler) stateReader(resource string) store.StateReader {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for _, w := range m.metricsWriters {
+		if reader, ok := w.(store.StateReader); ok && reader.Resource() == resource {
+			return reader
+		}
+	}
+	return nil
+}
+
+// parseFields returns the selected families, nil if all families are
+// selected.
+func parseFields(values []string, families []string) (map[string]struct{}, error) {
+	var fields map[string]struct{}
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if !slices.Contains(families, field) {
+				return nil, errors.Errorf("unknown field %q", field)
+			}
+			if fields == nil {
+				fields = map[string]struct{}{}
+			}
+			fields[field] = struct{}{}
+		}
+	}
+	return fields, nil
+}
+
+// parsePage returns the maximum number of items and the key of the last item
+// of the previous page.
+func parsePage(query url.Values) (int, string, error) {
+	limit := 0
+	if v := query.Get(limitParam); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return 0, "", errors.Errorf("invalid %s %q, must be a positive integer", limitParam, v)
+		}
+	}
+
+	start := ""
+	if v := query.Get(continueParam); v != "" {
+		key, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return 0, "", errors.Errorf("invalid %s %q", continueParam, v)
+		}
+		start = string(key)
+	}
+	return limit, start, nil
+}
+
+func newObjectState(s *store.ObjectState, fields map[string]struct{}) ObjectState {
+	state := ObjectState{Namespace: s.Namespace, Name: s.Name, Metrics: map[string][]Sample{}}
+	for _, f := range s.Families {
+		if fields != nil {
+			if _, ok := fields[f.Name]; !ok {
+				continue
+			}
+		}
+		samples := make([]Sample, 0, len(f.Metrics))
+		for _, m := range f.Metrics {
+			samples = append(samples, Sample{Labels: sampleLabels(m), Value: Value(m.Value)})
+		}
+		state.Metrics[f.Name] = samples
+	}
+	return state
+}
+
+func (s ObjectState) key() string {
+	return (&store.ObjectState{Namespace: s.Namespace, Name: s.Name}).Key()
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		klog.Errorf("Failed to write state response: %v", err)
+	}
+}
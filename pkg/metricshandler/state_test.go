@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+	generator "k8s.io/kube-state-metrics/v2/pkg/metric_generator"
+	metricsstore "k8s.io/kube-state-metrics/v2/pkg/metrics_store"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+	"github.com/openkruise/kruise-state-metrics/pkg/options"
+)
+
+func newStateTestHandler(t *testing.T) *MetricsHandler {
+	t.Helper()
+
+	families := []store.FamilyGenerator{
+		{FamilyGenerator: *generator.NewFamilyGenerator("kruise_cloneset_status_replicas", "Test gauge.", metric.Gauge, "", func(obj interface{}) *metric.Family {
+			p := obj.(*v1.Pod)
+			return &metric.Family{Metrics: []*metric.Metric{{
+				LabelKeys:   []string{"namespace", "cloneset"},
+				LabelValues: []string{p.Namespace, p.Name},
+				Value:       float64(p.Generation),
+			}}}
+		})},
+		{FamilyGenerator: *generator.NewFamilyGenerator("kruise_cloneset_status_ratio", "Test gauge.", metric.Gauge, "", func(obj interface{}) *metric.Family {
+			return &metric.Family{Metrics: []*metric.Metric{{Value: math.NaN()}}}
+		})},
+	}
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		result := make([]metric.FamilyInterface, 0, len(families))
+		for _, f := range families {
+			result = append(result, f.Generate(obj))
+		}
+		return result
+	}
+
+	s := store.NewMetricsStore(families, genFunc).WithState(true)
+	for i, key := range [][2]string{{"ns2", "cs1"}, {"ns1", "cs2"}, {"ns1", "cs1"}} {
+		if err := s.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: key[0], Name: key[1], UID: types.UID(key[0] + key[1]), Generation: int64(i + 1)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clusterScoped := store.NewMetricsStore(families[:1], func(obj interface{}) []metric.FamilyInterface {
+		return []metric.FamilyInterface{families[0].Generate(obj)}
+	}).WithState(false)
+	if err := clusterScoped.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "scs1", UID: "scs1", Generation: 5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(options.NewOptions(), fake.NewSimpleClientset(), kruisefake.NewSimpleClientset(), &fakeBuilder{writers: []metricsstore.MetricsWriter{
+		store.NewResourceMetricsWriter("clonesets", []*store.MetricsStore{s}),
+		store.NewResourceMetricsWriter("sidecarsets", []*store.MetricsStore{clusterScoped}),
+	}}, false)
+	m.ConfigureSharding(context.Background(), 0, 1)
+	return m
+}
+
+func getState(t *testing.T, h http.Handler, target string, v interface{}) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code == http.StatusOK {
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("expected JSON, got content type %q", got)
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("invalid JSON %s: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestStateHandler(t *testing.T) {
+	h := newStateTestHandler(t).StateHandler()
+
+	var object ObjectState
+	if code := getState(t, h, StatePath+"clonesets/ns1/cs2", &object); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	want := ObjectState{
+		Namespace: "ns1",
+		Name:      "cs2",
+		Metrics: map[string][]Sample{
+			"kruise_cloneset_status_replicas": {{Labels: map[string]string{"namespace": "ns1", "cloneset": "cs2"}, Value: 2}},
+			"kruise_cloneset_status_ratio":    {{Value: Value(math.NaN())}},
+		},
+	}
+	if diff := cmp.Diff(want, object, cmp.Comparer(func(a, b Value) bool {
+		return a == b || math.IsNaN(float64(a)) && math.IsNaN(float64(b))
+	})); diff != "" {
+		t.Errorf("unexpected object (-want, +got):\n%s", diff)
+	}
+
+	// Cluster scoped objects are addressed by name.
+	object = ObjectState{}
+	if code := getState(t, h, StatePath+"sidecarsets/scs1", &object); code != http.StatusOK || object.Name != "scs1" {
+		t.Errorf("expected sidecarset scs1, got status %d and %+v", code, object)
+	}
+
+	// Field selection.
+	var list StateList
+	if code := getState(t, h, StatePath+"clonesets/ns1?fields=kruise_cloneset_status_replicas", &list); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if len(list.Items) != 2 || list.Items[0].Name != "cs1" || list.Items[1].Name != "cs2" {
+		t.Fatalf("expected ns1/cs1 and ns1/cs2, got %+v", list.Items)
+	}
+	if _, ok := list.Items[0].Metrics["kruise_cloneset_status_ratio"]; ok || len(list.Items[0].Metrics) != 1 {
+		t.Errorf("expected only the selected field, got %v", list.Items[0].Metrics)
+	}
+
+	// Pagination.
+	var names []string
+	target := StatePath + "clonesets?limit=2"
+	for pages := 0; ; pages++ {
+		list = StateList{}
+		if code := getState(t, h, target, &list); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+		for _, item := range list.Items {
+			names = append(names, item.Namespace+"/"+item.Name)
+		}
+		if list.Continue == "" {
+			if pages != 1 {
+				t.Errorf("expected 2 pages, got %d", pages+1)
+			}
+			break
+		}
+		target = StatePath + "clonesets?limit=2&continue=" + list.Continue
+	}
+	if want := []string{"ns1/cs1", "ns1/cs2", "ns2/cs1"}; !cmp.Equal(want, names) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+
+	for target, want := range map[string]int{
+		StatePath + "deployments":                    http.StatusNotFound,
+		StatePath + "clonesets/ns1/missing":          http.StatusNotFound,
+		StatePath + "clonesets/ns1/cs1/extra":        http.StatusNotFound,
+		StatePath + "sidecarsets/ns1/scs1":           http.StatusNotFound,
+		StatePath + "clonesets?fields=unknown":       http.StatusBadRequest,
+		StatePath + "clonesets?limit=-1":             http.StatusBadRequest,
+		StatePath + "clonesets?continue=not+base64!": http.StatusBadRequest,
+	} {
+		if code := getState(t, h, target, nil); code != want {
+			t.Errorf("expected status %d for %s, got %d", want, target, code)
+		}
+	}
+}
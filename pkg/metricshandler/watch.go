@@ -0,0 +1,375 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+)
+
+const (
+	// WatchPath is the path of the server-sent events stream of the state
+	// changes of the objects.
+	WatchPath = "/api/v1/watch"
+
+	labelSelectorParam = "labelSelector"
+
+	watchKeepAliveInterval = 30 * time.Second
+	watchWriteTimeout      = 10 * time.Second
+)
+
+// WatchEvent is the JSON representation of a state change of an object. The
+// changes hold the samples whose value changed by metric family name, they
+// are empty for deleted objects.
+type WatchEvent struct {
+	Type      watch.EventType     `json:"type"`
+	Resource  string              `json:"resource"`
+	Namespace string              `json:"namespace,omitempty"`
+	Name      string              `json:"name"`
+	Changes   map[string][]Change `json:"changes,omitempty"`
+}
+
+// Change is a sample whose value changed. Old is nil for new samples and New
+// is nil for removed samples.
+type Change struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Old    *Value            `json:"old,omitempty"`
+	New    *Value            `json:"new,omitempty"`
+}
+
+// Watcher broadcasts the state changes of the objects of the stores to the
+// clients of the watch API. Every client has a bounded buffer of events,
+// clients which don't keep up are disconnected.
+type Watcher struct {
+	bufferSize int
+
+	mtx     sync.Mutex
+	clients map[*watchClient]struct{}
+
+	connectedClients prometheus.Gauge
+	slowClients      prometheus.Counter
+}
+
+type watchClient struct {
+	filter   store.Filter
+	selector labels.Selector
+
+	events chan *WatchEvent
+	// done is closed when the client is disconnected for being too slow.
+	done chan struct{}
+}
+
+// NewWatcher returns a new Watcher buffering up to bufferSize events per
+// client.
+func NewWatcher(bufferSize int, r prometheus.Registerer) *Watcher {
+	return &Watcher{
+		bufferSize: bufferSize,
+		clients:    map[*watchClient]struct{}{},
+		connectedClients: promauto.With(r).NewGauge(prometheus.GaugeOpts{
+			Name: "kruise_state_metrics_watch_clients",
+			Help: "Number of clients connected to the watch API.",
+		}),
+		slowClients: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "kruise_state_metrics_watch_slow_clients_total",
+			Help: "Number of clients of the watch API disconnected because their buffer was full.",
+		}),
+	}
+}
+
+// OnStateChange is the store.StateListener of the watcher. It doesn't block,
+// events are dropped for the clients whose buffer is full and these clients
+// are disconnected.
+func (w *Watcher) OnStateChange(e store.StateEvent) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if len(w.clients) == 0 {
+		return
+	}
+
+	event := newWatchEvent(e)
+	if event == nil {
+		return
+	}
+	objectLabels := e.New
+	if objectLabels == nil {
+		objectLabels = e.Old
+	}
+	for c := range w.clients {
+		if !c.matches(event, labels.Set(objectLabels.Labels)) {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+			delete(w.clients, c)
+			close(c.done)
+			w.slowClients.Inc()
+		}
+	}
+}
+
+func (w *Watcher) subscribe(filter store.Filter, selector labels.Selector) *watchClient {
+	c := &watchClient{
+		filter:   filter,
+		selector: selector,
+		events:   make(chan *WatchEvent, w.bufferSize),
+		done:     make(chan struct{}),
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.clients[c] = struct{}{}
+	w.connectedClients.Inc()
+	return c
+}
+
+func (w *Watcher) unsubscribe(c *watchClient) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	delete(w.clients, c)
+	w.connectedClients.Dec()
+}
+
+func (c *watchClient) matches(e *WatchEvent, objectLabels labels.Set) bool {
+	if len(c.filter.Resources) > 0 {
+		if _, ok := c.filter.Resources[e.Resource]; !ok {
+			return false
+		}
+	}
+	if c.filter.Namespace != "" && c.filter.Namespace != e.Namespace {
+		return false
+	}
+	return c.selector.Matches(objectLabels)
+}
+
+// WatchHandler returns the handler of the watch API. It streams the state
+// changes of the objects as server-sent events, whose type is the type of
+// the change and whose data is a WatchEvent. The events can be filtered with
+// the resource, family and namespace parameters of /metrics and with a
+// labelSelector matching the labels of the objects.
+//
+// A client which doesn't keep up with the events is disconnected, it should
+// get the current state from the state API before watching again.
+func (m *MetricsHandler) WatchHandler(w *Watcher) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.Header().Set("Allow", "GET")
+			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		m.mtx.RLock()
+		filter, err := parseFilter(r.URL.Query(), m.metricsWriters)
+		m.mtx.RUnlock()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		selector, err := labels.Parse(r.URL.Query().Get(labelSelectorParam))
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("invalid %s: %v", labelSelectorParam, err), http.StatusBadRequest)
+			return
+		}
+
+		c := w.subscribe(filter, selector)
+		defer w.unsubscribe(c)
+		w.serve(rw, r, c)
+	})
+}
+
+func (w *Watcher) serve(rw http.ResponseWriter, r *http.Request, c *watchClient) {
+	rc := http.NewResponseController(rw)
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		klog.Errorf("Failed to start watch stream: %v", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(watchKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		var message string
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.done:
+			klog.V(2).Infof("Disconnecting slow watch client %s", r.RemoteAddr)
+			return
+		case <-keepAlive.C:
+			message = ": keep-alive\n\n"
+		case e := <-c.events:
+			e = filterChanges(e, c.filter)
+			if e == nil {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				klog.Errorf("Failed to marshal watch event: %v", err)
+				continue
+			}
+			message = fmt.Sprintf("event: %s\ndata: %s\n\n", e.Type, data)
+		}
+
+		// Writes to clients which don't read are bounded as well.
+		rc.SetWriteDeadline(time.Now().Add(watchWriteTimeout))
+		if _, err := rw.Write([]byte(message)); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// filterChanges returns the event with the changes of the families selected
+// by the filter, nil if a modification has no change left.
+func filterChanges(e *WatchEvent, filter store.Filter) *WatchEvent {
+	if filter.Family == nil {
+		return e
+	}
+	filtered := *e
+	filtered.Changes = map[string][]Change{}
+	for name, changes := range e.Changes {
+		if filter.Family.MatchString(name) {
+			filtered.Changes[name] = changes
+		}
+	}
+	if e.Type == watch.Modified && len(filtered.Changes) == 0 {
+		return nil
+	}
+	return &filtered
+}
+
+// newWatchEvent returns the event of a state change, nil if no metric value
+// changed.
+func newWatchEvent(e store.StateEvent) *WatchEvent {
+	event := &WatchEvent{Type: e.Type, Resource: e.Resource}
+	switch {
+	case e.New != nil:
+		event.Namespace, event.Name = e.New.Namespace, e.New.Name
+	case e.Old != nil:
+		event.Namespace, event.Name = e.Old.Namespace, e.Old.Name
+	default:
+		return nil
+	}
+	if e.Type == watch.Deleted {
+		return event
+	}
+
+	event.Changes = diffStates(e.Old, e.New)
+	if e.Type == watch.Modified && len(event.Changes) == 0 {
+		return nil
+	}
+	return event
+}
+
+// diffStates returns the samples of the new state whose value differ from
+// the old state, and the samples of the old state which were removed, by
+// family name.
+func diffStates(old, new *store.ObjectState) map[string][]Change {
+	oldFamilies := map[string]metric.Family{}
+	if old != nil {
+		for _, f := range old.Families {
+			oldFamilies[f.Name] = f
+		}
+	}
+
+	changes := map[string][]Change{}
+	for _, f := range new.Families {
+		oldSamples := map[string]*metric.Metric{}
+		for _, m := range oldFamilies[f.Name].Metrics {
+			oldSamples[sampleKey(m)] = m
+		}
+
+		var familyChanges []Change
+		for _, m := range f.Metrics {
+			key := sampleKey(m)
+			oldSample, ok := oldSamples[key]
+			delete(oldSamples, key)
+			if ok && sameValue(oldSample.Value, m.Value) {
+				continue
+			}
+			change := Change{Labels: sampleLabels(m), New: valuePtr(m.Value)}
+			if ok {
+				change.Old = valuePtr(oldSample.Value)
+			}
+			familyChanges = append(familyChanges, change)
+		}
+		for _, m := range oldFamilies[f.Name].Metrics {
+			if _, ok := oldSamples[sampleKey(m)]; ok {
+				familyChanges = append(familyChanges, Change{Labels: sampleLabels(m), Old: valuePtr(m.Value)})
+			}
+		}
+		if len(familyChanges) > 0 {
+			changes[f.Name] = familyChanges
+		}
+	}
+	return changes
+}
+
+// sampleKey identifies a sample within its family by its labels.
+func sampleKey(m *metric.Metric) string {
+	pairs := make([]string, 0, len(m.LabelKeys))
+	for i, k := range m.LabelKeys {
+		if i < len(m.LabelValues) {
+			pairs = append(pairs, k+"="+m.LabelValues[i])
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\xff")
+}
+
+func sampleLabels(m *metric.Metric) map[string]string {
+	if len(m.LabelKeys) == 0 {
+		return nil
+	}
+	sampleLabels := make(map[string]string, len(m.LabelKeys))
+	for i, k := range m.LabelKeys {
+		if i < len(m.LabelValues) {
+			sampleLabels[k] = m.LabelValues[i]
+		}
+	}
+	return sampleLabels
+}
+
+func sameValue(a, b float64) bool {
+	return a == b || math.IsNaN(a) && math.IsNaN(b)
+}
+
+func valuePtr(f float64) *Value {
+	v := Value(f)
+	return &v
+}
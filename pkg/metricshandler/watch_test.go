@@ -0,0 +1,171 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/kube-state-metrics/v2/pkg/metric"
+
+	"github.com/openkruise/kruise-state-metrics/internal/store"
+)
+
+func testObjectState(namespace, name string, objectLabels map[string]string, values ...float64) *store.ObjectState {
+	state := &store.ObjectState{Namespace: namespace, Name: name, Labels: objectLabels}
+	for i, v := range values {
+		state.Families = append(state.Families, metric.Family{
+			Name: []string{"kruise_cloneset_status_replicas", "kruise_cloneset_status_replicas_updated_ready"}[i],
+			Metrics: []*metric.Metric{{
+				LabelKeys:   []string{"namespace", "cloneset"},
+				LabelValues: []string{namespace, name},
+				Value:       v,
+			}},
+		})
+	}
+	return state
+}
+
+func TestWatchHandler(t *testing.T) {
+	watcher := NewWatcher(10, prometheus.NewRegistry())
+	server := httptest.NewServer(newStateTestHandler(t).WatchHandler(watcher))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + WatchPath + "?resource=clonesets&namespace=ns1&labelSelector=app%3Dweb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got status %d and content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	web := map[string]string{"app": "web"}
+	for _, e := range []store.StateEvent{
+		// Filtered out by resource, namespace and label selector.
+		{Type: watch.Added, Resource: "sidecarsets", New: testObjectState("", "scs1", web, 1, 1)},
+		{Type: watch.Added, Resource: "clonesets", New: testObjectState("ns2", "cs1", web, 1, 1)},
+		{Type: watch.Added, Resource: "clonesets", New: testObjectState("ns1", "db", map[string]string{"app": "db"}, 1, 1)},
+		// Modifications without changes are left out.
+		{Type: watch.Modified, Resource: "clonesets", Old: testObjectState("ns1", "cs1", web, 4, 3), New: testObjectState("ns1", "cs1", web, 4, 3)},
+		{Type: watch.Modified, Resource: "clonesets", Old: testObjectState("ns1", "cs1", web, 4, 3), New: testObjectState("ns1", "cs1", web, 4, 4)},
+		{Type: watch.Deleted, Resource: "clonesets", Old: testObjectState("ns1", "cs1", web, 4, 4)},
+	} {
+		watcher.OnStateChange(e)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var got []string
+	for len(got) < 4 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			got = append(got, line)
+		}
+	}
+	want := []string{
+		"event: MODIFIED",
+		`data: {"type":"MODIFIED","resource":"clonesets","namespace":"ns1","name":"cs1","changes":{"kruise_cloneset_status_replicas_updated_ready":[{"labels":{"cloneset":"cs1","namespace":"ns1"},"old":3,"new":4}]}}`,
+		"event: DELETED",
+		`data: {"type":"DELETED","resource":"clonesets","namespace":"ns1","name":"cs1"}`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected events (-want, +got):\n%s", diff)
+	}
+
+	h := newStateTestHandler(t).WatchHandler(watcher)
+	for target, want := range map[string]int{
+		WatchPath + "?resource=deployments":       http.StatusBadRequest,
+		WatchPath + "?labelSelector=app%3D%3D%3D": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != want {
+			t.Errorf("expected status %d for %s, got %d", want, target, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, WatchPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestWatcherSlowClient(t *testing.T) {
+	watcher := NewWatcher(1, prometheus.NewRegistry())
+	slow := watcher.subscribe(store.Filter{}, labels.Everything())
+	filtered := watcher.subscribe(store.Filter{Namespace: "ns2"}, labels.Everything())
+
+	for i := 0; i < 2; i++ {
+		watcher.OnStateChange(store.StateEvent{Type: watch.Added, Resource: "clonesets", New: testObjectState("ns1", "cs1", nil, float64(i))})
+	}
+
+	select {
+	case <-slow.done:
+	default:
+		t.Error("expected the slow client to be disconnected")
+	}
+	select {
+	case <-filtered.done:
+		t.Error("expected the client not receiving the events to stay connected")
+	default:
+	}
+	if got := testutil.ToFloat64(watcher.slowClients); got != 1 {
+		t.Errorf("expected 1 slow client, got %v", got)
+	}
+
+	watcher.unsubscribe(slow)
+	watcher.unsubscribe(filtered)
+	if got := testutil.ToFloat64(watcher.connectedClients); got != 0 {
+		t.Errorf("expected no connected client, got %v", got)
+	}
+}
+
+func TestDiffStates(t *testing.T) {
+	sample := func(value float64, labelValues ...string) *metric.Metric {
+		return &metric.Metric{LabelKeys: []string{"condition"}, LabelValues: labelValues, Value: value}
+	}
+	old := &store.ObjectState{Families: []metric.Family{{
+		Name:    "kruise_cloneset_status_condition",
+		Metrics: []*metric.Metric{sample(1, "Ready"), sample(0, "Failed")},
+	}}}
+	new := &store.ObjectState{Families: []metric.Family{{
+		Name:    "kruise_cloneset_status_condition",
+		Metrics: []*metric.Metric{sample(0, "Ready"), sample(1, "Progressing")},
+	}}}
+
+	want := map[string][]Change{
+		"kruise_cloneset_status_condition": {
+			{Labels: map[string]string{"condition": "Ready"}, Old: valuePtr(1), New: valuePtr(0)},
+			{Labels: map[string]string{"condition": "Progressing"}, New: valuePtr(1)},
+			{Labels: map[string]string{"condition": "Failed"}, Old: valuePtr(0)},
+		},
+	}
+	if diff := cmp.Diff(want, diffStates(old, new)); diff != "" {
+		t.Errorf("unexpected changes (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/v2/pkg/options"
+)
+
+// Options are the configurable parameters for kruise-state-metrics. They
+// extend the kube-state-metrics options with kruise specific settings.
+type Options struct {
+	*options.Options
+
+	FamilySeriesLimit  int
+	FamilySeriesLimits FamilyLimits
+	TotalSeriesLimit   int
+
+	ResourceLabelSelectors ResourceSelectors
+	ResourceFieldSelectors ResourceSelectors
+
+	NamespaceSelector string
+
+	ShardingAlgorithm string
+
+	LeaderElect          bool
+	LeaderElectNamespace string
+	LeaderElectName      string
+
+	EnableStateAPI  bool
+	EnableWatchAPI  bool
+	WatchBufferSize int
+
+	EnablePodMetrics bool
+
+	EventReasons     []string
+	ConditionReasons []string
+
+	DebugPort int
+	DebugHost string
+
+	EnableAuth            bool
+	AuthNonResourceURL    string
+	AuthResource          string
+	AuthResourceNamespace string
+	AuthCacheTTL          time.Duration
+
+	ClusterName  string
+	OTLPEndpoint string
+	OTLPProtocol string
+	OTLPInsecure bool
+	OTLPHeaders  map[string]string
+	OTLPInterval time.Duration
+	OTLPTimeout  time.Duration
+
+	RemoteWriteURL             string
+	RemoteWriteUsername        string
+	RemoteWritePasswordFile    string
+	RemoteWriteBearerTokenFile string
+	RemoteWriteExternalLabels  map[string]string
+	RemoteWriteInterval        time.Duration
+	RemoteWriteTimeout         time.Duration
+	RemoteWriteQueueMaxSamples int
+
+	Filenames    []string
+	RenderFormat string
+
+	flags *pflag.FlagSet
+}
+
+// NewOptions returns a new instance of `Options`.
+func NewOptions() *Options {
+	return &Options{
+		Options:                   options.NewOptions(),
+		FamilySeriesLimits:        FamilyLimits{},
+		ResourceLabelSelectors:    ResourceSelectors{},
+		ResourceFieldSelectors:    ResourceSelectors{},
+		OTLPHeaders:               map[string]string{},
+		RemoteWriteExternalLabels: map[string]string{},
+	}
+}
+
+// AddFlags populated the Options struct from the command line arguments passed.
+// The kube-state-metrics flag set is not exported, so its flags are declared
+// again here next to the kruise specific ones.
+func (o *Options) AddFlags() {
+	o.flags = pflag.NewFlagSet("", pflag.ExitOnError)
+	// add klog flags
+	klogFlags := flag.NewFlagSet("klog", flag.ExitOnError)
+	klog.InitFlags(klogFlags)
+	o.flags.AddGoFlagSet(klogFlags)
+	o.flags.Lookup("logtostderr").Value.Set("true")
+	o.flags.Lookup("logtostderr").DefValue = "true"
+	o.flags.Lookup("logtostderr").NoOptDefVal = "true"
+
+	o.flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		o.flags.PrintDefaults()
+	}
+
+	o.flags.BoolVarP(&o.UseAPIServerCache, "use-apiserver-cache", "", false, "Sets resourceVersion=0 for ListWatch requests, using cached resources from the apiserver instead of an etcd quorum read.")
+	o.flags.StringVar(&o.Apiserver, "apiserver", "", `The URL of the apiserver to use as a master`)
+	o.flags.StringVar(&o.Kubeconfig, "kubeconfig", "", "Absolute path to the kubeconfig file")
+	o.flags.StringVar(&o.TLSConfig, "tls-config", "", "Path to the TLS configuration file")
+	o.flags.BoolVarP(&o.Help, "help", "h", false, "Print Help text")
+	o.flags.IntVar(&o.Port, "port", 8080, `Port to expose metrics on.`)
+	o.flags.StringVar(&o.Host, "host", "::", `Host to expose metrics on.`)
+	o.flags.IntVar(&o.TelemetryPort, "telemetry-port", 8081, `Port to expose kruise-state-metrics self metrics on.`)
+	o.flags.StringVar(&o.TelemetryHost, "telemetry-host", "::", `Host to expose kruise-state-metrics self metrics on.`)
+	o.flags.IntVar(&o.DebugPort, "debug-port", 0, `Port to expose pprof and the debug endpoints on. The debug server is disabled when 0.`)
+	o.flags.StringVar(&o.DebugHost, "debug-host", "127.0.0.1", `Host to expose pprof and the debug endpoints on.`)
+	o.flags.Var(&o.Resources, "resources", fmt.Sprintf("Comma-separated list of Resources to be enabled. Defaults to %q", &DefaultResources))
+	o.flags.Var(&o.Namespaces, "namespaces", fmt.Sprintf("Comma-separated list of namespaces to be enabled. Defaults to %q", &options.DefaultNamespaces))
+	o.flags.StringVar(&o.NamespaceSelector, "namespace-selector", "", "Label selector on Namespace objects. Only the objects of the matching namespaces are exposed, following namespaces as they are created, relabeled or deleted. If --namespaces is set as well, only the matching namespaces among them are exposed.")
+	o.flags.Var(&o.MetricAllowlist, "metric-allowlist", "Comma-separated list of metrics to be exposed. This list comprises of exact metric names and/or regex patterns. The allowlist and denylist are mutually exclusive.")
+	o.flags.Var(&o.MetricDenylist, "metric-denylist", "Comma-separated list of metrics not to be enabled. This list comprises of exact metric names and/or regex patterns. The allowlist and denylist are mutually exclusive.")
+	o.flags.Var(&o.AnnotationsAllowList, "metric-annotations-allowlist", "Comma-separated list of Kubernetes annotations keys that will be used in the resource' labels metric. By default the metric contains only name and namespace labels. To include additional annotations provide a list of resource names in their plural form and Kubernetes annotation keys you would like to allow for them (Example: '=clonesets=[kubernetes.io/team,...],statefulsets=[kubernetes.io/team],...)'. A single '*' can be provided per resource instead to allow any annotations, but that has severe performance implications (Example: '=clonesets=[*]').")
+	o.flags.Var(&o.LabelsAllowList, "metric-labels-allowlist", "Comma-separated list of additional Kubernetes label keys that will be used in the resource' labels metric. By default the metric contains only name and namespace labels. To include additional labels provide a list of resource names in their plural form and Kubernetes label keys you would like to allow for them (Example: '=clonesets=[k8s-label-1,k8s-label-n,...],statefulsets=[app],...)'. A single '*' can be provided per resource instead to allow any labels, but that has severe performance implications (Example: '=clonesets=[*]').")
+	o.flags.Int32Var(&o.Shard, "shard", int32(0), "The instances shard nominal (zero indexed) within the total number of shards. (default 0)")
+	o.flags.IntVar(&o.TotalShards, "total-shards", 1, "The total number of shards. Sharding is disabled when total shards is set to 1.")
+	o.flags.StringVar(&o.ShardingAlgorithm, "sharding-algorithm", "jump", "The algorithm assigning objects to shards, either 'jump' (jump consistent hash) or 'rendezvous' (highest random weight hash). Both move about 1/(N+1) of the objects when the total number of shards grows from N to N+1. All shards must use the same algorithm.")
+
+	autoshardingNotice := "When set, it is expected that --pod and --pod-namespace are both set. Most likely this should be passed via the downward API. This is used for auto-detecting sharding. If set, this has preference over statically configured sharding. This is experimental, it may be removed without notice."
+
+	o.flags.StringVar(&o.Pod, "pod", "", "Name of the pod that contains the kruise-state-metrics container. "+autoshardingNotice)
+	o.flags.StringVar(&o.Namespace, "pod-namespace", "", "Name of the namespace of the pod specified by --pod. "+autoshardingNotice)
+	o.flags.BoolVar(&o.LeaderElect, "leader-elect", false, "Run several replicas active-passive. Only the replica holding the Lease serves metrics, the others answer scrapes with an empty body and take over when the Lease is released or expires.")
+	o.flags.StringVar(&o.LeaderElectNamespace, "leader-elect-namespace", "kruise-system", "Namespace of the Lease used for leader election.")
+	o.flags.StringVar(&o.LeaderElectName, "leader-elect-name", "kruise-state-metrics", "Name of the Lease used for leader election.")
+	o.flags.BoolVarP(&o.Version, "version", "", false, "kruise-state-metrics build version information")
+	o.flags.BoolVar(&o.EnableGZIPEncoding, "enable-gzip-encoding", false, "Gzip responses when requested by clients via 'Accept-Encoding: gzip' header.")
+	o.flags.BoolVar(&o.EnableStateAPI, "enable-state-api", false, "Serve the values of the metric families of every object as JSON on /api/v1/state/{resource}[/{namespace}[/{name}]]. The stores keep the structured metrics next to their text representation, which increases the memory usage.")
+	o.flags.BoolVar(&o.EnableWatchAPI, "enable-watch-api", false, "Stream the changes of the metric values of every object as server-sent events on /api/v1/watch. Like --enable-state-api, it increases the memory usage of the stores.")
+	o.flags.IntVar(&o.WatchBufferSize, "watch-buffer-size", 100, "Number of events buffered for every client of the watch API. Clients whose buffer is full are disconnected.")
+	o.flags.BoolVar(&o.EnablePodMetrics, "enable-pod-metrics", false, "Watch pods to compute the metrics of the Kruise workloads derived from their pods, e.g. their in-place updates, the injection of the SidecarSets or the placement of the pods of the WorkloadSpreads. Every shard watches all pods, which increases the load on the apiserver and the memory usage in large clusters.")
+	o.flags.StringSliceVar(&o.EventReasons, "event-reasons", DefaultEventReasons, "Comma-separated list of the reasons of the events counted by the events resource. The events of other reasons are counted with the reason Other, to bound the cardinality.")
+	o.flags.StringSliceVar(&o.ConditionReasons, "condition-reasons", nil, "Comma-separated list of the reasons of the status conditions reported by the kruise_*_status_condition_reason families, which are only generated if reasons are set. The conditions of other reasons are reported with the reason Other, to bound the cardinality.")
+	o.flags.BoolVar(&o.EnableAuth, "enable-auth", false, "Authenticate requests to the metrics server by their bearer token with a TokenReview and authorize them with a SubjectAccessReview. /healthz is not protected.")
+	o.flags.StringVar(&o.AuthNonResourceURL, "auth-non-resource-url", "", "Non-resource URL requests must be allowed to access with the verb of their HTTP method. Defaults to the path of the request.")
+	o.flags.StringVar(&o.AuthResource, "auth-resource", "", "Virtual resource in the form resource.group requests must be allowed to get (Example: 'metrics.kruise-state-metrics.kruise.io'). Takes precedence over --auth-non-resource-url.")
+	o.flags.StringVar(&o.AuthResourceNamespace, "auth-resource-namespace", "", "Namespace of the virtual resource set with --auth-resource. Cluster scoped when empty.")
+	o.flags.DurationVar(&o.AuthCacheTTL, "auth-cache-ttl", 2*time.Minute, "Duration for which TokenReview and SubjectAccessReview results are cached.")
+
+	o.flags.IntVar(&o.FamilySeriesLimit, "family-series-limit", 0, "Maximum number of series a single metric family may expose across all objects. Series beyond the limit are dropped. 0 disables the limit.")
+	o.flags.Var(&o.FamilySeriesLimits, "family-series-limits", "Comma-separated list of per family series limits overriding --family-series-limit (Example: 'kruise_cloneset_labels=1000,kruise_sidecarset_spec_containers_injectpolicy=500'). 0 disables the limit for that family.")
+	o.flags.IntVar(&o.TotalSeriesLimit, "total-series-limit", 0, "Maximum number of series exposed across all metric families. Series beyond the limit are dropped. 0 disables the limit.")
+	o.flags.Var(&o.ResourceLabelSelectors, "resource-label-selector", "Label selector used to list and watch the objects of a resource, in the form resource=selector (Example: 'clonesets=monitoring.example.com/enabled=true'). Use '*' as resource to select the objects of all resources without a selector of their own. Can be repeated.")
+	o.flags.Var(&o.ResourceFieldSelectors, "resource-field-selector", "Field selector used to list and watch the objects of a resource, in the form resource=selector (Example: '*=metadata.namespace!=kube-system'). Kruise resources only support the metadata.name and metadata.namespace fields. Use '*' as resource to select the objects of all resources without a selector of their own. Can be repeated.")
+
+	o.flags.StringVar(&o.ClusterName, "cluster-name", "", "Name of the cluster, exported as the k8s.cluster.name resource attribute over OTLP.")
+	o.flags.StringVar(&o.OTLPEndpoint, "otlp-endpoint", "", "Endpoint of an OpenTelemetry collector to push the metrics to over OTLP, host:port for grpc and a URL or host:port for http/protobuf. Pushing is disabled when empty.")
+	o.flags.StringVar(&o.OTLPProtocol, "otlp-protocol", "grpc", "Protocol used to push metrics over OTLP, either 'grpc' or 'http/protobuf'.")
+	o.flags.BoolVar(&o.OTLPInsecure, "otlp-insecure", false, "Push metrics over OTLP without TLS.")
+	o.flags.StringToStringVar(&o.OTLPHeaders, "otlp-headers", o.OTLPHeaders, "Comma-separated list of headers sent with every OTLP export (Example: 'authorization=Bearer token').")
+	o.flags.DurationVar(&o.OTLPInterval, "otlp-interval", 30*time.Second, "Interval between two OTLP exports.")
+	o.flags.DurationVar(&o.OTLPTimeout, "otlp-timeout", 10*time.Second, "Timeout of a single OTLP export attempt.")
+
+	o.flags.StringVar(&o.RemoteWriteURL, "remote-write-url", "", "URL of a Prometheus remote write endpoint to push the metrics to (Example: 'https://prometheus.example.com/api/v1/write'). Pushing is disabled when empty.")
+	o.flags.StringVar(&o.RemoteWriteUsername, "remote-write-username", "", "Username for basic authentication against the remote write endpoint.")
+	o.flags.StringVar(&o.RemoteWritePasswordFile, "remote-write-password-file", "", "File containing the password for basic authentication against the remote write endpoint.")
+	o.flags.StringVar(&o.RemoteWriteBearerTokenFile, "remote-write-bearer-token-file", "", "File containing the bearer token for authentication against the remote write endpoint, read before every request.")
+	o.flags.StringToStringVar(&o.RemoteWriteExternalLabels, "remote-write-external-labels", o.RemoteWriteExternalLabels, "Comma-separated list of labels added to every series pushed over remote write (Example: 'cluster=edge-1,region=eu').")
+	o.flags.DurationVar(&o.RemoteWriteInterval, "remote-write-interval", 30*time.Second, "Interval between two remote write snapshots.")
+	o.flags.DurationVar(&o.RemoteWriteTimeout, "remote-write-timeout", 10*time.Second, "Timeout of a single remote write request.")
+	o.flags.IntVar(&o.RemoteWriteQueueMaxSamples, "remote-write-queue-max-samples", 100000, "Maximum number of samples kept in memory while the remote write endpoint is unavailable. The oldest samples are dropped beyond it.")
+}
+
+// AddRenderFlags adds the flags of the render subcommand, AddFlags must be
+// called first.
+func (o *Options) AddRenderFlags() {
+	o.flags.StringSliceVarP(&o.Filenames, "filename", "f", nil, "Manifests of the objects to render the metrics of. Directories are read recursively for .yaml, .yml and .json files, - reads stdin. Can be repeated.")
+	o.flags.StringVar(&o.RenderFormat, "format", "text", "Format of the rendered metrics, either 'text' (Prometheus text format) or 'openmetrics'.")
+	o.flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s render:\n", os.Args[0])
+		o.flags.PrintDefaults()
+	}
+}
+
+// Parse parses the flag definitions from the argument list.
+func (o *Options) Parse() error {
+	err := o.flags.Parse(os.Args)
+	return err
+}
+
+// Usage is the function called when an error occurs while parsing flags.
+func (o *Options) Usage() {
+	o.flags.Usage()
+}
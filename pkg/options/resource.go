@@ -29,4 +29,16 @@ var (
 		"broadcastjobs":             struct{}{},
 		"containerrecreaterequests": struct{}{},
 	}
+
+	// DefaultEventReasons represents the default reasons of the events counted
+	// by the events resource, emitted by the Kruise controllers.
+	DefaultEventReasons = []string{
+		"SuccessfulCreate",
+		"FailedCreate",
+		"SuccessfulUpdate",
+		"FailedUpdate",
+		"SuccessfulDelete",
+		"FailedDelete",
+		"PreDeleteHookBlocked",
+	}
 )
@@ -0,0 +1,95 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FamilyLimits represents a series limit per metric family name.
+type FamilyLimits map[string]int
+
+func (f *FamilyLimits) String() string {
+	s := *f
+	ss := make([]string, 0, len(s))
+	for family, limit := range s {
+		ss = append(ss, family+"="+strconv.Itoa(limit))
+	}
+	sort.Strings(ss)
+	return strings.Join(ss, ",")
+}
+
+// Set converts a comma-separated string of family=limit pairs and adds them to the FamilyLimits.
+func (f *FamilyLimits) Set(value string) error {
+	s := *f
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+		family, limit, found := strings.Cut(pair, "=")
+		if !found || len(strings.TrimSpace(family)) == 0 {
+			return fmt.Errorf("invalid family limit %q, expected family=limit", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid limit for family %q, expected a non-negative integer", family)
+		}
+		s[strings.TrimSpace(family)] = n
+	}
+	return nil
+}
+
+// Type returns a descriptive string about the FamilyLimits type.
+func (f *FamilyLimits) Type() string {
+	return "string"
+}
+
+// ResourceSelectors represents a selector per resource name.
+type ResourceSelectors map[string]string
+
+func (r *ResourceSelectors) String() string {
+	s := *r
+	ss := make([]string, 0, len(s))
+	for resource, selector := range s {
+		ss = append(ss, resource+"="+selector)
+	}
+	sort.Strings(ss)
+	return strings.Join(ss, ";")
+}
+
+// Set parses a resource=selector pair and adds it to the ResourceSelectors.
+// The selector may contain commas, so the flag has to be repeated to set the
+// selectors of several resources.
+func (r *ResourceSelectors) Set(value string) error {
+	s := *r
+	resource, selector, found := strings.Cut(value, "=")
+	resource = strings.TrimSpace(resource)
+	if !found || len(resource) == 0 {
+		return fmt.Errorf("invalid resource selector %q, expected resource=selector", value)
+	}
+	s[resource] = strings.TrimSpace(selector)
+	return nil
+}
+
+// Type returns a descriptive string about the ResourceSelectors type.
+func (r *ResourceSelectors) Type() string {
+	return "string"
+}
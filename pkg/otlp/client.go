@@ -0,0 +1,169 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	klog "k8s.io/klog/v2"
+)
+
+const defaultHTTPPath = "/v1/metrics"
+
+type grpcClient struct {
+	conn    *grpc.ClientConn
+	client  colmetricspb.MetricsServiceClient
+	headers metadata.MD
+	timeout time.Duration
+}
+
+func newGRPCClient(config Config) (*grpcClient, error) {
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if config.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(config.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, errors.Wrapf(err, "create OTLP gRPC client for %s", config.Endpoint)
+	}
+
+	return &grpcClient{
+		conn:    conn,
+		client:  colmetricspb.NewMetricsServiceClient(conn),
+		headers: metadata.New(config.Headers),
+		timeout: config.Timeout,
+	}, nil
+}
+
+func (c *grpcClient) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.Export(metadata.NewOutgoingContext(ctx, c.headers), req)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange,
+			codes.Unavailable, codes.DataLoss, codes.ResourceExhausted:
+			return retryableError{err}
+		}
+		return err
+	}
+	logPartialSuccess(resp)
+
+	return nil
+}
+
+func (c *grpcClient) close() error {
+	return c.conn.Close()
+}
+
+type httpClient struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPClient(config Config) (*httpClient, error) {
+	endpoint := config.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https://"
+		if config.Insecure {
+			scheme = "http://"
+		}
+		endpoint = scheme + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse OTLP endpoint %s", config.Endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultHTTPPath
+	}
+
+	return &httpClient{
+		url:     u.String(),
+		headers: config.Headers,
+		client:  &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+func (c *httpClient) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return errors.Wrap(err, "marshal export request")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return retryableError{err}
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return retryableError{err}
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		exportResp := &colmetricspb.ExportMetricsServiceResponse{}
+		if err := proto.Unmarshal(respBody, exportResp); err == nil {
+			logPartialSuccess(exportResp)
+		}
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return retryableError{errors.Errorf("OTLP endpoint returned %s", resp.Status)}
+	default:
+		return errors.Errorf("OTLP endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+}
+
+func (c *httpClient) close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+func logPartialSuccess(resp *colmetricspb.ExportMetricsServiceResponse) {
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+		klog.Warningf("OTLP collector rejected %d data points: %s", ps.GetRejectedDataPoints(), ps.GetErrorMessage())
+	}
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/version"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/util/wait"
+	klog "k8s.io/klog/v2"
+
+	"github.com/openkruise/kruise-state-metrics/pkg/samples"
+)
+
+const (
+	// ProtocolGRPC exports over OTLP/gRPC.
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTP exports over OTLP/HTTP with binary protobuf payloads.
+	ProtocolHTTP = "http/protobuf"
+
+	scopeName = "github.com/openkruise/kruise-state-metrics"
+
+	attributeServiceName  = "service.name"
+	attributeClusterName  = "k8s.cluster.name"
+	attributeShard        = "kruise_state_metrics.shard"
+	attributeTotalShards  = "kruise_state_metrics.total_shards"
+	defaultServiceName    = "kruise-state-metrics"
+	defaultExportInterval = 30 * time.Second
+	defaultExportTimeout  = 10 * time.Second
+)
+
+// Config configures the Exporter.
+type Config struct {
+	// Endpoint is host:port for ProtocolGRPC and a URL for ProtocolHTTP,
+	// host:port is completed to http(s)://host:port/v1/metrics.
+	Endpoint string
+	Protocol string
+	// Insecure disables TLS.
+	Insecure bool
+	// Headers are sent with every export, e.g. for authentication.
+	Headers map[string]string
+	// Interval is the time between two exports.
+	Interval time.Duration
+	// Timeout bounds every export attempt.
+	Timeout time.Duration
+	// ClusterName is exposed as the k8s.cluster.name resource attribute.
+	ClusterName string
+}
+
+// client sends an export request to the collector.
+type client interface {
+	export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error
+	close() error
+}
+
+// retryableError is an export error which is worth retrying, e.g. because
+// the collector is temporarily unavailable.
+type retryableError struct {
+	error
+}
+
+// Exporter periodically converts the gathered metric families to OTLP and
+// pushes them to an OpenTelemetry collector.
+type Exporter struct {
+	config   Config
+	gatherer prometheus.Gatherer
+	sharding func() (int32, int)
+	client   client
+	backoff  wait.Backoff
+	now      func() time.Time
+	// start is the start of the cumulative metrics whose start is not known,
+	// when the exporter was created.
+	start time.Time
+
+	exportedDataPoints prometheus.Counter
+	failedDataPoints   prometheus.Counter
+}
+
+// New returns an Exporter pushing the families of gatherer. sharding returns
+// the shard of this instance and the total number of shards, which are added
+// as resource attributes.
+func New(config Config, gatherer prometheus.Gatherer, sharding func() (int32, int), r prometheus.Registerer) (*Exporter, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("no OTLP endpoint configured")
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultExportInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultExportTimeout
+	}
+
+	var c client
+	var err error
+	switch config.Protocol {
+	case ProtocolGRPC, "":
+		c, err = newGRPCClient(config)
+	case ProtocolHTTP:
+		c, err = newHTTPClient(config)
+	default:
+		return nil, errors.Errorf("unknown OTLP protocol %q, must be one of %s, %s", config.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Exporter{
+		config:   config,
+		gatherer: gatherer,
+		sharding: sharding,
+		client:   c,
+		backoff: wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    5,
+			Cap:      config.Interval,
+		},
+		now:   time.Now,
+		start: time.Now(),
+		exportedDataPoints: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "kruise_state_metrics_otlp_exported_data_points_total",
+			Help: "Number of data points successfully exported over OTLP.",
+		}),
+		failedDataPoints: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "kruise_state_metrics_otlp_failed_data_points_total",
+			Help: "Number of data points which could not be exported over OTLP.",
+		}),
+	}, nil
+}
+
+// Run exports the metrics every interval until the context is done.
+func (e *Exporter) Run(ctx context.Context) error {
+	defer e.client.close()
+
+	klog.Infof("Exporting metrics over OTLP (%s) to %s every %s", e.config.Protocol, e.config.Endpoint, e.config.Interval)
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := e.export(ctx); err != nil && ctx.Err() == nil {
+			klog.Errorf("Failed to export metrics over OTLP: %v", err)
+		}
+	}
+}
+
+// export gathers the metrics and pushes them, retrying with backoff on
+// retryable errors.
+func (e *Exporter) export(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return errors.Wrap(err, "gather metrics")
+	}
+	if len(families) == 0 {
+		return nil
+	}
+
+	shard, totalShards := e.sharding()
+	req, dataPoints := e.request(families, shard, totalShards)
+
+	err = wait.ExponentialBackoffWithContext(ctx, e.backoff, func(ctx context.Context) (bool, error) {
+		err := e.client.export(ctx, req)
+		if err == nil {
+			return true, nil
+		}
+		var retryable retryableError
+		if errors.As(err, &retryable) {
+			klog.V(2).Infof("Retrying OTLP export: %v", err)
+			return false, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		e.failedDataPoints.Add(float64(dataPoints))
+		return err
+	}
+	e.exportedDataPoints.Add(float64(dataPoints))
+
+	return nil
+}
+
+// request converts the metric families to an export request. Gauges and
+// untyped families become OTLP gauges, counters become monotonic cumulative
+// sums and histograms cumulative histograms.
+func (e *Exporter) request(families []*dto.MetricFamily, shard int32, totalShards int) (*colmetricspb.ExportMetricsServiceRequest, int) {
+	now := uint64(e.now().UnixNano())
+	dataPoints := 0
+
+	metrics := make([]*metricspb.Metric, 0, len(families))
+	for _, f := range families {
+		var metric *metricspb.Metric
+		var n int
+		if f.GetType() == dto.MetricType_HISTOGRAM {
+			metric, n = e.histogram(f, now)
+		} else {
+			metric, n = e.numbers(f, now)
+		}
+		if n == 0 {
+			continue
+		}
+		dataPoints += n
+		metrics = append(metrics, metric)
+	}
+
+	attributes := []*commonpb.KeyValue{
+		stringAttribute(attributeServiceName, defaultServiceName),
+		stringAttribute(attributeShard, strconv.Itoa(int(shard))),
+		stringAttribute(attributeTotalShards, strconv.Itoa(totalShards)),
+	}
+	if e.config.ClusterName != "" {
+		attributes = append(attributes, stringAttribute(attributeClusterName, e.config.ClusterName))
+	}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{Attributes: attributes},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: scopeName, Version: version.Version},
+				Metrics: metrics,
+			}},
+		}},
+	}, dataPoints
+}
+
+// numbers converts a gauge, counter or untyped family to an OTLP metric and
+// returns its number of data points.
+func (e *Exporter) numbers(f *dto.MetricFamily, now uint64) (*metricspb.Metric, int) {
+	points := make([]*metricspb.NumberDataPoint, 0, len(f.Metric))
+	for _, m := range f.Metric {
+		value, ok := samples.Value(f.GetType(), m)
+		if !ok {
+			continue
+		}
+		point := &metricspb.NumberDataPoint{
+			Attributes:   labelAttributes(m.Label),
+			TimeUnixNano: now,
+			Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+		}
+		if f.GetType() == dto.MetricType_COUNTER {
+			point.StartTimeUnixNano = e.startTime(m)
+		}
+		points = append(points, point)
+	}
+
+	metric := &metricspb.Metric{Name: f.GetName(), Description: f.GetHelp()}
+	if f.GetType() == dto.MetricType_COUNTER {
+		metric.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             points,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+	} else {
+		metric.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+	}
+	return metric, len(points)
+}
+
+// histogram converts a histogram family to an OTLP metric and returns its
+// number of data points. The cumulative counts of the Prometheus buckets
+// become the counts of the OTLP buckets, the last one counting the
+// observations above the highest bound.
+func (e *Exporter) histogram(f *dto.MetricFamily, now uint64) (*metricspb.Metric, int) {
+	points := make([]*metricspb.HistogramDataPoint, 0, len(f.Metric))
+	for _, m := range f.Metric {
+		h := m.GetHistogram()
+		if h == nil {
+			continue
+		}
+		bounds := make([]float64, 0, len(h.Bucket))
+		counts := make([]uint64, 0, len(h.Bucket)+1)
+		var cumulative uint64
+		for _, b := range h.Bucket {
+			if math.IsInf(b.GetUpperBound(), +1) {
+				continue
+			}
+			bounds = append(bounds, b.GetUpperBound())
+			counts = append(counts, b.GetCumulativeCount()-min(cumulative, b.GetCumulativeCount()))
+			cumulative = max(cumulative, b.GetCumulativeCount())
+		}
+		counts = append(counts, h.GetSampleCount()-min(cumulative, h.GetSampleCount()))
+		points = append(points, &metricspb.HistogramDataPoint{
+			Attributes:        labelAttributes(m.Label),
+			StartTimeUnixNano: e.startTime(m),
+			TimeUnixNano:      now,
+			Count:             h.GetSampleCount(),
+			Sum:               proto.Float64(h.GetSampleSum()),
+			BucketCounts:      counts,
+			ExplicitBounds:    bounds,
+		})
+	}
+
+	return &metricspb.Metric{
+		Name:        f.GetName(),
+		Description: f.GetHelp(),
+		Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             points,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}},
+	}, len(points)
+}
+
+// startTime returns the start of a cumulative metric, when the exporter was
+// created if it is not known.
+func (e *Exporter) startTime(m *dto.Metric) uint64 {
+	if start, ok := samples.StartTime(m); ok {
+		return uint64(start.UnixNano())
+	}
+	return uint64(e.start.UnixNano())
+}
+
+func labelAttributes(labels []*dto.LabelPair) []*commonpb.KeyValue {
+	attributes := make([]*commonpb.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		attributes = append(attributes, stringAttribute(l.GetName(), l.GetValue()))
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Key < attributes[j].Key
+	})
+	return attributes
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
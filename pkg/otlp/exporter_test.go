@@ -0,0 +1,332 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+)
+
+// receiver is an in-process OTLP collector which fails the first failures
+// exports.
+type receiver struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	mtx      sync.Mutex
+	failures int
+	requests []*colmetricspb.ExportMetricsServiceRequest
+	headers  []string
+}
+
+func (r *receiver) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		r.headers = append(r.headers, md.Get("x-tenant")...)
+	}
+	if r.failures > 0 {
+		r.failures--
+		return nil, status.Error(codes.Unavailable, "collector not ready")
+	}
+	r.requests = append(r.requests, req)
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func (r *receiver) received() []*colmetricspb.ExportMetricsServiceRequest {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.requests
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != defaultHTTPPath || req.Header.Get("Content-Type") != "application/x-protobuf" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	r.mtx.Lock()
+	r.headers = append(r.headers, req.Header.Get("X-Tenant"))
+	if r.failures > 0 {
+		r.failures--
+		r.mtx.Unlock()
+		http.Error(w, "collector not ready", http.StatusServiceUnavailable)
+		return
+	}
+	r.mtx.Unlock()
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exportReq := &colmetricspb.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(body, exportReq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mtx.Lock()
+	r.requests = append(r.requests, exportReq)
+	r.mtx.Unlock()
+
+	resp, _ := proto.Marshal(&colmetricspb.ExportMetricsServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(resp)
+}
+
+func testFamilies() []*dto.MetricFamily {
+	return []*dto.MetricFamily{
+		{
+			Name: ptr.To("kruise_cloneset_status_replicas"),
+			Help: ptr.To("The number of replicas per cloneset."),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{
+					{Name: ptr.To("namespace"), Value: ptr.To("ns1")},
+					{Name: ptr.To("cloneset"), Value: ptr.To("cs1")},
+				},
+				Gauge: &dto.Gauge{Value: ptr.To[float64](3)},
+			}},
+		},
+		{
+			Name: ptr.To("kruise_test_restarts_total"),
+			Help: ptr.To("Test counter."),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{
+				{Counter: &dto.Counter{Value: ptr.To[float64](1)}},
+				{Counter: &dto.Counter{Value: ptr.To[float64](2)}},
+			},
+		},
+	}
+}
+
+func newTestExporter(t *testing.T, config Config) (*Exporter, *prometheus.Registry) {
+	t.Helper()
+
+	config.Insecure = true
+	config.ClusterName = "test-cluster"
+	config.Headers = map[string]string{"x-tenant": "team-a"}
+	r := prometheus.NewRegistry()
+	e, err := New(config, prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return testFamilies(), nil
+	}), func() (int32, int) { return 1, 2 }, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	e.now = func() time.Time { return time.Unix(1500000000, 0) }
+	t.Cleanup(func() { e.client.close() })
+
+	return e, r
+}
+
+func checkRequest(t *testing.T, req *colmetricspb.ExportMetricsServiceRequest) {
+	t.Helper()
+
+	rm := req.GetResourceMetrics()
+	if len(rm) != 1 {
+		t.Fatalf("expected one resource, got %d", len(rm))
+	}
+	attributes := map[string]string{}
+	for _, a := range rm[0].GetResource().GetAttributes() {
+		attributes[a.GetKey()] = a.GetValue().GetStringValue()
+	}
+	wantAttributes := map[string]string{
+		attributeServiceName: defaultServiceName,
+		attributeClusterName: "test-cluster",
+		attributeShard:       "1",
+		attributeTotalShards: "2",
+	}
+	if diff := cmp.Diff(wantAttributes, attributes); diff != "" {
+		t.Errorf("unexpected resource attributes (-want, +got):\n%s", diff)
+	}
+
+	metrics := rm[0].GetScopeMetrics()[0].GetMetrics()
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(metrics))
+	}
+
+	gauge := metrics[0]
+	if gauge.GetName() != "kruise_cloneset_status_replicas" || gauge.GetGauge() == nil {
+		t.Fatalf("expected gauge kruise_cloneset_status_replicas, got %v", gauge)
+	}
+	point := gauge.GetGauge().GetDataPoints()[0]
+	if point.GetAsDouble() != 3 || point.GetTimeUnixNano() != uint64(time.Unix(1500000000, 0).UnixNano()) {
+		t.Errorf("unexpected data point %v", point)
+	}
+	if got := []string{point.GetAttributes()[0].GetKey(), point.GetAttributes()[1].GetKey()}; !cmp.Equal(got, []string{"cloneset", "namespace"}) {
+		t.Errorf("expected sorted attributes, got %v", got)
+	}
+
+	sum := metrics[1].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() || sum.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Fatalf("expected a monotonic cumulative sum, got %v", metrics[1])
+	}
+	if len(sum.GetDataPoints()) != 2 {
+		t.Errorf("expected 2 data points, got %d", len(sum.GetDataPoints()))
+	}
+}
+
+func TestExporterGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recv := &receiver{failures: 1}
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, recv)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	e, r := newTestExporter(t, Config{Endpoint: lis.Addr().String(), Protocol: ProtocolGRPC})
+	if err := e.export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := recv.received()
+	if len(requests) != 1 {
+		t.Fatalf("expected one request after retrying, got %d", len(requests))
+	}
+	checkRequest(t, requests[0])
+	if !cmp.Equal(recv.headers, []string{"team-a", "team-a"}) {
+		t.Errorf("expected headers on every attempt, got %v", recv.headers)
+	}
+	if got := testutil.ToFloat64(e.exportedDataPoints); got != 3 {
+		t.Errorf("expected 3 exported data points, got %v", got)
+	}
+	if n, err := testutil.GatherAndCount(r); err != nil || n != 2 {
+		t.Errorf("expected 2 self metrics, got %d (%v)", n, err)
+	}
+}
+
+func TestExporterHTTP(t *testing.T) {
+	recv := &receiver{failures: 1}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	e, _ := newTestExporter(t, Config{Endpoint: server.Listener.Addr().String(), Protocol: ProtocolHTTP})
+	if err := e.export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := recv.received()
+	if len(requests) != 1 {
+		t.Fatalf("expected one request after retrying, got %d", len(requests))
+	}
+	checkRequest(t, requests[0])
+	if !cmp.Equal(recv.headers, []string{"team-a", "team-a"}) {
+		t.Errorf("expected headers on every attempt, got %v", recv.headers)
+	}
+}
+
+func TestExporterFailure(t *testing.T) {
+	recv := &receiver{failures: 10}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	e, _ := newTestExporter(t, Config{Endpoint: server.URL, Protocol: ProtocolHTTP})
+	if err := e.export(context.Background()); err == nil {
+		t.Fatal("expected the export to fail after the retries are exhausted")
+	}
+	if got := testutil.ToFloat64(e.failedDataPoints); got != 3 {
+		t.Errorf("expected 3 failed data points, got %v", got)
+	}
+	if got := len(recv.headers); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRequestStartTimesAndHistograms(t *testing.T) {
+	e, _ := newTestExporter(t, Config{Endpoint: "localhost:4317", Protocol: ProtocolHTTP})
+	e.start = time.Unix(1400000000, 0)
+	families := []*dto.MetricFamily{
+		{
+			Name: ptr.To("kruise_test_restarts_total"),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{
+				{Counter: &dto.Counter{Value: ptr.To[float64](1), CreatedTimestamp: timestamppb.New(time.Unix(1450000000, 0))}},
+				{Counter: &dto.Counter{Value: ptr.To[float64](2)}},
+			},
+		},
+		{
+			Name: ptr.To("kruise_test_duration_seconds"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{Histogram: &dto.Histogram{
+				SampleCount: ptr.To[uint64](5),
+				SampleSum:   ptr.To[float64](12),
+				Bucket: []*dto.Bucket{
+					{UpperBound: ptr.To[float64](1), CumulativeCount: ptr.To[uint64](1)},
+					{UpperBound: ptr.To[float64](5), CumulativeCount: ptr.To[uint64](4)},
+					{UpperBound: ptr.To(math.Inf(+1)), CumulativeCount: ptr.To[uint64](5)},
+				},
+			}}},
+		},
+	}
+
+	req, dataPoints := e.request(families, 0, 1)
+	if dataPoints != 3 {
+		t.Errorf("expected 3 data points, got %d", dataPoints)
+	}
+	metrics := req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+
+	var starts []uint64
+	for _, point := range metrics[0].GetSum().GetDataPoints() {
+		starts = append(starts, point.GetStartTimeUnixNano())
+	}
+	if want := []uint64{uint64(time.Unix(1450000000, 0).UnixNano()), uint64(e.start.UnixNano())}; !cmp.Equal(starts, want) {
+		t.Errorf("expected the counters to start at %v, got %v", want, starts)
+	}
+
+	histogram := metrics[1].GetHistogram()
+	if histogram == nil || histogram.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Fatalf("expected a cumulative histogram, got %v", metrics[1])
+	}
+	point := histogram.GetDataPoints()[0]
+	if point.GetCount() != 5 || point.GetSum() != 12 || point.GetStartTimeUnixNano() != uint64(e.start.UnixNano()) {
+		t.Errorf("unexpected data point %v", point)
+	}
+	if !cmp.Equal(point.GetBucketCounts(), []uint64{1, 3, 1}) || !cmp.Equal(point.GetExplicitBounds(), []float64{1, 5}) {
+		t.Errorf("unexpected buckets %v with bounds %v", point.GetBucketCounts(), point.GetExplicitBounds())
+	}
+}
+
+func TestNewUnknownProtocol(t *testing.T) {
+	if _, err := New(Config{Endpoint: "localhost:4317", Protocol: "thrift"}, prometheus.NewRegistry(), nil, prometheus.NewRegistry()); err == nil {
+		t.Error("expected an error for an unknown protocol")
+	}
+}
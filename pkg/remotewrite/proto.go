@@ -0,0 +1,90 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotewrite
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The types below mirror the prometheus.WriteRequest protobuf message of the
+// remote write 1.0 protocol. They are encoded by hand to avoid depending on
+// the whole Prometheus module:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label        { string name = 1; string value = 2; }
+//	message Sample       { double value = 1; int64 timestamp = 2; }
+
+type label struct {
+	name, value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+type timeSeries struct {
+	// labels must be sorted by name.
+	labels []label
+	sample sample
+}
+
+type writeRequest struct {
+	timeseries []timeSeries
+}
+
+func (r *writeRequest) marshal() []byte {
+	var b, ts []byte
+	for _, s := range r.timeseries {
+		ts = s.appendProto(ts[:0])
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+	}
+	return b
+}
+
+func (s *timeSeries) appendProto(b []byte) []byte {
+	for _, l := range s.labels {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendVarint(b, uint64(labelSize(l)))
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, l.name)
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, l.value)
+	}
+
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(sampleSize(s.sample)))
+	b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(s.sample.value))
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(s.sample.timestamp))
+	return b
+}
+
+func labelSize(l label) int {
+	return protowire.SizeTag(1) + protowire.SizeBytes(len(l.name)) +
+		protowire.SizeTag(2) + protowire.SizeBytes(len(l.value))
+}
+
+func sampleSize(s sample) int {
+	return protowire.SizeTag(1) + protowire.SizeFixed64() +
+		protowire.SizeTag(2) + protowire.SizeVarint(uint64(s.timestamp))
+}
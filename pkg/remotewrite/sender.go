@@ -0,0 +1,356 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/version"
+	"k8s.io/apimachinery/pkg/util/wait"
+	klog "k8s.io/klog/v2"
+
+	"github.com/openkruise/kruise-state-metrics/pkg/samples"
+)
+
+const (
+	defaultInterval       = 30 * time.Second
+	defaultTimeout        = 10 * time.Second
+	defaultQueueMaxSample = 100000
+	maxSamplesPerSend     = 2000
+	remoteWriteVersion    = "0.1.0"
+)
+
+// Config configures the Sender.
+type Config struct {
+	// URL is the remote write endpoint, e.g. https://prometheus/api/v1/write.
+	URL string
+	// Username and PasswordFile enable basic authentication.
+	Username     string
+	PasswordFile string
+	// BearerTokenFile enables bearer token authentication. The file is read
+	// before every request, so that rotated tokens are picked up.
+	BearerTokenFile string
+	// ExternalLabels are added to every series which does not have a label
+	// of the same name.
+	ExternalLabels map[string]string
+	// Interval is the time between two snapshots.
+	Interval time.Duration
+	// Timeout bounds every request.
+	Timeout time.Duration
+	// QueueMaxSamples bounds the number of samples waiting to be sent. The
+	// oldest samples are dropped when it is exceeded.
+	QueueMaxSamples int
+}
+
+// rejectedError is a send error the receiver returned for a request it will
+// never accept, e.g. because it is malformed. Other errors are retried.
+type rejectedError struct {
+	error
+}
+
+// Sender periodically snapshots the gathered metric families and pushes them
+// to a Prometheus remote write endpoint. Requests which cannot be sent are
+// kept in a bounded in-memory queue and retried on the next interval.
+type Sender struct {
+	config   Config
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	backoff  wait.Backoff
+	now      func() time.Time
+
+	// queue is only accessed by the goroutine calling Run.
+	queue         []*writeRequest
+	queuedSamples int
+
+	samplesSent    prometheus.Counter
+	samplesFailed  prometheus.Counter
+	samplesPending prometheus.Gauge
+}
+
+// New returns a Sender pushing the families of gatherer.
+func New(config Config, gatherer prometheus.Gatherer, r prometheus.Registerer) (*Sender, error) {
+	if config.URL == "" {
+		return nil, errors.New("no remote write URL configured")
+	}
+	if !strings.HasPrefix(config.URL, "http://") && !strings.HasPrefix(config.URL, "https://") {
+		return nil, errors.Errorf("remote write URL %q must start with http:// or https://", config.URL)
+	}
+	if config.BearerTokenFile != "" && (config.Username != "" || config.PasswordFile != "") {
+		return nil, errors.New("basic and bearer token authentication are mutually exclusive")
+	}
+	for name := range config.ExternalLabels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+			return nil, errors.Errorf("invalid external label name %q", name)
+		}
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.QueueMaxSamples <= 0 {
+		config.QueueMaxSamples = defaultQueueMaxSample
+	}
+
+	return &Sender{
+		config:   config,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: config.Timeout},
+		backoff: wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    3,
+			Cap:      config.Interval,
+		},
+		now: time.Now,
+		samplesSent: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "kruise_state_metrics_remote_write_samples_sent_total",
+			Help: "Number of samples successfully sent over remote write.",
+		}),
+		samplesFailed: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "kruise_state_metrics_remote_write_samples_failed_total",
+			Help: "Number of samples which were rejected by the receiver or dropped from the full queue.",
+		}),
+		samplesPending: promauto.With(r).NewGauge(prometheus.GaugeOpts{
+			Name: "kruise_state_metrics_remote_write_samples_pending",
+			Help: "Number of samples waiting in the queue to be sent over remote write.",
+		}),
+	}, nil
+}
+
+// Run snapshots and sends the metrics every interval until the context is
+// done.
+func (s *Sender) Run(ctx context.Context) error {
+	klog.Infof("Sending metrics over remote write to %s every %s", s.config.URL, s.config.Interval)
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := s.snapshot(); err != nil {
+			klog.Errorf("Failed to snapshot metrics for remote write: %v", err)
+		}
+		if err := s.flush(ctx); err != nil && ctx.Err() == nil {
+			klog.Errorf("Failed to send metrics over remote write, %d samples queued: %v", s.queuedSamples, err)
+		}
+	}
+}
+
+// snapshot gathers the metrics and queues them as write requests of at most
+// maxSamplesPerSend samples. Histograms are sent as their _bucket, _sum and
+// _count series.
+func (s *Sender) snapshot() error {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		return errors.Wrap(err, "gather metrics")
+	}
+
+	timestamp := s.now().UnixMilli()
+	req := &writeRequest{}
+	for _, f := range families {
+		for _, m := range f.Metric {
+			for _, smpl := range samples.Expand(f, m) {
+				req.timeseries = append(req.timeseries, timeSeries{
+					labels: s.labels(smpl.Name, smpl.Labels),
+					sample: sample{value: smpl.Value, timestamp: timestamp},
+				})
+				if len(req.timeseries) == maxSamplesPerSend {
+					s.enqueue(req)
+					req = &writeRequest{}
+				}
+			}
+		}
+	}
+	if len(req.timeseries) > 0 {
+		s.enqueue(req)
+	}
+
+	return nil
+}
+
+// enqueue appends req to the queue, dropping the oldest requests when the
+// queue exceeds its capacity.
+func (s *Sender) enqueue(req *writeRequest) {
+	s.queue = append(s.queue, req)
+	s.queuedSamples += len(req.timeseries)
+
+	dropped := 0
+	for s.queuedSamples > s.config.QueueMaxSamples && len(s.queue) > 0 {
+		dropped += len(s.queue[0].timeseries)
+		s.dequeue()
+	}
+	if dropped > 0 {
+		klog.Warningf("Remote write queue is full, dropped the %d oldest samples", dropped)
+		s.samplesFailed.Add(float64(dropped))
+	}
+	s.samplesPending.Set(float64(s.queuedSamples))
+}
+
+func (s *Sender) dequeue() {
+	s.queuedSamples -= len(s.queue[0].timeseries)
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	s.samplesPending.Set(float64(s.queuedSamples))
+}
+
+// flush sends the queued requests in order, retrying with backoff on errors.
+// Requests still failing after the retries stay queued for the next flush,
+// requests the receiver rejects are dropped.
+func (s *Sender) flush(ctx context.Context) error {
+	for len(s.queue) > 0 {
+		req := s.queue[0]
+		body := snappy.Encode(nil, req.marshal())
+
+		var lastErr error
+		err := wait.ExponentialBackoffWithContext(ctx, s.backoff, func(ctx context.Context) (bool, error) {
+			lastErr = s.send(ctx, body)
+			if lastErr == nil {
+				return true, nil
+			}
+			if errors.As(lastErr, new(rejectedError)) {
+				return false, lastErr
+			}
+			klog.V(2).Infof("Retrying remote write: %v", lastErr)
+			return false, nil
+		})
+		switch {
+		case err == nil:
+			s.samplesSent.Add(float64(len(req.timeseries)))
+			s.dequeue()
+		case errors.As(err, new(rejectedError)):
+			klog.Errorf("Remote write receiver rejected %d samples: %v", len(req.timeseries), err)
+			s.samplesFailed.Add(float64(len(req.timeseries)))
+			s.dequeue()
+		case lastErr != nil:
+			return lastErr
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Sender) send(ctx context.Context, body []byte) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("User-Agent", "kruise-state-metrics/"+version.Version)
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	if err := s.authenticate(httpReq); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && !retryableStatus(resp.StatusCode):
+		return rejectedError{errors.Errorf("remote write endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))}
+	default:
+		return errors.Errorf("remote write endpoint returned %s", resp.Status)
+	}
+}
+
+// retryableStatus returns whether a client error status may go away on its
+// own, e.g. once the receiver is less loaded or the rotated credentials are
+// accepted.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+func (s *Sender) authenticate(req *http.Request) error {
+	if s.config.BearerTokenFile != "" {
+		token, err := os.ReadFile(s.config.BearerTokenFile)
+		if err != nil {
+			return errors.Wrap(err, "read bearer token file")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+		return nil
+	}
+	if s.config.Username != "" {
+		var password []byte
+		if s.config.PasswordFile != "" {
+			var err error
+			if password, err = os.ReadFile(s.config.PasswordFile); err != nil {
+				return errors.Wrap(err, "read password file")
+			}
+		}
+		req.SetBasicAuth(s.config.Username, strings.TrimSpace(string(password)))
+	}
+	return nil
+}
+
+// labels returns the sorted labels of a series including its name and the
+// external labels.
+func (s *Sender) labels(name string, pairs []*dto.LabelPair) []label {
+	labels := make([]label, 0, len(pairs)+len(s.config.ExternalLabels)+1)
+	labels = append(labels, label{name: model.MetricNameLabel, value: name})
+	for _, p := range pairs {
+		labels = append(labels, label{name: p.GetName(), value: p.GetValue()})
+	}
+	for name, value := range s.config.ExternalLabels {
+		if !hasLabel(pairs, name) {
+			labels = append(labels, label{name: name, value: value})
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return labels
+}
+
+func hasLabel(pairs []*dto.LabelPair, name string) bool {
+	for _, p := range pairs {
+		if p.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,420 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotewrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+)
+
+// receiver is a remote write endpoint answering with the queued status codes
+// before accepting requests.
+type receiver struct {
+	t *testing.T
+
+	mtx      sync.Mutex
+	statuses []int
+	series   [][]timeSeries
+	auth     []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if req.Header.Get("Content-Encoding") != "snappy" || req.Header.Get("Content-Type") != "application/x-protobuf" ||
+		req.Header.Get("X-Prometheus-Remote-Write-Version") != remoteWriteVersion {
+		r.t.Errorf("unexpected headers %v", req.Header)
+	}
+	r.auth = append(r.auth, req.Header.Get("Authorization"))
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	compressed, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	series, err := unmarshalWriteRequest(body)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	r.series = append(r.series, series)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *receiver) received() [][]timeSeries {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.series
+}
+
+// unmarshalWriteRequest decodes the series of a WriteRequest holding a single
+// sample per series.
+func unmarshalWriteRequest(b []byte) ([]timeSeries, error) {
+	var series []timeSeries
+	err := consumeMessage(b, func(num protowire.Number, v []byte) error {
+		ts := timeSeries{}
+		err := consumeMessage(v, func(num protowire.Number, v []byte) error {
+			if num == 1 {
+				l := label{}
+				err := consumeMessage(v, func(num protowire.Number, v []byte) error {
+					if num == 1 {
+						l.name = string(v)
+					} else {
+						l.value = string(v)
+					}
+					return nil
+				})
+				ts.labels = append(ts.labels, l)
+				return err
+			}
+			for len(v) > 0 {
+				num, typ, n := protowire.ConsumeTag(v)
+				switch {
+				case num == 1 && typ == protowire.Fixed64Type:
+					bits, m := protowire.ConsumeFixed64(v[n:])
+					ts.sample.value = math.Float64frombits(bits)
+					n += m
+				case num == 2 && typ == protowire.VarintType:
+					timestamp, m := protowire.ConsumeVarint(v[n:])
+					ts.sample.timestamp = int64(timestamp)
+					n += m
+				default:
+					return fmt.Errorf("unexpected sample field %d of type %d", num, typ)
+				}
+				v = v[n:]
+			}
+			return nil
+		})
+		series = append(series, ts)
+		return err
+	})
+	return series, err
+}
+
+func consumeMessage(b []byte, field func(num protowire.Number, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ != protowire.BytesType {
+			return fmt.Errorf("unexpected field %d of type %d", num, typ)
+		}
+		v, m := protowire.ConsumeBytes(b[n:])
+		if m < 0 {
+			return protowire.ParseError(m)
+		}
+		if err := field(num, v); err != nil {
+			return err
+		}
+		b = b[n+m:]
+	}
+	return nil
+}
+
+func testFamilies(n int) []*dto.MetricFamily {
+	f := &dto.MetricFamily{
+		Name: ptr.To("kruise_cloneset_status_replicas"),
+		Help: ptr.To("The number of replicas per cloneset."),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	for i := 0; i < n; i++ {
+		f.Metric = append(f.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{
+				{Name: ptr.To("namespace"), Value: ptr.To("ns1")},
+				{Name: ptr.To("cloneset"), Value: ptr.To(fmt.Sprintf("cs%d", i))},
+			},
+			Gauge: &dto.Gauge{Value: ptr.To(float64(i))},
+		})
+	}
+	return []*dto.MetricFamily{f}
+}
+
+func newTestSender(t *testing.T, config Config, families []*dto.MetricFamily) *Sender {
+	t.Helper()
+
+	s, err := New(config, prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return families, nil
+	}), prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 2}
+	s.now = func() time.Time { return time.Unix(1500000000, 0) }
+	return s
+}
+
+func TestSender(t *testing.T) {
+	recv := &receiver{t: t}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSender(t, Config{
+		URL:            server.URL,
+		Username:       "kruise",
+		PasswordFile:   passwordFile,
+		ExternalLabels: map[string]string{"cluster": "edge-1", "namespace": "ignored"},
+	}, testFamilies(1))
+
+	if err := s.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]timeSeries{{{
+		labels: []label{
+			{name: "__name__", value: "kruise_cloneset_status_replicas"},
+			{name: "cloneset", value: "cs0"},
+			{name: "cluster", value: "edge-1"},
+			{name: "namespace", value: "ns1"},
+		},
+		sample: sample{value: 0, timestamp: 1500000000000},
+	}}}
+	if diff := cmp.Diff(want, recv.received(), cmp.AllowUnexported(timeSeries{}, label{}, sample{})); diff != "" {
+		t.Errorf("unexpected series (-want, +got):\n%s", diff)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.SetBasicAuth("kruise", "secret")
+	if recv.auth[0] != req.Header.Get("Authorization") {
+		t.Errorf("expected basic authentication, got %q", recv.auth[0])
+	}
+	if got := testutil.ToFloat64(s.samplesSent); got != 1 {
+		t.Errorf("expected 1 sent sample, got %v", got)
+	}
+}
+
+func TestSenderHistogram(t *testing.T) {
+	recv := &receiver{t: t}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	s := newTestSender(t, Config{URL: server.URL}, []*dto.MetricFamily{{
+		Name: ptr.To("kruise_test_duration_seconds"),
+		Type: dto.MetricType_HISTOGRAM.Enum(),
+		Metric: []*dto.Metric{{Histogram: &dto.Histogram{
+			SampleCount: ptr.To[uint64](5),
+			SampleSum:   ptr.To[float64](12),
+			Bucket:      []*dto.Bucket{{UpperBound: ptr.To[float64](1), CumulativeCount: ptr.To[uint64](1)}},
+		}}},
+	}})
+
+	if err := s.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	series := func(name string, value float64, labels ...label) timeSeries {
+		return timeSeries{
+			labels: append([]label{{name: "__name__", value: name}}, labels...),
+			sample: sample{value: value, timestamp: 1500000000000},
+		}
+	}
+	want := [][]timeSeries{{
+		series("kruise_test_duration_seconds_bucket", 1, label{name: "le", value: "1"}),
+		series("kruise_test_duration_seconds_bucket", 5, label{name: "le", value: "+Inf"}),
+		series("kruise_test_duration_seconds_sum", 12),
+		series("kruise_test_duration_seconds_count", 5),
+	}}
+	if diff := cmp.Diff(want, recv.received(), cmp.AllowUnexported(timeSeries{}, label{}, sample{})); diff != "" {
+		t.Errorf("unexpected series (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSenderBearerToken(t *testing.T) {
+	recv := &receiver{t: t}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token-1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSender(t, Config{URL: server.URL, BearerTokenFile: tokenFile}, testFamilies(1))
+	if err := s.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotated tokens are picked up.
+	if err := os.WriteFile(tokenFile, []byte("token-2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"Bearer token-1", "Bearer token-2"}; !cmp.Equal(want, recv.auth) {
+		t.Errorf("expected authorization %v, got %v", want, recv.auth)
+	}
+}
+
+func TestSenderRetryQueue(t *testing.T) {
+	// The first flush fails twice and keeps the requests queued, the
+	// second flush sends them in order.
+	recv := &receiver{t: t, statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	s := newTestSender(t, Config{URL: server.URL, QueueMaxSamples: maxSamplesPerSend + maxSamplesPerSend/2}, testFamilies(maxSamplesPerSend+1))
+	if err := s.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.queue) != 2 {
+		t.Fatalf("expected the snapshot to be split in 2 requests, got %d", len(s.queue))
+	}
+	if err := s.flush(context.Background()); err == nil {
+		t.Fatal("expected the first flush to fail")
+	}
+	if got := testutil.ToFloat64(s.samplesPending); got != maxSamplesPerSend+1 {
+		t.Errorf("expected %d pending samples, got %v", maxSamplesPerSend+1, got)
+	}
+
+	// The queue is bounded, the oldest request is dropped.
+	if err := s.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(s.samplesFailed); got != maxSamplesPerSend {
+		t.Errorf("expected %d failed samples, got %v", maxSamplesPerSend, got)
+	}
+
+	if err := s.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	received := recv.received()
+	if len(received) != 3 || len(received[0]) != 1 || len(received[1]) != maxSamplesPerSend || len(received[2]) != 1 {
+		t.Errorf("unexpected requests received: %d", len(received))
+	}
+	if got := testutil.ToFloat64(s.samplesSent); got != maxSamplesPerSend+2 {
+		t.Errorf("expected %d sent samples, got %v", maxSamplesPerSend+2, got)
+	}
+	if got := testutil.ToFloat64(s.samplesPending); got != 0 {
+		t.Errorf("expected an empty queue, got %v pending samples", got)
+	}
+}
+
+func TestSenderRejected(t *testing.T) {
+	recv := &receiver{t: t, statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	s := newTestSender(t, Config{URL: server.URL}, testFamilies(2))
+	if err := s.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(recv.auth) != 1 {
+		t.Errorf("expected rejected requests not to be retried, got %d attempts", len(recv.auth))
+	}
+	if got := testutil.ToFloat64(s.samplesFailed); got != 2 {
+		t.Errorf("expected 2 failed samples, got %v", got)
+	}
+	if len(s.queue) != 0 {
+		t.Errorf("expected rejected requests to be dropped")
+	}
+}
+
+func TestSenderAuthenticationError(t *testing.T) {
+	// Neither an unreadable token file nor a refused token drop the
+	// requests, they are sent once the token is fixed.
+	recv := &receiver{t: t, statuses: []int{http.StatusUnauthorized, http.StatusUnauthorized}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	s := newTestSender(t, Config{URL: server.URL, BearerTokenFile: tokenFile}, testFamilies(2))
+	if err := s.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.flush(context.Background()); err == nil {
+		t.Fatal("expected the flush to fail without a token file")
+	}
+	if len(recv.auth) != 0 {
+		t.Errorf("expected no request without a token, got %d", len(recv.auth))
+	}
+
+	if err := os.WriteFile(tokenFile, []byte("token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.flush(context.Background()); err == nil {
+		t.Fatal("expected the flush to fail while the token is refused")
+	}
+	if got := testutil.ToFloat64(s.samplesFailed); got != 0 {
+		t.Errorf("expected no failed samples, got %v", got)
+	}
+	if got := testutil.ToFloat64(s.samplesPending); got != 2 {
+		t.Errorf("expected 2 pending samples, got %v", got)
+	}
+
+	if err := s.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(s.samplesSent); got != 2 {
+		t.Errorf("expected 2 sent samples, got %v", got)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, config := range []Config{
+		{},
+		{URL: "prometheus:9090/api/v1/write"},
+		{URL: "http://prometheus", Username: "kruise", BearerTokenFile: "token"},
+		{URL: "http://prometheus", ExternalLabels: map[string]string{"__name__": "x"}},
+	} {
+		if _, err := New(config, prometheus.NewRegistry(), prometheus.NewRegistry()); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+}
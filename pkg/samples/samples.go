@@ -0,0 +1,106 @@
+/*
+Copyright 2026 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package samples converts the gathered metric families to the samples
+// pushed by the exporters.
+package samples
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// Sample is a sample of a series of a metric family.
+type Sample struct {
+	// Name is the name of the series, e.g. the name of the family followed
+	// by _bucket for the buckets of a histogram.
+	Name   string
+	Labels []*dto.LabelPair
+	Value  float64
+}
+
+// Value returns the value of a gauge, counter or untyped metric, false for
+// the other types.
+func Value(t dto.MetricType, m *dto.Metric) (float64, bool) {
+	switch t {
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), m.Gauge != nil
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), m.Counter != nil
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), m.Untyped != nil
+	default:
+		return 0, false
+	}
+}
+
+// Expand returns the samples of the series of a metric like in the Prometheus
+// text format: a single sample for gauges, counters and untyped metrics, and
+// the _bucket, _sum and _count samples of histograms. Summaries are left
+// out.
+func Expand(f *dto.MetricFamily, m *dto.Metric) []Sample {
+	if value, ok := Value(f.GetType(), m); ok {
+		return []Sample{{Name: f.GetName(), Labels: m.Label, Value: value}}
+	}
+	if f.GetType() != dto.MetricType_HISTOGRAM || m.Histogram == nil {
+		return nil
+	}
+
+	h := m.GetHistogram()
+	samples := make([]Sample, 0, len(h.Bucket)+3)
+	inf := false
+	for _, b := range h.Bucket {
+		inf = inf || math.IsInf(b.GetUpperBound(), +1)
+		samples = append(samples, bucket(f.GetName(), m.Label, b.GetUpperBound(), float64(b.GetCumulativeCount())))
+	}
+	// The +Inf bucket is implicit in the protobuf format.
+	if !inf {
+		samples = append(samples, bucket(f.GetName(), m.Label, math.Inf(+1), float64(h.GetSampleCount())))
+	}
+	return append(samples,
+		Sample{Name: f.GetName() + "_sum", Labels: m.Label, Value: h.GetSampleSum()},
+		Sample{Name: f.GetName() + "_count", Labels: m.Label, Value: float64(h.GetSampleCount())},
+	)
+}
+
+func bucket(name string, labels []*dto.LabelPair, upperBound, count float64) Sample {
+	le := strconv.FormatFloat(upperBound, 'g', -1, 64)
+	if math.IsInf(upperBound, +1) {
+		le = "+Inf"
+	}
+	withBound := make([]*dto.LabelPair, len(labels), len(labels)+1)
+	copy(withBound, labels)
+	withBound = append(withBound, &dto.LabelPair{Name: proto.String(model.BucketLabel), Value: proto.String(le)})
+	return Sample{Name: name + "_bucket", Labels: withBound, Value: count}
+}
+
+// StartTime returns when a counter or a histogram started counting, false if
+// it is not known.
+func StartTime(m *dto.Metric) (time.Time, bool) {
+	switch {
+	case m.GetCounter().GetCreatedTimestamp() != nil:
+		return m.GetCounter().GetCreatedTimestamp().AsTime(), true
+	case m.GetHistogram().GetCreatedTimestamp() != nil:
+		return m.GetHistogram().GetCreatedTimestamp().AsTime(), true
+	default:
+		return time.Time{}, false
+	}
+}